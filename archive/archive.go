@@ -0,0 +1,221 @@
+// Package archive reads and writes account data archives: a zip holding one
+// JSON file per table, a manifest and a sha256sum-compatible checksum list.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"clementus360/ai-helper/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	ManifestName  = "manifest.json"
+	ChecksumsName = "checksums.sha256"
+)
+
+// Tables lists the archived tables in restore order: every table comes
+// after the tables its rows refer to
+var Tables = []string{
+	"sessions",
+	"messages",
+	"tasks",
+	"session_summaries",
+	"session_metrics",
+	"user_patterns",
+	"user_activities",
+	"memories",
+}
+
+// maxArchiveBytes bounds the uncompressed size of all the files read back
+// from one archive together, so a small upload can't expand without limit
+const maxArchiveBytes = 256 << 20
+
+// Row is a database row as exported, keyed by column name
+type Row = map[string]any
+
+// FileName is the archive file holding a table's rows
+func FileName(table string) string {
+	return table + ".json"
+}
+
+// Write writes an archive of the given tables to w
+func Write(w io.Writer, sourceUserID string, now time.Time, tables map[string][]Row) (types.ArchiveManifest, error) {
+	manifest := types.ArchiveManifest{
+		Schema:       types.AccountArchiveSchema,
+		Version:      types.AccountArchiveVersion,
+		SourceUserID: sourceUserID,
+		CreatedAt:    now.UTC(),
+	}
+
+	zw := zip.NewWriter(w)
+	var checksums strings.Builder
+	for _, table := range Tables {
+		rows := make([]Row, len(tables[table]))
+		for i, row := range tables[table] {
+			rows[i] = withoutGenerated(table, row)
+		}
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return manifest, fmt.Errorf("failed to encode %s: %w", table, err)
+		}
+		sum := checksum(data)
+		name := FileName(table)
+		if err := writeFile(zw, name, data, now); err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, types.ArchiveFile{
+			Name:    name,
+			Table:   table,
+			Records: len(rows),
+			Bytes:   int64(len(data)),
+			SHA256:  sum,
+		})
+		fmt.Fprintf(&checksums, "%s  %s\n", sum, name)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeFile(zw, ManifestName, data, now); err != nil {
+		return manifest, err
+	}
+	fmt.Fprintf(&checksums, "%s  %s\n", checksum(data), ManifestName)
+	if err := writeFile(zw, ChecksumsName, []byte(checksums.String()), now); err != nil {
+		return manifest, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// Read opens an archive, checks its schema and checksums and returns its
+// rows by table
+func Read(data []byte) (types.ArchiveManifest, map[string][]Row, error) {
+	var manifest types.ArchiveManifest
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return manifest, nil, fmt.Errorf("not a zip archive: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	budget := int64(maxArchiveBytes)
+	raw, err := readFile(files, ManifestName, &budget)
+	if err != nil {
+		return manifest, nil, err
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Schema != types.AccountArchiveSchema {
+		return manifest, nil, fmt.Errorf("not an account archive: schema is %q", manifest.Schema)
+	}
+	if manifest.Version < 1 || manifest.Version > types.AccountArchiveVersion {
+		return manifest, nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+
+	known := map[string]bool{}
+	for _, table := range Tables {
+		known[table] = true
+	}
+
+	tables := map[string][]Row{}
+	for _, entry := range manifest.Files {
+		if !known[entry.Table] || entry.Name != FileName(entry.Table) {
+			return manifest, nil, fmt.Errorf("unexpected file %q in manifest", entry.Name)
+		}
+		if _, seen := tables[entry.Table]; seen {
+			return manifest, nil, fmt.Errorf("%s is listed twice in manifest", entry.Name)
+		}
+		raw, err := readFile(files, entry.Name, &budget)
+		if err != nil {
+			return manifest, nil, err
+		}
+		if checksum(raw) != entry.SHA256 {
+			return manifest, nil, fmt.Errorf("checksum mismatch for %s", entry.Name)
+		}
+
+		var rows []Row
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber() // keep numbers exactly as exported
+		if err := dec.Decode(&rows); err != nil {
+			return manifest, nil, fmt.Errorf("invalid %s: %w", entry.Name, err)
+		}
+		if len(rows) != entry.Records {
+			return manifest, nil, fmt.Errorf("%s holds %d records, manifest says %d", entry.Name, len(rows), entry.Records)
+		}
+		tables[entry.Table] = rows
+	}
+	return manifest, tables, nil
+}
+
+// Counts returns the number of rows per table
+func Counts(tables map[string][]Row) map[string]int {
+	counts := make(map[string]int, len(Tables))
+	for _, table := range Tables {
+		counts[table] = len(tables[table])
+	}
+	return counts
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte, now time.Time) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// readFile reads a file from the archive, counting its size against the
+// bytes left in budget
+func readFile(files map[string]*zip.File, name string, budget *int64) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("archive is missing %s", name)
+	}
+	// The header's size is only a hint, but saves inflating a file that
+	// admits to being too large
+	if f.UncompressedSize64 > uint64(*budget) {
+		return nil, fmt.Errorf("archive is too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, *budget+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if int64(len(data)) > *budget {
+		return nil, fmt.Errorf("archive is too large")
+	}
+	*budget -= int64(len(data))
+	return data, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
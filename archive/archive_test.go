@@ -0,0 +1,239 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleTables is an account as fetchAllRows returns it, generated columns
+// included
+func sampleTables() map[string][]Row {
+	return map[string][]Row{
+		"sessions": {
+			{"id": "s1", "user_id": "u1", "title": "Job search", "created_at": "2026-10-14T09:00:00Z"},
+			{"id": "s2", "user_id": "u1", "title": "Fork", "parent_session_id": "s1", "forked_from_message_id": "m2"},
+		},
+		"messages": {
+			{"id": "m1", "user_id": "u1", "session_id": "s1", "sender": "user", "content": "Where do I start?", "search_vector": "'start':4"},
+			{"id": "m2", "user_id": "u1", "session_id": "s1", "sender": "ai", "content": "Your CV.", "user_message_id": "m1", "search_vector": "'cv':2"},
+		},
+		"tasks": {
+			{"id": "t1", "user_id": "u1", "session_id": "s1", "message_id": "m2", "title": "Update CV", "column_id": "c1", "search_vector": "'cv':2A"},
+		},
+		"session_summaries": {
+			{"session_id": "s1", "user_id": "u1", "summary": "Planned the search.", "search_vector": "'plan':1"},
+		},
+		"session_metrics": {
+			{"session_id": "s1", "user_id": "u1", "message_count": json.Number("2")},
+		},
+		"user_patterns": {
+			{"user_id": "u1", "struggles": []any{"starting"}},
+		},
+		"user_activities": {
+			{"id": "a1", "user_id": "u1", "session_id": "s1", "activity_type": "message"},
+		},
+		"memories": {
+			{"id": "mem1", "user_id": "u1", "content": "Wants a remote role", "embedding": "[0.1,0.2]", "embedder": "test", "source_session_id": "s1", "source_message_id": "m1"},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	manifest, err := Write(&buf, "u1", now, sampleTables())
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(manifest.Files) != len(Tables) {
+		t.Errorf("manifest lists %d files, want %d", len(manifest.Files), len(Tables))
+	}
+
+	read, tables, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if read.SourceUserID != "u1" || read.Schema != types.AccountArchiveSchema {
+		t.Errorf("manifest = %+v", read)
+	}
+	for table, rows := range sampleTables() {
+		if len(tables[table]) != len(rows) {
+			t.Errorf("%s: read %d rows, wrote %d", table, len(tables[table]), len(rows))
+		}
+	}
+	for table := range generated {
+		for _, row := range tables[table] {
+			if _, ok := row["search_vector"]; ok {
+				t.Errorf("%s was exported with its generated search_vector", table)
+			}
+		}
+	}
+
+	remapped, skipped, problems := Remap(tables, "u2")
+	if len(skipped) > 0 || len(problems) > 0 {
+		t.Fatalf("Remap() skipped %v: %v", skipped, problems)
+	}
+	if got := Counts(remapped); got["memories"] != 1 || got["messages"] != 2 {
+		t.Errorf("Counts() = %v", got)
+	}
+
+	session := remapped["sessions"][0]
+	fork := remapped["sessions"][1]
+	user := remapped["messages"][0]
+	reply := remapped["messages"][1]
+	task := remapped["tasks"][0]
+	memory := remapped["memories"][0]
+	links := []struct {
+		name      string
+		got, want any
+	}{
+		{"fork parent", fork["parent_session_id"], session["id"]},
+		{"fork point", fork["forked_from_message_id"], reply["id"]},
+		{"message session", user["session_id"], session["id"]},
+		{"reply", reply["user_message_id"], user["id"]},
+		{"task session", task["session_id"], session["id"]},
+		{"task message", task["message_id"], reply["id"]},
+		{"summary session", remapped["session_summaries"][0]["session_id"], session["id"]},
+		{"metrics session", remapped["session_metrics"][0]["session_id"], session["id"]},
+		{"activity session", remapped["user_activities"][0]["session_id"], session["id"]},
+		{"memory session", memory["source_session_id"], session["id"]},
+		{"memory message", memory["source_message_id"], user["id"]},
+	}
+	for _, l := range links {
+		if l.got != l.want {
+			t.Errorf("%s = %v, want %v", l.name, l.got, l.want)
+		}
+	}
+	if session["id"] == "s1" || memory["id"] == "mem1" {
+		t.Errorf("rows kept their old IDs: %v, %v", session["id"], memory["id"])
+	}
+	if memory["embedding"] != "[0.1,0.2]" {
+		t.Errorf("memory embedding = %v", memory["embedding"])
+	}
+	if task["column_id"] != nil {
+		t.Errorf("task kept column_id %v", task["column_id"])
+	}
+	if n := remapped["session_metrics"][0]["message_count"]; n != json.Number("2") {
+		t.Errorf("message_count = %#v, want the exported number", n)
+	}
+	for table, rows := range remapped {
+		for _, row := range rows {
+			if row["user_id"] != "u2" {
+				t.Errorf("%s row owned by %v, want u2", table, row["user_id"])
+			}
+		}
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	valid := func() map[string][]byte {
+		var buf bytes.Buffer
+		if _, err := Write(&buf, "u1", time.Now(), sampleTables()); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string][]byte{}
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			var b bytes.Buffer
+			b.ReadFrom(rc)
+			rc.Close()
+			files[f.Name] = b.Bytes()
+		}
+		return files
+	}
+	editManifest := func(files map[string][]byte, edit func(*types.ArchiveManifest)) {
+		var m types.ArchiveManifest
+		json.Unmarshal(files[ManifestName], &m)
+		edit(&m)
+		files[ManifestName], _ = json.Marshal(m)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(files map[string][]byte)
+		wantErr string
+	}{
+		{
+			name:    "tampered table",
+			edit:    func(files map[string][]byte) { files["tasks.json"] = []byte(`[]`) },
+			wantErr: "checksum mismatch for tasks.json",
+		},
+		{
+			name:    "missing manifest",
+			edit:    func(files map[string][]byte) { delete(files, ManifestName) },
+			wantErr: "missing manifest.json",
+		},
+		{
+			name: "other schema",
+			edit: func(files map[string][]byte) {
+				editManifest(files, func(m *types.ArchiveManifest) { m.Schema = "ai-helper.transcript" })
+			},
+			wantErr: "not an account archive",
+		},
+		{
+			name: "newer version",
+			edit: func(files map[string][]byte) {
+				editManifest(files, func(m *types.ArchiveManifest) { m.Version = types.AccountArchiveVersion + 1 })
+			},
+			wantErr: "unsupported archive version",
+		},
+		{
+			name: "unknown table",
+			edit: func(files map[string][]byte) {
+				editManifest(files, func(m *types.ArchiveManifest) {
+					m.Files = append(m.Files, types.ArchiveFile{Name: "auth.json", Table: "auth"})
+				})
+			},
+			wantErr: `unexpected file "auth.json"`,
+		},
+		{
+			name: "table listed twice",
+			edit: func(files map[string][]byte) {
+				editManifest(files, func(m *types.ArchiveManifest) { m.Files = append(m.Files, m.Files[0]) })
+			},
+			wantErr: "listed twice",
+		},
+		{
+			name: "wrong record count",
+			edit: func(files map[string][]byte) {
+				editManifest(files, func(m *types.ArchiveManifest) { m.Files[0].Records++ })
+			},
+			wantErr: "manifest says",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := valid()
+			tt.edit(files)
+
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for name, data := range files {
+				fw, _ := zw.Create(name)
+				fw.Write(data)
+			}
+			zw.Close()
+
+			_, _, err := Read(buf.Bytes())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadRejectsNonZip(t *testing.T) {
+	if _, _, err := Read([]byte("not a zip")); err == nil || !strings.Contains(err.Error(), "not a zip archive") {
+		t.Errorf("Read() = %v, want not a zip archive", err)
+	}
+}
@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// A reference from one table's column to the rows of another
+type reference struct {
+	column   string
+	table    string
+	required bool // rows whose reference can't be resolved are skipped
+}
+
+// identified tables get fresh IDs on import; the rest let the database
+// assign theirs
+var identified = map[string]bool{
+	"sessions":        true,
+	"messages":        true,
+	"tasks":           true,
+	"user_activities": true,
+	"memories":        true,
+}
+
+var references = map[string][]reference{
+	"sessions": {
+		{column: "parent_session_id", table: "sessions"},
+		{column: "forked_from_message_id", table: "messages"},
+	},
+	"messages": {
+		{column: "session_id", table: "sessions", required: true},
+		{column: "user_message_id", table: "messages"},
+	},
+	"tasks": {
+		{column: "session_id", table: "sessions"},
+		{column: "message_id", table: "messages"},
+	},
+	"session_summaries": {
+		{column: "session_id", table: "sessions", required: true},
+	},
+	"session_metrics": {
+		{column: "session_id", table: "sessions", required: true},
+	},
+	"user_activities": {
+		{column: "session_id", table: "sessions"},
+	},
+	"memories": {
+		{column: "source_session_id", table: "sessions"},
+		{column: "source_message_id", table: "messages"},
+	},
+}
+
+// cleared columns point at data the archive doesn't carry
+var cleared = map[string][]string{
+	"tasks": {"goal_id", "column_id"},
+}
+
+// generated columns are computed by the database, which rejects inserts
+// that set them. Archives leave them out, and they are dropped from
+// archives written before that.
+var generated = map[string][]string{
+	"messages":          {"search_vector"},
+	"tasks":             {"search_vector"},
+	"session_summaries": {"search_vector"},
+}
+
+// withoutGenerated returns row without the table's generated columns
+func withoutGenerated(table string, row Row) Row {
+	out := make(Row, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	for _, column := range generated[table] {
+		delete(out, column)
+	}
+	return out
+}
+
+// Remap prepares archived rows for import into targetUserID's account. Rows
+// get new IDs with references rewritten to match; references to rows
+// outside the archive are cleared, or the row is skipped when it can't
+// exist without them. Problems lists every skipped row.
+func Remap(tables map[string][]Row, targetUserID string) (remapped map[string][]Row, skipped map[string]int, problems []string) {
+	ids := map[string]map[string]string{}
+	for table := range identified {
+		ids[table] = map[string]string{}
+		for _, row := range tables[table] {
+			if old, ok := row["id"].(string); ok && old != "" {
+				ids[table][old] = uuid.NewString()
+			}
+		}
+	}
+
+	// Find the rows that can't be imported first, so optional references to
+	// them are cleared rather than pointed at rows that won't exist. Required
+	// references only point at earlier tables, so one pass in order will do.
+	rowProblems := map[string][]string{}
+	for _, table := range Tables {
+		rowProblems[table] = make([]string, len(tables[table]))
+		for i, row := range tables[table] {
+			problem := checkRow(table, row, ids)
+			if problem == "" {
+				continue
+			}
+			rowProblems[table][i] = problem
+			if old, ok := row["id"].(string); ok && identified[table] {
+				delete(ids[table], old)
+			}
+		}
+	}
+
+	remapped = map[string][]Row{}
+	skipped = map[string]int{}
+	for _, table := range Tables {
+		for i, row := range tables[table] {
+			if problem := rowProblems[table][i]; problem != "" {
+				skipped[table]++
+				problems = append(problems, fmt.Sprintf("%s[%d]: %s", table, i, problem))
+				continue
+			}
+			remapped[table] = append(remapped[table], remapRow(table, row, ids, targetUserID))
+		}
+	}
+	return remapped, skipped, problems
+}
+
+// checkRow reports why a row can't be imported, or "" if it can
+func checkRow(table string, row Row, ids map[string]map[string]string) string {
+	if identified[table] {
+		if old, _ := row["id"].(string); old == "" {
+			return "missing id"
+		}
+	}
+	for _, ref := range references[table] {
+		if !ref.required {
+			continue
+		}
+		old, _ := row[ref.column].(string)
+		if old == "" {
+			return fmt.Sprintf("missing %s", ref.column)
+		}
+		if _, ok := ids[ref.table][old]; !ok {
+			return fmt.Sprintf("%s %s is not in the archive", ref.column, old)
+		}
+	}
+	return ""
+}
+
+// remapRow copies a row that passed checkRow into the target account
+func remapRow(table string, row Row, ids map[string]map[string]string, targetUserID string) Row {
+	out := withoutGenerated(table, row)
+	out["user_id"] = targetUserID
+
+	if identified[table] {
+		out["id"] = ids[table][row["id"].(string)]
+	} else {
+		delete(out, "id")
+	}
+
+	for _, ref := range references[table] {
+		old, _ := row[ref.column].(string)
+		if old == "" {
+			continue
+		}
+		if id, ok := ids[ref.table][old]; ok {
+			out[ref.column] = id
+		} else {
+			out[ref.column] = nil
+		}
+	}
+
+	for _, column := range cleared[table] {
+		if _, ok := out[column]; ok {
+			out[column] = nil
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemapSkipsAndClears(t *testing.T) {
+	tests := []struct {
+		name        string
+		tables      map[string][]Row
+		wantSkipped map[string]int
+		wantProblem string // in the first problem, when rows are skipped
+		check       func(t *testing.T, remapped map[string][]Row)
+	}{
+		{
+			name: "message without its session",
+			tables: map[string][]Row{
+				"messages": {{"id": "m1", "session_id": "gone", "content": "hi"}},
+			},
+			wantSkipped: map[string]int{"messages": 1},
+			wantProblem: "messages[0]: session_id gone is not in the archive",
+		},
+		{
+			name: "row without an id",
+			tables: map[string][]Row{
+				"sessions": {{"title": "No id"}},
+			},
+			wantSkipped: map[string]int{"sessions": 1},
+			wantProblem: "sessions[0]: missing id",
+		},
+		{
+			name: "summary without a session_id",
+			tables: map[string][]Row{
+				"session_summaries": {{"summary": "Orphan"}},
+			},
+			wantSkipped: map[string]int{"session_summaries": 1},
+			wantProblem: "missing session_id",
+		},
+		{
+			name: "skipped rows take their dependents with them",
+			tables: map[string][]Row{
+				"sessions": {{"id": "s1"}},
+				"messages": {
+					{"id": "m1", "session_id": "gone"},
+					{"id": "m2", "session_id": "s1", "user_message_id": "m1"},
+				},
+				"tasks": {{"id": "t1", "session_id": "s1", "message_id": "m1"}},
+			},
+			wantSkipped: map[string]int{"messages": 1},
+			wantProblem: "messages[0]",
+			check: func(t *testing.T, remapped map[string][]Row) {
+				if got := remapped["messages"][0]["user_message_id"]; got != nil {
+					t.Errorf("reply to a skipped message links to %v", got)
+				}
+				if got := remapped["tasks"][0]["message_id"]; got != nil {
+					t.Errorf("task of a skipped message links to %v", got)
+				}
+			},
+		},
+		{
+			name: "optional references outside the archive are cleared",
+			tables: map[string][]Row{
+				"sessions": {{"id": "s1", "parent_session_id": "elsewhere"}},
+				"tasks":    {{"id": "t1", "session_id": "elsewhere", "goal_id": "g1", "column_id": "c1"}},
+				"memories": {{"id": "mem1", "source_session_id": "elsewhere"}},
+			},
+			check: func(t *testing.T, remapped map[string][]Row) {
+				for _, c := range []struct {
+					table, column string
+				}{
+					{"sessions", "parent_session_id"},
+					{"tasks", "session_id"},
+					{"tasks", "goal_id"},
+					{"tasks", "column_id"},
+					{"memories", "source_session_id"},
+				} {
+					if got := remapped[c.table][0][c.column]; got != nil {
+						t.Errorf("%s.%s = %v, want cleared", c.table, c.column, got)
+					}
+				}
+			},
+		},
+		{
+			name: "tables without ids of their own let the database assign them",
+			tables: map[string][]Row{
+				"sessions":        {{"id": "s1"}},
+				"session_metrics": {{"id": "1", "session_id": "s1"}},
+			},
+			check: func(t *testing.T, remapped map[string][]Row) {
+				if _, ok := remapped["session_metrics"][0]["id"]; ok {
+					t.Error("session_metrics kept its id")
+				}
+			},
+		},
+		{
+			name: "generated columns of older archives are dropped",
+			tables: map[string][]Row{
+				"sessions":          {{"id": "s1"}},
+				"messages":          {{"id": "m1", "session_id": "s1", "search_vector": "'hi':1"}},
+				"tasks":             {{"id": "t1", "search_vector": "'cv':1"}},
+				"session_summaries": {{"session_id": "s1", "search_vector": "'plan':1"}},
+			},
+			check: func(t *testing.T, remapped map[string][]Row) {
+				for _, table := range []string{"messages", "tasks", "session_summaries"} {
+					if _, ok := remapped[table][0]["search_vector"]; ok {
+						t.Errorf("%s kept search_vector", table)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remapped, skipped, problems := Remap(tt.tables, "u2")
+
+			if len(skipped) != len(tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+			for table, n := range tt.wantSkipped {
+				if skipped[table] != n {
+					t.Errorf("skipped[%s] = %d, want %d", table, skipped[table], n)
+				}
+			}
+			if tt.wantProblem != "" && (len(problems) == 0 || !strings.Contains(problems[0], tt.wantProblem)) {
+				t.Errorf("problems = %v, want one containing %q", problems, tt.wantProblem)
+			}
+			if tt.check != nil {
+				tt.check(t, remapped)
+			}
+		})
+	}
+}
+
+func TestRemapDoesNotChangeInput(t *testing.T) {
+	tables := map[string][]Row{
+		"sessions": {{"id": "s1", "user_id": "u1"}},
+		"messages": {{"id": "m1", "user_id": "u1", "session_id": "s1", "search_vector": "'hi':1"}},
+	}
+	Remap(tables, "u2")
+
+	if tables["sessions"][0]["id"] != "s1" || tables["messages"][0]["user_id"] != "u1" {
+		t.Errorf("Remap changed its input: %v", tables)
+	}
+	if _, ok := tables["messages"][0]["search_vector"]; !ok {
+		t.Error("Remap dropped a column from its input")
+	}
+}
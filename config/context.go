@@ -1,16 +1,38 @@
 package config
 
-import "clementus360/ai-helper/types"
+import (
+	"clementus360/ai-helper/types"
+	"time"
+)
 
 // Context configuration
 var ContextConfig = types.ContextConfig{
 	MaxTokens:                6000,
-	MaxRecentMessages:        10,
+	MaxRecentMessages:        10, // earlier messages sent as chat turns
 	MaxKeyTasks:              5,
 	SummaryMaxLength:         500,
 	MessagePriorityThreshold: 2,
 }
 
+// Session lifecycle timing, measured from a session's last activity
+var (
+	SessionIdleAfter     = 30 * time.Minute
+	SessionCloseAfter    = 4 * time.Hour
+	SessionSweepInterval = 5 * time.Minute
+)
+
+// User pattern analysis: how often the job runs, how old a user's patterns
+// get before they're recomputed, and how much history is analyzed
+var (
+	PatternAnalysisInterval = time.Hour
+	PatternRefreshAfter     = 24 * time.Hour
+	PatternLookback         = 30 * 24 * time.Hour
+)
+
+// AccountDeletionGrace is how long an account deletion can be cancelled
+// before the data is purged
+var AccountDeletionGrace = 7 * 24 * time.Hour
+
 // Activity types constants
 const (
 	ActivityTypeMessage       = "message"
@@ -20,4 +42,24 @@ const (
 	ActivityTypeTaskDeleted   = "task_deleted"
 	ActivityTypeAIResponse    = "ai_response"
 	ActivityTypeTasksCreated  = "tasks_created"
+	ActivityTypeTasksUpdated  = "tasks_updated"
+	ActivityTypeTasksDeleted  = "tasks_deleted"
+	ActivityTypeTasksBatch    = "tasks_batch"
+	ActivityTypeTasksImported = "tasks_imported"
+	ActivityTypeTemplateUsed  = "template_instantiated"
+	ActivityTypeFocusStarted  = "focus_started"
+	ActivityTypeFocusStopped  = "focus_stopped"
+
+	ActivityTypeMessageEdited      = "message_edited"
+	ActivityTypeMessageRegenerated = "message_regenerated"
+	ActivityTypeSessionForked      = "session_forked"
+	ActivityTypeSessionExported    = "session_exported"
+	ActivityTypeSessionImported    = "session_imported"
+)
+
+// Task revision actors
+const (
+	RevisionActorUser   = "user"
+	RevisionActorAI     = "ai"
+	RevisionActorSystem = "system"
 )
@@ -0,0 +1,49 @@
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var offsetRe = regexp.MustCompile(`^(\d+)\s*(m|h|d|w)$`)
+
+// ValidateOffset checks a relative offset such as "30m", "2h", "3d" or "1w"
+func ValidateOffset(offset string) error {
+	if !offsetRe.MatchString(offset) {
+		return fmt.Errorf("invalid offset %q, expected a number followed by m, h, d or w", offset)
+	}
+	return nil
+}
+
+// ApplyOffset returns the time offset from now. Minute and hour offsets are
+// exact; day and week offsets land on that day at the default time of day.
+func ApplyOffset(offset string, now time.Time, opts Options) (time.Time, error) {
+	m := offsetRe.FindStringSubmatch(offset)
+	if m == nil {
+		return time.Time{}, ValidateOffset(offset)
+	}
+	n, _ := strconv.Atoi(m[1])
+
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	at := clock{hour: opts.DefaultHour, minute: opts.DefaultMinute, set: true}
+	if opts.DefaultHour == 0 && opts.DefaultMinute == 0 {
+		at = clock{hour: 17, set: true}
+	}
+
+	switch m[2] {
+	case "m":
+		return now.Add(time.Duration(n) * time.Minute), nil
+	case "h":
+		return now.Add(time.Duration(n) * time.Hour), nil
+	case "d":
+		return atClock(now.AddDate(0, 0, n), at), nil
+	default:
+		return atClock(now.AddDate(0, 0, 7*n), at), nil
+	}
+}
@@ -0,0 +1,533 @@
+// Package dates resolves natural-language due-date phrases ("friday afternoon",
+// "by end of next week", "in 3 days") into concrete times. Parsing is fully
+// deterministic: the same phrase, reference time and location always yield the
+// same result, and phrases that could reasonably mean more than one thing are
+// rejected with an explanation instead of being guessed.
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError explains why a phrase could not be turned into a due date
+type ParseError struct {
+	Phrase    string
+	Reason    string
+	Ambiguous bool
+}
+
+func (e *ParseError) Error() string {
+	if e.Ambiguous {
+		return fmt.Sprintf("%q is ambiguous: %s", e.Phrase, e.Reason)
+	}
+	return fmt.Sprintf("could not understand %q: %s", e.Phrase, e.Reason)
+}
+
+// Options controls how relative phrases are resolved
+type Options struct {
+	// Location is the user's timezone. Defaults to UTC.
+	Location *time.Location
+	// DefaultHour and DefaultMinute are used when the phrase names a day but
+	// no time of day. When both are zero, 17:00 is used.
+	DefaultHour   int
+	DefaultMinute int
+}
+
+// LoadLocation returns the named IANA location, falling back to UTC when the
+// name is empty or unknown
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+var (
+	leadingFiller = []string{"remind me", "please", "due", "by", "before", "on", "until", "till", "for", "at", "no later than"}
+
+	clockRe      = regexp.MustCompile(`\b(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm|a\.m\.|p\.m\.)?(?:\s|$)`)
+	partOfDayRe  = regexp.MustCompile(`\b(?:in the\s+|this\s+)?(morning|noon|midday|afternoon|evening|tonight|night|midnight|end of (?:the\s+)?day|eod)\b`)
+	inDurationRe = regexp.MustCompile(`^in\s+(a|an|half an|\d+|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)\s+(minutes?|mins?|hours?|hrs?|days?|weeks?|months?)$`)
+	monthDayRe   = regexp.MustCompile(`^([a-z]+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?$`)
+	dayMonthRe   = regexp.MustCompile(`^(?:the\s+)?(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?([a-z]+)\.?(?:,?\s+(\d{4}))?$`)
+	ordinalDayRe = regexp.MustCompile(`^the\s+(\d{1,2})(?:st|nd|rd|th)$`)
+	spaceRe      = regexp.MustCompile(`\s+`)
+
+	numberWords = map[string]int{
+		"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+		"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	}
+
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "sun": time.Sunday,
+		"monday": time.Monday, "mon": time.Monday,
+		"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+		"wednesday": time.Wednesday, "wed": time.Wednesday,
+		"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"friday": time.Friday, "fri": time.Friday,
+		"saturday": time.Saturday, "sat": time.Saturday,
+	}
+
+	months = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+)
+
+// clock is a resolved time of day
+type clock struct {
+	hour, minute int
+	set          bool
+	fromPart     bool // set from a part of the day such as "tonight"
+	late         bool // "tonight", which can still mean later once 20:00 has passed
+}
+
+// ParseDue resolves phrase relative to now in the configured location.
+// Absolute timestamps (RFC3339, "2006-01-02", "2006-01-02 15:04") are accepted
+// as-is; relative phrases that resolve to a moment in the past are rejected.
+func ParseDue(phrase string, now time.Time, opts Options) (time.Time, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	defaultClock := clock{hour: opts.DefaultHour, minute: opts.DefaultMinute, set: true}
+	if opts.DefaultHour == 0 && opts.DefaultMinute == 0 {
+		defaultClock = clock{hour: 17, set: true}
+	}
+
+	original := strings.TrimSpace(phrase)
+	if original == "" {
+		return time.Time{}, &ParseError{Phrase: phrase, Reason: "the phrase is empty"}
+	}
+
+	if t, ok := parseAbsolute(original, loc, defaultClock); ok {
+		return t, nil
+	}
+
+	text := normalize(original)
+
+	// Time of day is resolved first so that what remains names only the day
+	tod, text, err := extractTimeOfDay(text, defaultClock)
+	if err != nil {
+		err.Phrase = original
+		return time.Time{}, err
+	}
+
+	due, rollForward, perr := resolveDay(text, now, tod, defaultClock)
+	if perr != nil {
+		perr.Phrase = original
+		return time.Time{}, perr
+	}
+
+	if due.Before(now) {
+		if rollForward {
+			due = due.AddDate(0, 0, 1)
+		} else {
+			return time.Time{}, &ParseError{Phrase: original, Reason: fmt.Sprintf("it resolves to %s, which has already passed", due.Format("Mon Jan 2 15:04"))}
+		}
+	}
+
+	return due, nil
+}
+
+func parseAbsolute(text string, loc *time.Location, defaultClock clock) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, text, loc); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", text, loc); err == nil {
+		return atClock(t, defaultClock), true
+	}
+	return time.Time{}, false
+}
+
+func normalize(text string) string {
+	text = strings.ToLower(text)
+	text = strings.TrimRight(text, ".!?")
+	text = spaceRe.ReplaceAllString(strings.TrimSpace(text), " ")
+
+	for stripped := true; stripped; {
+		stripped = false
+		for _, filler := range leadingFiller {
+			if text == filler {
+				return ""
+			}
+			if strings.HasPrefix(text, filler+" ") {
+				text = strings.TrimSpace(strings.TrimPrefix(text, filler+" "))
+				stripped = true
+			}
+		}
+	}
+	return text
+}
+
+// extractTimeOfDay pulls clock times ("3pm", "15:30") and parts of the day
+// ("afternoon", "tonight") out of text and returns what remains
+func extractTimeOfDay(text string, defaultClock clock) (clock, string, *ParseError) {
+	var tod clock
+	part := ""
+
+	if m := partOfDayRe.FindStringSubmatchIndex(text); m != nil {
+		part = text[m[2]:m[3]]
+		text = text[:m[0]] + " " + text[m[1]:]
+		switch part {
+		case "morning":
+			tod = clock{hour: 9, set: true}
+		case "noon", "midday":
+			tod = clock{hour: 12, set: true}
+		case "afternoon":
+			tod = clock{hour: 15, set: true}
+		case "evening":
+			tod = clock{hour: 18, set: true}
+		case "tonight", "night":
+			tod = clock{hour: 20, set: true, late: true}
+		case "midnight":
+			tod = clock{hour: 23, minute: 59, set: true}
+		default: // end of day
+			tod = defaultClock
+		}
+		tod.fromPart = true
+	}
+
+	var m []int
+	for _, candidate := range clockRe.FindAllStringSubmatchIndex(text, -1) {
+		if !isDayNumber(text, candidate) {
+			m = candidate
+			break
+		}
+	}
+	if m != nil {
+		hourText := text[m[2]:m[3]]
+		hour, _ := strconv.Atoi(hourText)
+		minute := 0
+		if m[4] != -1 {
+			minute, _ = strconv.Atoi(text[m[4]:m[5]])
+		}
+		meridiem := ""
+		if m[6] != -1 {
+			meridiem = strings.ReplaceAll(text[m[6]:m[7]], ".", "")
+		}
+		raw := strings.TrimSpace(text[m[0]:m[1]])
+		text = text[:m[0]] + " " + text[m[1]:]
+
+		if hour > 23 || minute > 59 || (meridiem != "" && (hour < 1 || hour > 12)) {
+			return clock{}, "", &ParseError{Reason: fmt.Sprintf("%q is not a valid time", raw)}
+		}
+
+		switch {
+		case meridiem == "pm" && hour < 12:
+			hour += 12
+		case meridiem == "am" && hour == 12:
+			hour = 0
+		case meridiem == "":
+			switch part {
+			case "afternoon", "evening", "tonight", "night":
+				if hour < 12 {
+					hour += 12
+				}
+			case "morning":
+				// already am
+			default:
+				// "3:30" is as ambiguous as "3"; a leading zero ("09:30")
+				// reads as 24-hour time
+				if hour >= 1 && hour <= 12 && !strings.HasPrefix(hourText, "0") {
+					return clock{}, "", &ParseError{Ambiguous: true, Reason: fmt.Sprintf("%q could be %d am or %d pm; add am/pm or use 24-hour time", raw, hour, hour)}
+				}
+			}
+		}
+		tod = clock{hour: hour, minute: minute, set: true}
+	}
+
+	text = spaceRe.ReplaceAllString(strings.TrimSpace(text), " ")
+	text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, " at"), " on"))
+	if text == "at" || text == "on" || text == "by" {
+		text = ""
+	}
+	return tod, text, nil
+}
+
+// isDayNumber reports whether a clock match is actually the day of a date
+// such as "oct 23" or "the 23rd"
+func isDayNumber(text string, m []int) bool {
+	if m[4] != -1 || m[6] != -1 {
+		return false
+	}
+	before := strings.Fields(text[:m[0]])
+	if len(before) > 0 {
+		if _, ok := months[strings.TrimSuffix(before[len(before)-1], ".")]; ok {
+			return true
+		}
+	}
+	rest := text[m[1]:]
+	if strings.HasPrefix(text[m[3]:], "st") || strings.HasPrefix(text[m[3]:], "nd") ||
+		strings.HasPrefix(text[m[3]:], "rd") || strings.HasPrefix(text[m[3]:], "th") {
+		return true
+	}
+	after := strings.Fields(rest)
+	if len(after) > 0 {
+		word := after[0]
+		if word == "of" {
+			return true
+		}
+		if _, ok := months[strings.TrimSuffix(word, ".")]; ok {
+			return true
+		}
+		if _, ok := numberUnit(word); ok {
+			return true
+		}
+	}
+	return strings.HasPrefix(text, "in ")
+}
+
+// resolveDay maps the day part of a phrase to a concrete time. rollForward
+// reports whether a result in the past should move to the next day instead
+// of being rejected (used for bare times such as "at 3pm").
+func resolveDay(text string, now time.Time, tod, defaultClock clock) (time.Time, bool, *ParseError) {
+	at := tod
+	if !at.set {
+		at = defaultClock
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch text {
+	case "":
+		if !tod.set {
+			return time.Time{}, false, &ParseError{Reason: "no day or time was given"}
+		}
+		// A bare clock time means its next occurrence; "tonight" means today,
+		// and later tonight when asked after 20:00
+		due := atClock(today, at)
+		if tod.late && due.Before(now) {
+			due = atClock(today, clock{hour: 23, minute: 59, set: true})
+		}
+		return due, !tod.fromPart, nil
+	case "today":
+		return atClock(today, at), false, nil
+	case "tomorrow", "tmrw", "tmr":
+		return atClock(today.AddDate(0, 0, 1), at), false, nil
+	case "day after tomorrow", "the day after tomorrow":
+		return atClock(today.AddDate(0, 0, 2), at), false, nil
+	case "yesterday":
+		return time.Time{}, false, &ParseError{Reason: "due dates cannot be in the past"}
+	case "end of week", "end of the week", "end of this week", "eow", "the end of the week":
+		friday := startOfWeek(today).AddDate(0, 0, 4)
+		due := atClock(friday, at)
+		if due.Before(now) {
+			return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "this week's Friday has already passed; say \"end of next week\" or name a day"}
+		}
+		return due, false, nil
+	case "end of next week", "the end of next week":
+		return atClock(startOfWeek(today).AddDate(0, 0, 11), at), false, nil
+	case "start of next week", "beginning of next week", "early next week", "the start of next week":
+		return atClock(startOfWeek(today).AddDate(0, 0, 7), at), false, nil
+	case "next week", "sometime next week":
+		return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "say \"start of next week\", \"end of next week\" or name a day"}
+	case "end of month", "end of the month", "end of this month", "eom":
+		return atClock(lastDayOfMonth(today, 0), at), false, nil
+	case "end of next month":
+		return atClock(lastDayOfMonth(today, 1), at), false, nil
+	case "next month", "sometime next month":
+		return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "say \"end of next month\" or give a date"}
+	case "weekend", "this weekend", "the weekend", "next weekend":
+		return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "the weekend spans Saturday and Sunday; name the day"}
+	}
+
+	if m := inDurationRe.FindStringSubmatch(text); m != nil {
+		return resolveDuration(m[1], m[2], now, today, tod, at)
+	}
+
+	if due, ok, err := resolveWeekday(text, now, today, at); ok || err != nil {
+		return due, false, err
+	}
+
+	if due, ok, err := resolveCalendarDate(text, now, today, at); ok || err != nil {
+		return due, false, err
+	}
+
+	return time.Time{}, false, &ParseError{Reason: "try a day (\"friday\", \"tomorrow\"), a date (\"oct 23\"), or an offset (\"in 3 days\")"}
+}
+
+func resolveDuration(amount, unit string, now, today time.Time, tod, at clock) (time.Time, bool, *ParseError) {
+	n, ok := numberWords[amount]
+	if !ok {
+		if amount == "half an" {
+			if !strings.HasPrefix(unit, "hour") {
+				return time.Time{}, false, &ParseError{Reason: "\"half an\" only works with hours"}
+			}
+			return now.Add(30 * time.Minute), false, nil
+		}
+		n, _ = strconv.Atoi(amount)
+	}
+	if n <= 0 {
+		return time.Time{}, false, &ParseError{Reason: "the offset must be positive"}
+	}
+
+	base, _ := numberUnit(unit)
+	switch base {
+	case "minute":
+		if tod.set {
+			return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "an offset in minutes can't also have a time of day"}
+		}
+		return now.Add(time.Duration(n) * time.Minute), false, nil
+	case "hour":
+		if tod.set {
+			return time.Time{}, false, &ParseError{Ambiguous: true, Reason: "an offset in hours can't also have a time of day"}
+		}
+		return now.Add(time.Duration(n) * time.Hour), false, nil
+	case "day":
+		return atClock(today.AddDate(0, 0, n), at), false, nil
+	case "week":
+		return atClock(today.AddDate(0, 0, 7*n), at), false, nil
+	default:
+		// AddDate would carry Jan 31 over into March; stop at the month's end
+		if last := lastDayOfMonth(today, n); today.Day() > last.Day() {
+			return atClock(last, at), false, nil
+		}
+		return atClock(today.AddDate(0, n, 0), at), false, nil
+	}
+}
+
+// resolveWeekday handles "friday", "this friday" and "next friday"
+func resolveWeekday(text string, now, today time.Time, at clock) (time.Time, bool, *ParseError) {
+	modifier := ""
+	name := text
+	if fields := strings.Fields(text); len(fields) == 2 && (fields[0] == "this" || fields[0] == "next" || fields[0] == "coming" || fields[0] == "upcoming") {
+		modifier, name = fields[0], fields[1]
+	}
+	wd, ok := weekdays[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+	title := wd.String()
+
+	switch modifier {
+	case "next":
+		if ahead == 0 {
+			return atClock(today.AddDate(0, 0, 7), at), true, nil
+		}
+		upcoming := today.AddDate(0, 0, ahead)
+		if startOfWeek(upcoming).Equal(startOfWeek(today)) {
+			following := upcoming.AddDate(0, 0, 7)
+			return time.Time{}, true, &ParseError{Ambiguous: true, Reason: fmt.Sprintf(
+				"it could mean %s or %s; say \"this %s\" or give the date",
+				upcoming.Format("Mon Jan 2"), following.Format("Mon Jan 2"), title)}
+		}
+		return atClock(upcoming, at), true, nil
+	default:
+		if ahead == 0 {
+			due := atClock(today, at)
+			if modifier == "this" && !due.Before(now) {
+				return due, true, nil
+			}
+			return time.Time{}, true, &ParseError{Ambiguous: true, Reason: fmt.Sprintf(
+				"today is %s; say \"today\" or \"next %s\"", today.Weekday(), title)}
+		}
+		return atClock(today.AddDate(0, 0, ahead), at), true, nil
+	}
+}
+
+// resolveCalendarDate handles "oct 23", "23rd of october 2026" and "the 23rd"
+func resolveCalendarDate(text string, now, today time.Time, at clock) (time.Time, bool, *ParseError) {
+	var monthName, dayStr, yearStr string
+	if m := ordinalDayRe.FindStringSubmatch(text); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		if day < 1 || day > 31 {
+			return time.Time{}, true, &ParseError{Reason: fmt.Sprintf("%d is not a valid day of the month", day)}
+		}
+		for offset := 0; offset < 3; offset++ {
+			candidate := time.Date(today.Year(), today.Month()+time.Month(offset), day, 0, 0, 0, 0, today.Location())
+			if candidate.Day() != day {
+				continue // e.g. the 31st in a 30-day month
+			}
+			if due := atClock(candidate, at); !due.Before(now) {
+				return due, true, nil
+			}
+		}
+		return time.Time{}, true, &ParseError{Reason: fmt.Sprintf("could not find an upcoming %s", text)}
+	} else if m := monthDayRe.FindStringSubmatch(text); m != nil {
+		monthName, dayStr, yearStr = m[1], m[2], m[3]
+	} else if m := dayMonthRe.FindStringSubmatch(text); m != nil {
+		dayStr, monthName, yearStr = m[1], m[2], m[3]
+	} else {
+		return time.Time{}, false, nil
+	}
+
+	month, ok := months[monthName]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	day, _ := strconv.Atoi(dayStr)
+
+	year := today.Year()
+	explicitYear := yearStr != ""
+	if explicitYear {
+		year, _ = strconv.Atoi(yearStr)
+	}
+
+	candidate := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	if candidate.Month() != month || day < 1 {
+		return time.Time{}, true, &ParseError{Reason: fmt.Sprintf("%s %d is not a valid date", month, day)}
+	}
+	due := atClock(candidate, at)
+	if due.Before(now) && !explicitYear {
+		due = due.AddDate(1, 0, 0)
+	}
+	return due, true, nil
+}
+
+// numberUnit maps a unit word ("hrs", "weeks") to its singular base
+func numberUnit(word string) (string, bool) {
+	switch strings.TrimSuffix(word, "s") {
+	case "minute", "min":
+		return "minute", true
+	case "hour", "hr":
+		return "hour", true
+	case "day":
+		return "day", true
+	case "week":
+		return "week", true
+	case "month":
+		return "month", true
+	}
+	return "", false
+}
+
+func atClock(day time.Time, c clock) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.hour, c.minute, 0, 0, day.Location())
+}
+
+// startOfWeek returns the Monday of the week containing day
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, day.Location())
+}
+
+func lastDayOfMonth(day time.Time, monthsAhead int) time.Time {
+	return time.Date(day.Year(), day.Month()+time.Month(monthsAhead)+1, 0, 0, 0, 0, 0, day.Location())
+}
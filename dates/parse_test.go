@@ -0,0 +1,129 @@
+package dates
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, loc) // a Wednesday
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		phrase    string
+		opts      Options
+		want      time.Time
+		ambiguous bool // when want is zero: whether the error is ambiguity
+	}{
+		// Days, with the default 17:00
+		{phrase: "tomorrow", want: at(10, 15, 17, 0)},
+		{phrase: "by tomorrow", want: at(10, 15, 17, 0)},
+		{phrase: "friday", want: at(10, 16, 17, 0)},
+		{phrase: "this friday", want: at(10, 16, 17, 0)},
+		{phrase: "in 3 days", want: at(10, 17, 17, 0)},
+		{phrase: "in two weeks", want: at(10, 28, 17, 0)},
+		{phrase: "end of next week", want: at(10, 23, 17, 0)},
+		{phrase: "end of month", want: at(10, 31, 17, 0)},
+		{phrase: "oct 23", want: at(10, 23, 17, 0)},
+		{phrase: "23rd of october", want: at(10, 23, 17, 0)},
+		{phrase: "the 20th", want: at(10, 20, 17, 0)},
+		{phrase: "tomorrow", opts: Options{DefaultHour: 9}, want: at(10, 15, 9, 0)},
+
+		// Times of day
+		{phrase: "tomorrow at 3pm", want: at(10, 15, 15, 0)},
+		{phrase: "friday afternoon", want: at(10, 16, 15, 0)},
+		{phrase: "friday at 15:30", want: at(10, 16, 15, 30)},
+		{phrase: "friday at 09:30", want: at(10, 16, 9, 30)},
+		{phrase: "friday at 3:30pm", want: at(10, 16, 15, 30)},
+		{phrase: "tonight", want: at(10, 14, 20, 0)},
+		{phrase: "at 3pm", want: at(10, 14, 15, 0)},
+		{phrase: "at 9am", want: at(10, 15, 9, 0)}, // already passed today
+		{phrase: "in 2 hours", want: at(10, 14, 12, 0)},
+
+		// Absolute
+		{phrase: "2026-11-01", want: at(11, 1, 17, 0)},
+		{phrase: "2026-11-01 08:15", want: at(11, 1, 8, 15)},
+
+		// Ambiguous
+		{phrase: "at 3", ambiguous: true},
+		{phrase: "tomorrow at 9", ambiguous: true},
+		{phrase: "friday at 3:30", ambiguous: true},
+		{phrase: "tomorrow at 12:15", ambiguous: true},
+		{phrase: "next week", ambiguous: true},
+		{phrase: "this weekend", ambiguous: true},
+		{phrase: "next friday", ambiguous: true},
+		{phrase: "wednesday", ambiguous: true},
+
+		// Invalid
+		{phrase: ""},
+		{phrase: "yesterday"},
+		{phrase: "someday"},
+		{phrase: "tomorrow at 25:00"},
+		{phrase: "feb 30"},
+		{phrase: "in 0 days"},
+	}
+
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.Location = loc
+
+		got, err := ParseDue(tt.phrase, now, opts)
+		if !tt.want.IsZero() {
+			if err != nil {
+				t.Errorf("ParseDue(%q) error: %v", tt.phrase, err)
+			} else if !got.Equal(tt.want) {
+				t.Errorf("ParseDue(%q) = %s, want %s", tt.phrase, got, tt.want)
+			}
+			continue
+		}
+
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParseDue(%q) = %s, %v; want a ParseError", tt.phrase, got, err)
+			continue
+		}
+		if perr.Ambiguous != tt.ambiguous {
+			t.Errorf("ParseDue(%q) ambiguous = %v, want %v (%v)", tt.phrase, perr.Ambiguous, tt.ambiguous, err)
+		}
+	}
+}
+
+func TestParseDueEdges(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		phrase string
+		now    time.Time
+		want   time.Time
+	}{
+		// "tonight" once the evening has begun
+		{phrase: "tonight", now: at(2026, 10, 14, 19, 59), want: at(2026, 10, 14, 20, 0)},
+		{phrase: "tonight", now: at(2026, 10, 14, 21, 30), want: at(2026, 10, 14, 23, 59)},
+		{phrase: "by tonight", now: at(2026, 10, 14, 20, 0), want: at(2026, 10, 14, 20, 0)},
+		{phrase: "tomorrow night", now: at(2026, 10, 14, 22, 0), want: at(2026, 10, 15, 20, 0)},
+
+		// Month offsets stop at the end of shorter months
+		{phrase: "in 1 month", now: at(2026, 1, 31, 10, 0), want: at(2026, 2, 28, 17, 0)},
+		{phrase: "in a month", now: at(2028, 1, 30, 10, 0), want: at(2028, 2, 29, 17, 0)},
+		{phrase: "in 3 months", now: at(2026, 5, 31, 10, 0), want: at(2026, 8, 31, 17, 0)},
+		{phrase: "in 4 months", now: at(2026, 5, 31, 10, 0), want: at(2026, 9, 30, 17, 0)},
+		{phrase: "in 2 months", now: at(2026, 12, 31, 10, 0), want: at(2027, 2, 28, 17, 0)},
+		{phrase: "in 1 month", now: at(2026, 10, 14, 10, 0), want: at(2026, 11, 14, 17, 0)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDue(tt.phrase, tt.now, Options{Location: loc})
+		if err != nil {
+			t.Errorf("ParseDue(%q) at %s error: %v", tt.phrase, tt.now, err)
+		} else if !got.Equal(tt.want) {
+			t.Errorf("ParseDue(%q) at %s = %s, want %s", tt.phrase, tt.now, got, tt.want)
+		}
+	}
+}
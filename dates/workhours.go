@@ -0,0 +1,70 @@
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkingHours is a daily window, in the user's timezone, during which
+// reminders and follow-ups should land
+type WorkingHours struct {
+	StartHour, StartMinute int
+	EndHour, EndMinute     int
+}
+
+// ParseClock parses a 24-hour "HH:MM" time of day
+func ParseClock(value string) (int, int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("invalid hour in %q", value)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid minute in %q", value)
+	}
+	return hour, minute, nil
+}
+
+// ParseWorkingHours parses a start and end "HH:MM" pair
+func ParseWorkingHours(start, end string) (WorkingHours, error) {
+	sh, sm, err := ParseClock(start)
+	if err != nil {
+		return WorkingHours{}, err
+	}
+	eh, em, err := ParseClock(end)
+	if err != nil {
+		return WorkingHours{}, err
+	}
+	if eh*60+em <= sh*60+sm {
+		return WorkingHours{}, fmt.Errorf("workday end %s must be after start %s", end, start)
+	}
+	return WorkingHours{StartHour: sh, StartMinute: sm, EndHour: eh, EndMinute: em}, nil
+}
+
+// Start returns the start of the working window on the day of t
+func (w WorkingHours) Start(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), w.StartHour, w.StartMinute, 0, 0, t.Location())
+}
+
+// End returns the end of the working window on the day of t
+func (w WorkingHours) End(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), w.EndHour, w.EndMinute, 0, 0, t.Location())
+}
+
+// Clamp moves t forward into the working window: times before the start move
+// to that day's start, times after the end move to the next day's start
+func (w WorkingHours) Clamp(t time.Time) time.Time {
+	if t.Before(w.Start(t)) {
+		return w.Start(t)
+	}
+	if t.After(w.End(t)) {
+		return w.Start(t.AddDate(0, 0, 1))
+	}
+	return t
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxImportBytes bounds the size of an uploaded account archive. The zip
+// is read from memory, so the whole upload is held until it is checked.
+const maxImportBytes = 32 << 20
+
+// StartAccountExportHandler queues an export of all the user's data
+func StartAccountExportHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := supabase.StartAccountExport(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to start account export:", err)
+		writeError(w, "Failed to start export", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, types.DataJobResponse{
+		Success: true,
+		Job:     job,
+	})
+}
+
+// GetDataJobHandler reports the progress of an export or import
+func GetDataJobHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		writeError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := supabase.GetDataJob(client, userID, jobID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to fetch data job:", err)
+		writeError(w, "Failed to fetch job", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.DataJobResponse{
+		Success: true,
+		Job:     job,
+	})
+}
+
+// DownloadAccountExportHandler serves the archive of a finished export
+func DownloadAccountExportHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		writeError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := supabase.DownloadAccountExport(client, userID, jobID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			writeError(w, "Export not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "export is"):
+			writeError(w, err.Error(), http.StatusConflict)
+		default:
+			config.Logger.Error("Failed to download export:", err)
+			writeError(w, "Failed to download export", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="ai-helper-export-`+jobID+`.zip"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ImportAccountHandler restores an account archive into the user's account.
+// With dry_run=true it only validates the archive and reports what would
+// be imported.
+func ImportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, "dry_run must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Authenticate before reading the upload, so anonymous requests can't
+	// make the server buffer archives
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.ContentLength > maxImportBytes {
+		writeError(w, "Archive is larger than 32MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		writeError(w, "Archive is too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, "Missing archive in request body", http.StatusBadRequest)
+		return
+	}
+
+	report, tables := supabase.ValidateAccountImport(data, userID)
+	report.DryRun = dryRun
+	if !report.Valid {
+		writeJSON(w, http.StatusUnprocessableEntity, types.ImportResponse{
+			Success: false,
+			Report:  report,
+		})
+		return
+	}
+	if dryRun {
+		writeJSON(w, http.StatusOK, types.ImportResponse{
+			Success: true,
+			Report:  report,
+		})
+		return
+	}
+
+	job, err := supabase.StartAccountImport(client, userID, tables)
+	if err != nil {
+		config.Logger.Error("Failed to start account import:", err)
+		writeError(w, "Failed to start import", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, types.ImportResponse{
+		Success: true,
+		Report:  report,
+		Job:     &job,
+	})
+}
+
+// DeleteAccountHandler schedules the user's account for erasure. The body
+// must carry the confirmation phrase.
+func DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var req types.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Confirmation != types.AccountDeletionConfirmation {
+		writeError(w, `Send {"confirmation": "`+types.AccountDeletionConfirmation+`"} to delete your account`, http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deletion, created, err := supabase.RequestAccountDeletion(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to schedule account deletion:", err)
+		if strings.Contains(err.Error(), "not configured") {
+			writeError(w, "Account deletion is unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeError(w, "Failed to schedule account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+		config.Logger.Info("Account deletion scheduled for user ", userID, " after ", deletion.PurgeAfter)
+	}
+	writeJSON(w, status, types.AccountDeletionResponse{
+		Success:  true,
+		Deletion: deletion,
+	})
+}
+
+// GetAccountDeletionHandler shows the user's latest deletion request
+func GetAccountDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deletion, err := supabase.GetAccountDeletion(client, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no account deletion") {
+			writeError(w, "No account deletion requested", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to fetch account deletion:", err)
+		writeError(w, "Failed to fetch account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.AccountDeletionResponse{
+		Success:  true,
+		Deletion: deletion,
+	})
+}
+
+// CancelAccountDeletionHandler withdraws a scheduled deletion
+func CancelAccountDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deletion, err := supabase.CancelAccountDeletion(client, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no account deletion") {
+			writeError(w, "No account deletion that can still be cancelled", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to cancel account deletion:", err)
+		writeError(w, "Failed to cancel account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.AccountDeletionResponse{
+		Success:  true,
+		Deletion: deletion,
+	})
+}
+
+// GetDeletionReceiptHandler serves the signed receipt of a completed
+// deletion. It takes no Authorization header, since the account is gone;
+// the deletion ID acts as the credential.
+func GetDeletionReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	deletionID := r.PathValue("id")
+	if _, err := uuid.Parse(deletionID); err != nil {
+		writeError(w, "Invalid deletion ID", http.StatusBadRequest)
+		return
+	}
+
+	receipt, err := supabase.GetDeletionReceipt(supabase.ServiceClient, deletionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to fetch deletion receipt:", err)
+		writeError(w, "Failed to fetch receipt", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.DeletionReceiptResponse{
+		Success: true,
+		Receipt: receipt,
+		Valid:   supabase.VerifyDeletionReceipt(receipt),
+	})
+}
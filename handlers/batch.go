@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// BatchTasksHandler applies a list of create, update and delete operations and
+// records a single aggregated activity for the whole batch
+func BatchTasksHandler(w http.ResponseWriter, r *http.Request) {
+	var req types.TaskBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		config.Logger.Warn("Failed to decode batch JSON:", err)
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Mode == "" {
+		req.Mode = supabase.BatchModeAtomic
+	}
+	if req.Mode != supabase.BatchModeAtomic && req.Mode != supabase.BatchModeBestEffort {
+		writeError(w, "Invalid mode, expected atomic or best_effort", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, "No operations provided", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > supabase.MaxBatchOperations {
+		writeError(w, fmt.Sprintf("Too many operations, the limit is %d", supabase.MaxBatchOperations), http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+
+	results, rolledBack := supabase.ApplyTaskBatch(client, userID, profile, req.Mode, req.Operations)
+
+	resp := types.TaskBatchResponse{
+		Mode:       req.Mode,
+		Results:    results,
+		RolledBack: rolledBack,
+	}
+	counts := map[string]int{}
+	var taskIDs []string
+	var createdSessions, completedSessions []string
+	completed := 0
+	for i, result := range results {
+		if result.RollbackError != "" {
+			resp.RollbackFailed++
+		}
+		if !result.Success {
+			resp.Failed++
+			continue
+		}
+		resp.Applied++
+		counts[result.Op]++
+		taskIDs = append(taskIDs, result.ID)
+		if result.Op == "update" && req.Operations[i].Updates["status"] == "completed" {
+			completed++
+		}
+
+		if result.Task != nil && result.Task.SessionID != nil {
+			if result.Op == "create" {
+				createdSessions = append(createdSessions, *result.Task.SessionID)
+			} else if result.Op == "update" && req.Operations[i].Updates["status"] == "completed" {
+				completedSessions = append(completedSessions, *result.Task.SessionID)
+			}
+		}
+	}
+	resp.Success = resp.Failed == 0 && resp.RollbackFailed == 0
+
+	// One aggregated activity instead of one per operation
+	if resp.Applied > 0 {
+		go func() {
+			if err := supabase.TrackUserActivity(client, userID, "", config.ActivityTypeTasksBatch,
+				fmt.Sprintf("Batch: %d created, %d updated, %d deleted", counts["create"], counts["update"], counts["delete"]),
+				map[string]interface{}{
+					"mode":      req.Mode,
+					"created":   counts["create"],
+					"updated":   counts["update"],
+					"deleted":   counts["delete"],
+					"completed": completed,
+					"failed":    resp.Failed,
+					"task_ids":  taskIDs,
+				}); err != nil {
+				config.Logger.Warn("TrackUserActivity failed:", err)
+			}
+
+			for _, sessionID := range createdSessions {
+				if err := supabase.IncrementSessionCounter(client, sessionID, "task_created"); err != nil {
+					config.Logger.Warn("Failed to increment task_created session counter:", err)
+				}
+			}
+			for _, sessionID := range completedSessions {
+				if err := supabase.IncrementSessionCounter(client, sessionID, "task_completed"); err != nil {
+					config.Logger.Warn("Failed to increment task_completed session counter:", err)
+				}
+				if err := supabase.RefreshSessionEngagement(client, sessionID, userID); err != nil {
+					config.Logger.Warn("Failed to refresh session engagement:", err)
+				}
+			}
+		}()
+	}
+
+	status := http.StatusOK
+	switch {
+	case resp.RollbackFailed > 0:
+		status = http.StatusInternalServerError
+		resp.ErrorMessage = "Batch failed and some operations could not be rolled back"
+	case req.Mode == supabase.BatchModeAtomic && !resp.Success:
+		status = http.StatusUnprocessableEntity
+		resp.ErrorMessage = "Batch was not applied"
+	case !resp.Success:
+		status = http.StatusMultiStatus
+	}
+
+	writeJSON(w, status, resp)
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// GetColumnsHandler lists the user's board columns in order
+func GetColumnsHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	columns, err := supabase.GetBoardColumns(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch board columns:", err)
+		writeError(w, "Failed to fetch columns", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.ColumnsResponse{
+		Success: true,
+		Columns: columns,
+	})
+}
+
+// CreateColumnHandler adds a column at the end of the board
+func CreateColumnHandler(w http.ResponseWriter, r *http.Request) {
+	var column types.BoardColumn
+	if err := json.NewDecoder(r.Body).Decode(&column); err != nil {
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	created, err := supabase.CreateBoardColumn(client, userID, column)
+	if err != nil {
+		config.Logger.Warn("Failed to create board column:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, types.ColumnResponse{
+		Success: true,
+		Column:  created,
+	})
+}
+
+// UpdateColumnHandler renames a column or changes its status mapping
+func UpdateColumnHandler(w http.ResponseWriter, r *http.Request) {
+	columnID := r.PathValue("id")
+	if _, err := uuid.Parse(columnID); err != nil {
+		writeError(w, "Invalid column ID", http.StatusBadRequest)
+		return
+	}
+
+	var updates map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	updated, err := supabase.UpdateBoardColumn(client, userID, columnID, updates)
+	if err != nil {
+		config.Logger.Warn("Failed to update board column:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.ColumnResponse{
+		Success: true,
+		Column:  updated,
+	})
+}
+
+// DeleteColumnHandler deletes a column, leaving its tasks off the board
+func DeleteColumnHandler(w http.ResponseWriter, r *http.Request) {
+	columnID := r.PathValue("id")
+	if _, err := uuid.Parse(columnID); err != nil {
+		writeError(w, "Invalid column ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	moved, err := supabase.DeleteBoardColumn(client, userID, columnID)
+	if err != nil {
+		config.Logger.Error("Failed to delete board column:", err)
+		writeError(w, "Failed to delete column", http.StatusInternalServerError)
+		return
+	}
+
+	changes := make([]supabase.TaskChange, len(moved))
+	for i := range moved {
+		after := moved[i]
+		after.ColumnID = nil
+		after.Position = ""
+		changes[i] = supabase.TaskChange{Before: &moved[i], After: &after}
+	}
+	if err := supabase.RecordTaskRevisions(client, userID, changes, config.RevisionActorUser, ""); err != nil {
+		config.Logger.Warn("Failed to record task revisions:", err)
+	}
+
+	writeJSON(w, http.StatusOK, types.BaseResponse{
+		Success: true,
+		Message: "Column deleted successfully",
+	})
+}
+
+// ReorderTaskHandler moves a task within or between board columns
+func ReorderTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var req types.ReorderTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(req.TaskID); err != nil {
+		writeError(w, "Invalid task_id", http.StatusBadRequest)
+		return
+	}
+	if req.TaskID == req.AfterID || req.TaskID == req.BeforeID {
+		writeError(w, "A task can't be placed next to itself", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	before, after, err := supabase.ReorderTask(client, userID, req)
+	if err != nil {
+		config.Logger.Warn("Failed to reorder task:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := supabase.RecordTaskRevision(client, userID, &before, &after, config.RevisionActorUser, ""); err != nil {
+		config.Logger.Warn("Failed to record task revision:", err)
+	}
+
+	writeJSON(w, http.StatusOK, types.TaskResponse{
+		Success: true,
+		Task:    after,
+	})
+}
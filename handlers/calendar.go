@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/ical"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CalendarFeedHandler serves the user's tasks with due dates as an iCalendar feed.
+// Calendar clients can't send Bearer JWTs, so it authenticates with a feed token
+// in the query string and reads through the service client.
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	kind := ical.Kind(q.Get("type"))
+	if kind == "" {
+		kind = ical.KindEvent
+	}
+	if kind != ical.KindEvent && kind != ical.KindTodo {
+		http.Error(w, "Invalid type, expected event or todo", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := supabase.GetUserIDForFeedToken(supabase.ServiceClient, q.Get("token"))
+	if err != nil {
+		config.Logger.Warn("Rejected calendar feed request:", err)
+		http.Error(w, "Invalid or revoked feed token", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := supabase.GetTasksWithDueDates(supabase.ServiceClient, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch tasks for calendar feed:", err)
+		http.Error(w, "Failed to fetch tasks", http.StatusInternalServerError)
+		return
+	}
+
+	feed := ical.Render(tasks, ical.Options{Kind: kind, Now: time.Now()})
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(feed))
+}
+
+// CreateCalendarTokenHandler issues a new feed token, revoking any previous one
+func CreateCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := supabase.CreateCalendarFeedToken(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to create calendar feed token:", err)
+		writeError(w, "Failed to create calendar feed token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, types.CalendarFeedResponse{
+		Success: true,
+		Token:   token,
+		FeedURL: "/tasks/calendar.ics?token=" + url.QueryEscape(token),
+	})
+}
+
+// RevokeCalendarTokenHandler revokes the user's feed token so subscribed
+// calendars stop receiving updates
+func RevokeCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := supabase.RevokeCalendarFeedTokens(client, userID); err != nil {
+		config.Logger.Error("Failed to revoke calendar feed token:", err)
+		writeError(w, "Failed to revoke calendar feed token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.BaseResponse{
+		Success: true,
+		Message: "Calendar feed token revoked",
+	})
+}
@@ -2,15 +2,18 @@ package handlers
 
 import (
 	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/dates"
 	"clementus360/ai-helper/llm"
 	"clementus360/ai-helper/supabase"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	supabasego "github.com/supabase-community/supabase-go"
 )
 
 func ChatHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,13 +41,19 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The profile is fetched once and passed to everything that needs it
+	profile, err := supabase.GetUserProfile(supabaseClient, userId)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+
 	// Get or create active session
 	var sessionID string
 	if req.SessionID != "" {
 		sessionID = req.SessionID // Use provided session
 	} else {
 		var err error
-		sessionID, err = supabase.GetOrCreateActiveSession(supabaseClient, userId, req.ForceNew) // Create new one
+		sessionID, err = supabase.GetOrCreateActiveSession(supabaseClient, userId, profile, req.ForceNew) // Create new one
 		if err != nil {
 			config.Logger.Error("Failed to get or create session:", err)
 			writeError(w, "Could not manage session", http.StatusInternalServerError)
@@ -53,7 +62,7 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get SMART context instead of basic context
-	smartContext, err := supabase.BuildSmartContext(supabaseClient, sessionID, userId)
+	smartContext, err := supabase.BuildSmartContext(supabaseClient, sessionID, userId, profile)
 	if err != nil {
 		config.Logger.Warn("Failed to get smart context:", err)
 		// Continue with basic context as fallback
@@ -61,25 +70,37 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		smartContext = types.SmartContext{
 			Summary:        basicContext.Summary,
 			RecentMessages: basicContext.RecentMessages,
+			Profile:        profile,
 		}
 	}
 
+	// Recall long-term memories relevant to this message
+	if memories, err := supabase.RecallMemories(supabaseClient, userId, req.Message, 5); err != nil {
+		config.Logger.Warn("Failed to recall memories:", err)
+	} else {
+		smartContext.Memories = memories
+	}
+
 	// Save the user message
-	userMessageId, err := supabase.SaveMessage(supabaseClient, userId, sessionID, "user", "", req.Message)
+	userMessageId, err := supabase.SaveMessage(supabaseClient, userId, sessionID, "user", "", req.Message, "")
 	if err != nil {
 		config.Logger.Error("Failed to save message:", err)
 		writeError(w, "Could not save message", http.StatusInternalServerError)
 		return
 	}
 
-	// Track user message activity
+	// Track user message activity and its mood and topics
+	sentAt := time.Now()
 	go func() {
 		if err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, "message", req.Message, map[string]interface{}{
 			"message_length": len(req.Message),
-			"timestamp":      time.Now(),
+			"timestamp":      sentAt,
 		}); err != nil {
 			config.Logger.Warn("TrackUserActivity failed:", err)
 		}
+		if err := supabase.RecordMessageSignals(supabaseClient, sessionID, userId, req.Message, sentAt); err != nil {
+			config.Logger.Warn("Failed to record message signals:", err)
+		}
 	}()
 
 	// Generate AI response with enhanced context
@@ -93,7 +114,7 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save AI response
-	messageId, err := supabase.SaveMessage(supabaseClient, userId, sessionID, "ai", userMessageId, structuredResp.Response)
+	messageId, err := supabase.SaveMessage(supabaseClient, userId, sessionID, "ai", userMessageId, structuredResp.Response, structuredResp.PromptVersion)
 	if err != nil {
 		config.Logger.Warn("Failed to save AI message:", err)
 	}
@@ -108,8 +129,43 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// Apply the tasks, memories and templates the assistant asked for
+	tasks, warnings := applyAssistantActions(supabaseClient, userId, sessionID, messageId, userMessageId, req.Timezone, smartContext, structuredResp)
+
+	// Update session metrics asynchronously
+	go func() {
+		if err := supabase.TouchSession(supabaseClient, userId, sessionID); err != nil {
+			config.Logger.Warn("Failed to mark session active:", err)
+		}
+		if err := supabase.IncrementSessionCounter(supabaseClient, sessionID, "message"); err != nil {
+			config.Logger.Warn("Failed to incemment session counter:", err)
+		}
+		if err := supabase.UpdateSessionSummaryIfNeeded(supabaseClient, sessionID, userId); err != nil {
+			config.Logger.Warn("Failed to update session summary:", err)
+		}
+	}()
+
+	// Send response
+	resp := types.ChatResponse{
+		Success:     true,
+		UserMessage: req.Message,
+		AIResponse:  structuredResp.Response,
+		ActionItems: tasks,
+		Warnings:    warnings,
+		SessionID:   sessionID,
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// applyAssistantActions saves the action items, task updates and deletions,
+// template instantiations and memories in an assistant reply. messageId is
+// the reply's ID and userMessageId the message it answers. It returns the
+// tasks created and warnings about changes that couldn't be made.
+func applyAssistantActions(supabaseClient *supabasego.Client, userId, sessionID, messageId, userMessageId, timezone string, smartContext types.SmartContext, structuredResp llm.GeminiStructuredResponse) ([]types.Task, []string) {
 	// Save action items and track activity
 	var tasks []types.Task
+	var warnings []string
 	if len(structuredResp.ActionItems) > 0 {
 		for _, item := range structuredResp.ActionItems {
 			tasks = append(tasks, types.Task{
@@ -122,12 +178,17 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 				CreatedAt:   time.Now(),
 			})
 		}
-		if err := supabase.SaveTasks(supabaseClient, userId, tasks); err != nil {
+		if saved, err := supabase.SaveTasks(supabaseClient, userId, smartContext.Profile, tasks); err != nil {
 			config.Logger.Warn("Failed to save AI-suggested tasks:", err)
 		} else {
+			tasks = saved
+			if err := supabase.RecordTaskRevisions(supabaseClient, userId, supabase.TaskCreations(saved), config.RevisionActorAI, messageId); err != nil {
+				config.Logger.Warn("Failed to record task revisions:", err)
+			}
+
 			// Track task creation activity
 			go func() {
-				if err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, "tasks_created", fmt.Sprintf("Created %d AI-suggested tasks", len(tasks)), map[string]interface{}{
+				if err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, config.ActivityTypeTasksCreated, fmt.Sprintf("Created %d AI-suggested tasks", len(tasks)), map[string]interface{}{
 					"task_count":   len(tasks),
 					"ai_suggested": true,
 				}); err != nil {
@@ -141,170 +202,220 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Store durable facts the assistant picked up
+	if len(structuredResp.Remember) > 0 {
+		go func() {
+			if err := supabase.SaveMemories(supabaseClient, userId, sessionID, userMessageId, structuredResp.Remember); err != nil {
+				config.Logger.Warn("Failed to save memories:", err)
+			}
+		}()
+	}
+
+	// Expand templates the assistant chose to instantiate
+	for _, inst := range structuredResp.InstantiateTemplates {
+		var tmpl *types.TaskTemplate
+		for i := range smartContext.Templates {
+			if smartContext.Templates[i].ID == inst.TemplateID {
+				tmpl = &smartContext.Templates[i]
+				break
+			}
+		}
+		if tmpl == nil {
+			config.Logger.Warn("Attempted to instantiate unknown template:", inst.TemplateID)
+			continue
+		}
+
+		created, err := supabase.InstantiateTemplate(supabaseClient, userId, smartContext.Profile, *tmpl, inst.Values, sessionID, messageId, config.RevisionActorAI)
+		if err != nil {
+			config.Logger.Warn("Failed to instantiate template:", inst.TemplateID, "error:", err)
+			continue
+		}
+		tasks = append(tasks, created...)
+
+		go func(name string, count int) {
+			if err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, config.ActivityTypeTemplateUsed,
+				fmt.Sprintf("Assistant created %d tasks from template %s", count, name), map[string]interface{}{
+					"template_id":  inst.TemplateID,
+					"task_count":   count,
+					"ai_suggested": true,
+				}); err != nil {
+				config.Logger.Warn("Failed to track user activity ", err)
+			}
+			for i := 0; i < count; i++ {
+				if err := supabase.IncrementSessionCounter(supabaseClient, sessionID, "task_created"); err != nil {
+					config.Logger.Warn("Failed to increment task_created session counter:", err)
+				}
+			}
+		}(tmpl.Name, len(created))
+	}
+
 	// After getting smartContext, add validation data:
 	keyTasks := smartContext.KeyTasks // You already have this in smartContext
 
 	// Replace your task deletion section with:
 	if len(structuredResp.DeleteTasks) > 0 {
-		go func() {
-			deletedCount := 0
-			for _, taskID := range structuredResp.DeleteTasks {
-				// Add validation
-				if taskID == "" {
-					config.Logger.Warn("Empty task ID in delete request")
-					continue
-				}
+		deletedCount := 0
+		for _, taskID := range structuredResp.DeleteTasks {
+			// Add validation
+			if taskID == "" {
+				config.Logger.Warn("Empty task ID in delete request")
+				continue
+			}
 
-				// Verify task belongs to user by checking if it's in keyTasks
-				found := false
-				for _, task := range keyTasks {
-					if task.ID == taskID {
-						found = true
-						break
-					}
+			// Verify task belongs to user by checking if it's in keyTasks
+			var existing *types.Task
+			for i := range keyTasks {
+				if keyTasks[i].ID == taskID {
+					existing = &keyTasks[i]
+					break
 				}
+			}
 
-				if !found {
-					config.Logger.Warn("Attempted to delete non-existent or unauthorized task:", taskID)
-					continue
-				}
+			if existing == nil {
+				config.Logger.Warn("Attempted to delete non-existent or unauthorized task:", taskID)
+				continue
+			}
 
-				if err := supabase.DeleteTask(supabaseClient, taskID, userId); err != nil {
-					config.Logger.Warn("Failed to delete assistant-suggested task:", taskID, "error:", err)
-				} else {
-					deletedCount++
-					config.Logger.Info("AI successfully deleted task:", taskID)
+			if err := supabase.DeleteTask(supabaseClient, taskID, userId); err != nil {
+				config.Logger.Warn("Failed to delete assistant-suggested task:", taskID, "error:", err)
+			} else {
+				deletedCount++
+				config.Logger.Info("AI successfully deleted task:", taskID)
+				if err := supabase.RecordTaskRevision(supabaseClient, userId, existing, nil, config.RevisionActorAI, messageId); err != nil {
+					config.Logger.Warn("Failed to record task revision:", err)
 				}
 			}
+		}
 
-			if deletedCount > 0 {
-				_ = supabase.TrackUserActivity(supabaseClient, userId, sessionID, "tasks_deleted",
+		if deletedCount > 0 {
+			go func() {
+				_ = supabase.TrackUserActivity(supabaseClient, userId, sessionID, config.ActivityTypeTasksDeleted,
 					fmt.Sprintf("Assistant deleted %d tasks", deletedCount), map[string]interface{}{
 						"deleted_count": deletedCount,
 						"task_ids":      structuredResp.DeleteTasks,
 					})
-			}
-		}()
+			}()
+		}
 	}
 
 	// Replace your task update section with:
 	if len(structuredResp.UpdateTasks) > 0 {
-		go func() {
-			updatedCount := 0
-			for _, update := range structuredResp.UpdateTasks {
-				// Add validation
-				if update.ID == "" {
-					config.Logger.Warn("Empty task ID in update request")
-					continue
-				}
-
-				// Verify task belongs to user
-				found := false
+		// Resolve due dates now so the ones that can't be read are reported.
+		// A nil entry clears the due date.
+		dueOpts := dueDateOptions(smartContext.Profile, timezone)
+		dueDates := map[int]interface{}{}
+		for i, update := range structuredResp.UpdateTasks {
+			if update.DueDate == nil {
+				continue
+			}
+			phrase := strings.TrimSpace(*update.DueDate)
+			if phrase == "" || strings.EqualFold(phrase, "none") {
+				dueDates[i] = nil
+			} else if due, err := dates.ParseDue(phrase, time.Now(), dueOpts); err != nil {
 				for _, task := range keyTasks {
 					if task.ID == update.ID {
-						found = true
-						break
+						warnings = append(warnings, fmt.Sprintf("Couldn't change the due date of %q: %v", task.Title, err))
 					}
 				}
+			} else {
+				dueDates[i] = due
+			}
+		}
 
-				if !found {
-					config.Logger.Warn("Attempted to update non-existent or unauthorized task:", update.ID)
-					continue
+		updatedCount := 0
+		for i, update := range structuredResp.UpdateTasks {
+			// Add validation
+			if update.ID == "" {
+				config.Logger.Warn("Empty task ID in update request")
+				continue
+			}
+
+			// Verify task belongs to user
+			var existing *types.Task
+			for i := range keyTasks {
+				if keyTasks[i].ID == update.ID {
+					existing = &keyTasks[i]
+					break
 				}
+			}
 
-				payload := map[string]interface{}{}
-				hasUpdates := false
+			if existing == nil {
+				config.Logger.Warn("Attempted to update non-existent or unauthorized task:", update.ID)
+				continue
+			}
 
-				if update.Title != "" {
-					payload["title"] = update.Title
-					hasUpdates = true
-				}
-				if update.Description != "" {
-					payload["description"] = update.Description
-					hasUpdates = true
-				}
-				if update.Status != "" {
-					// Validate status
-					validStatuses := []string{"pending", "completed", "cancelled"}
-					isValid := false
-					for _, status := range validStatuses {
-						if update.Status == status {
-							isValid = true
-							break
-						}
-					}
-					if !isValid {
-						config.Logger.Warn("Invalid status for task update:", update.Status)
-						continue
-					}
-					payload["status"] = update.Status
-					hasUpdates = true
-				}
+			payload := map[string]interface{}{}
+			hasUpdates := false
 
-				// Handle DueDate updates - including clearing the date
-				// Check if DueDate field was explicitly set in the update request
-				if update.DueDate != nil {
-					if !update.DueDate.IsZero() {
-						payload["due_date"] = update.DueDate
-					} else {
-						// Clear the due date by setting it to null
-						payload["due_date"] = nil
+			if update.Title != "" {
+				payload["title"] = update.Title
+				hasUpdates = true
+			}
+			if update.Description != "" {
+				payload["description"] = update.Description
+				hasUpdates = true
+			}
+			if update.Status != "" {
+				// Validate status
+				validStatuses := []string{"pending", "completed", "cancelled"}
+				isValid := false
+				for _, status := range validStatuses {
+					if update.Status == status {
+						isValid = true
+						break
 					}
-					hasUpdates = true
 				}
-
-				// Handle Decision updates if applicable
-				if update.Decision != "" {
-					payload["decision"] = update.Decision
-					hasUpdates = true
+				if !isValid {
+					config.Logger.Warn("Invalid status for task update:", update.Status)
+					continue
 				}
+				payload["status"] = update.Status
+				hasUpdates = true
+			}
 
-				// Note: We need to check the UpdateTasks struct definition to handle
-				// FollowUpDueAt and FollowedUp properly without nil pointer errors
+			// Due date changes, including clearing it
+			if due, ok := dueDates[i]; ok {
+				payload["due_date"] = due
+				hasUpdates = true
+			}
 
-				if hasUpdates {
-					if updatedTask, err := supabase.UpdateTask(supabaseClient, update.ID, userId, payload); err != nil {
-						config.Logger.Warn("Failed to update assistant-suggested task:", update.ID, "error:", err)
-					} else {
-						updatedCount++
-						config.Logger.Info("AI successfully updated task:", update.ID, "changes:", payload)
-						config.Logger.Info("Updated task details:", updatedTask.Title, updatedTask.Status)
-					}
+			// Handle Decision updates if applicable
+			if update.Decision != "" {
+				payload["decision"] = update.Decision
+				hasUpdates = true
+			}
+
+			// Note: We need to check the UpdateTasks struct definition to handle
+			// FollowUpDueAt and FollowedUp properly without nil pointer errors
+
+			if hasUpdates {
+				if updatedTask, err := supabase.UpdateTask(supabaseClient, update.ID, userId, payload); err != nil {
+					config.Logger.Warn("Failed to update assistant-suggested task:", update.ID, "error:", err)
 				} else {
-					config.Logger.Warn("No valid fields to update for task:", update.ID)
+					updatedCount++
+					config.Logger.Info("AI successfully updated task:", update.ID, "changes:", payload)
+					config.Logger.Info("Updated task details:", updatedTask.Title, updatedTask.Status)
+					if err := supabase.RecordTaskRevision(supabaseClient, userId, existing, &updatedTask, config.RevisionActorAI, messageId); err != nil {
+						config.Logger.Warn("Failed to record task revision:", err)
+					}
 				}
+			} else {
+				config.Logger.Warn("No valid fields to update for task:", update.ID)
 			}
+		}
 
-			if updatedCount > 0 {
-				_ = supabase.TrackUserActivity(supabaseClient, userId, sessionID, "tasks_updated",
+		if updatedCount > 0 {
+			go func() {
+				_ = supabase.TrackUserActivity(supabaseClient, userId, sessionID, config.ActivityTypeTasksUpdated,
 					fmt.Sprintf("Assistant updated %d tasks", updatedCount), map[string]interface{}{
 						"updated_count": updatedCount,
 						"updates":       structuredResp.UpdateTasks,
 					})
-			}
-		}()
-	}
-
-	// Update session metrics asynchronously
-	go func() {
-		if err := supabase.IncrementSessionCounter(supabaseClient, sessionID, "message"); err != nil {
-			config.Logger.Warn("Failed to incemment session counter:", err)
-		}
-		if err := supabase.UpdateSessionSummaryIfNeeded(supabaseClient, sessionID, userId); err != nil {
-			config.Logger.Warn("Failed to update session summary:", err)
+			}()
 		}
-	}()
-
-	// Send response
-	resp := types.ChatResponse{
-		Success:     true,
-		UserMessage: req.Message,
-		AIResponse:  structuredResp.Response,
-		ActionItems: tasks,
-		SessionID:   sessionID,
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	return tasks, warnings
 }
 
 func GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
@@ -317,6 +428,15 @@ func GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "Invalid session_id", http.StatusBadRequest)
 		return
 	}
+	limit, cursor, err := pageParams(r, 50, 200)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Clients that don't page still get the whole conversation
+	if q := r.URL.Query(); q.Get("limit") == "" && q.Get("cursor") == "" {
+		limit = 0
+	}
 
 	// Auth check
 	authHeader := r.Header.Get("Authorization")
@@ -331,7 +451,7 @@ func GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	messages, err := supabase.GetMessages(client, sessionID, userID)
+	messages, page, err := supabase.GetMessages(client, sessionID, userID, limit, cursor)
 	if err != nil {
 		config.Logger.Error("Failed to fetch messages:", err)
 		writeError(w, "Could not fetch messages", http.StatusInternalServerError)
@@ -339,7 +459,9 @@ func GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, types.GetMessagesResponse{
-		Success:  true,
-		Messages: messages,
+		Success:    true,
+		Messages:   messages,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
 	})
 }
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/transcript"
+	"clementus360/ai-helper/types"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// maxTranscriptImportBytes caps the size of a transcript import body
+const maxTranscriptImportBytes = 32 << 20
+
+// ExportSessionHandler downloads a session as a Markdown, JSON or HTML
+// transcript
+func ExportSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		writeError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = transcript.FormatMarkdown
+	}
+	if format != transcript.FormatMarkdown && format != transcript.FormatJSON && format != transcript.FormatHTML {
+		writeError(w, "format must be md, json or html", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	t, err := supabase.LoadTranscript(client, userID, sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to load transcript:", err)
+		writeError(w, "Failed to export session", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+	loc := supabase.ProfileLocation(profile)
+
+	var body []byte
+	var contentType string
+	switch format {
+	case transcript.FormatJSON:
+		body, err = transcript.JSON(t)
+		contentType = "application/json"
+	case transcript.FormatHTML:
+		body, err = transcript.HTML(t, loc)
+		contentType = "text/html; charset=utf-8"
+	default:
+		body = transcript.Markdown(t, loc)
+		contentType = "text/markdown; charset=utf-8"
+	}
+	if err != nil {
+		config.Logger.Error("Failed to render transcript:", err)
+		writeError(w, "Failed to export session", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, sessionID, config.ActivityTypeSessionExported, t.Session.Title, map[string]interface{}{
+			"format":   format,
+			"messages": len(t.Messages),
+		}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, exportFilename(t.Session.Title), format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// ImportSessionHandler recreates a session from a JSON transcript, as
+// downloaded from ExportSessionHandler, as a new session of the caller's
+func ImportSessionHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTranscriptImportBytes))
+	if err != nil {
+		writeError(w, "Transcript larger than 32MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	t, err := transcript.Parse(data)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := supabase.ImportTranscript(client, userID, t)
+	if err != nil {
+		config.Logger.Error("Failed to import transcript:", err)
+		writeError(w, "Failed to import session", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, session.ID, config.ActivityTypeSessionImported, session.Title, map[string]interface{}{
+			"messages": len(t.Messages),
+			"tasks":    len(t.Tasks),
+		}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	writeJSON(w, http.StatusCreated, types.ImportTranscriptResponse{
+		Success:  true,
+		Session:  session,
+		Messages: len(t.Messages),
+		Tasks:    len(t.Tasks),
+	})
+}
+
+// exportFilename turns a session title into a safe file name
+func exportFilename(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-")
+	if len(name) > 60 {
+		name = strings.Trim(name[:60], "-")
+	}
+	if name == "" {
+		return "session"
+	}
+	return name
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StartTimerHandler starts a timer or pomodoro on a task
+func StartTimerHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.PathValue("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		writeError(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.StartTimerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := supabase.GetSingleTask(client, userID, taskID)
+	if err != nil || len(tasks) == 0 {
+		writeError(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	running, err := supabase.GetRunningFocusSession(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to check running focus session:", err)
+		writeError(w, "Failed to start timer", http.StatusInternalServerError)
+		return
+	}
+	if running != nil {
+		writeError(w, fmt.Sprintf("A timer is already running on task %s; stop it first", running.TaskID), http.StatusConflict)
+		return
+	}
+
+	session, err := supabase.StartFocusSession(client, userID, taskID, req)
+	if errors.Is(err, supabase.ErrFocusSessionRunning) {
+		writeError(w, "A timer is already running; stop it first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		config.Logger.Warn("Failed to start focus session:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, req.SessionID, config.ActivityTypeFocusStarted,
+			fmt.Sprintf("Started %s on %s", session.Kind, tasks[0].Title), map[string]interface{}{
+				"task_id":         taskID,
+				"kind":            session.Kind,
+				"planned_minutes": session.PlannedMinutes,
+			}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	writeJSON(w, http.StatusCreated, types.FocusSessionResponse{
+		Success: true,
+		Session: session,
+	})
+}
+
+// StopTimerHandler stops the running timer on a task
+func StopTimerHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.PathValue("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		writeError(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	running, err := supabase.GetRunningFocusSession(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to check running focus session:", err)
+		writeError(w, "Failed to stop timer", http.StatusInternalServerError)
+		return
+	}
+	if running == nil || running.TaskID != taskID {
+		writeError(w, "No timer is running on this task", http.StatusConflict)
+		return
+	}
+
+	session, err := supabase.StopFocusSession(client, userID, *running)
+	if err != nil {
+		config.Logger.Warn("Failed to stop focus session:", err)
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	go func() {
+		sessionID := ""
+		if session.SessionID != nil {
+			sessionID = *session.SessionID
+		}
+		if err := supabase.TrackUserActivity(client, userID, sessionID, config.ActivityTypeFocusStopped,
+			fmt.Sprintf("Focused for %d minutes", session.DurationSeconds/60), map[string]interface{}{
+				"task_id":          taskID,
+				"kind":             session.Kind,
+				"duration_seconds": session.DurationSeconds,
+				"completed":        session.Completed,
+			}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	writeJSON(w, http.StatusOK, types.FocusSessionResponse{
+		Success: true,
+		Session: session,
+	})
+}
+
+// GetFocusSessionsHandler lists focus sessions with totals per task and per day
+func GetFocusSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	taskID := query.Get("task_id")
+	if taskID != "" {
+		if _, err := uuid.Parse(taskID); err != nil {
+			writeError(w, "Invalid task_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile:", err)
+	}
+	loc := supabase.ProfileLocation(profile)
+
+	// from and to are inclusive local dates; default to the last 7 days
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := to.AddDate(0, 0, -6)
+	if v := query.Get("from"); v != "" {
+		if from, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			writeError(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.ParseInLocation("2006-01-02", v, loc); err != nil {
+			writeError(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if to.Before(from) {
+		writeError(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := supabase.GetFocusSessions(client, userID, from, to.AddDate(0, 0, 1), taskID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch focus sessions:", err)
+		writeError(w, "Failed to fetch focus sessions", http.StatusInternalServerError)
+		return
+	}
+
+	perTask, perDay, total := supabase.AggregateFocus(sessions, loc, time.Now())
+	if err := supabase.AttachTaskTitles(client, userID, perTask); err != nil {
+		config.Logger.Warn("Failed to attach task titles:", err)
+	}
+
+	writeJSON(w, http.StatusOK, types.FocusSessionsResponse{
+		Success:      true,
+		Sessions:     sessions,
+		PerTask:      perTask,
+		PerDay:       perDay,
+		TotalSeconds: total,
+	})
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/pagination"
+	"clementus360/ai-helper/supabase"
 	"clementus360/ai-helper/types"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 )
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -26,3 +31,33 @@ func writeError(w http.ResponseWriter, message string, status int) {
 	}
 	writeJSON(w, status, resp)
 }
+
+// dueDateOptions builds the options used to resolve natural-language due dates
+// from the user's profile, with an optional per-request timezone override
+func dueDateOptions(profile types.UserProfile, timezone string) dates.Options {
+	opts := supabase.ProfileDueDateOptions(profile)
+	if timezone != "" {
+		opts.Location = dates.LoadLocation(timezone)
+	}
+	return opts
+}
+
+// pageParams reads the limit and cursor query parameters of a paginated list
+func pageParams(r *http.Request, defaultLimit, maxLimit int) (int, pagination.Cursor, error) {
+	q := r.URL.Query()
+
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return 0, pagination.Cursor{}, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		limit = n
+	}
+
+	cursor, err := pagination.Decode(q.Get("cursor"))
+	if err != nil {
+		return 0, pagination.Cursor{}, err
+	}
+	return limit, cursor, nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// GetMemoriesHandler lists what the assistant remembers about the user
+func GetMemoriesHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	memories, err := supabase.GetMemories(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch memories:", err)
+		writeError(w, "Failed to fetch memories", http.StatusInternalServerError)
+		return
+	}
+
+	// Embeddings are an internal detail
+	for i := range memories {
+		memories[i].Embedding = nil
+		memories[i].Embedder = ""
+	}
+
+	writeJSON(w, http.StatusOK, types.MemoriesResponse{
+		Success:  true,
+		Memories: memories,
+	})
+}
+
+// DeleteMemoryHandler makes the assistant forget a memory
+func DeleteMemoryHandler(w http.ResponseWriter, r *http.Request) {
+	memoryID := r.PathValue("id")
+	if _, err := uuid.Parse(memoryID); err != nil {
+		writeError(w, "Invalid memory ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := supabase.DeleteMemory(client, userID, memoryID); err != nil {
+		config.Logger.Error("Failed to delete memory:", err)
+		writeError(w, "Failed to delete memory", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.BaseResponse{
+		Success: true,
+		Message: "Memory deleted successfully",
+	})
+}
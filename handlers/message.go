@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/llm"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/titles"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	supabasego "github.com/supabase-community/supabase-go"
+)
+
+// EditMessageHandler fixes the text of a user message. A message that has
+// been answered can only be edited together with regenerating the reply,
+// so the conversation never shows a reply to text that was changed.
+func EditMessageHandler(w http.ResponseWriter, r *http.Request) {
+	messageID := r.PathValue("id")
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeError(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Content) == "" {
+		writeError(w, "Invalid JSON body or missing content", http.StatusBadRequest)
+		return
+	}
+	if !isValidTaskPolicy(req.TaskPolicy) {
+		writeError(w, "task_policy must be keep or rollback", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	original, err := supabase.GetMessage(client, userID, messageID)
+	if err != nil || original.Sender != "user" {
+		writeError(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	// Check before editing so a rejected regeneration leaves nothing half done
+	if req.Regenerate {
+		if status, err := checkRegenerable(client, userID, original); err != nil {
+			writeError(w, err.Error(), status)
+			return
+		}
+	} else {
+		replies, err := supabase.GetReplies(client, userID, messageID, false)
+		if err != nil {
+			config.Logger.Error("Failed to fetch replies:", err)
+			writeError(w, "Failed to edit message", http.StatusInternalServerError)
+			return
+		}
+		if len(replies) > 0 {
+			writeError(w, "This message has a reply; edit it with regenerate set to replace the reply", http.StatusConflict)
+			return
+		}
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if req.Regenerate {
+		// regenerateReply saves the edit only once the new reply exists, so a
+		// failed regeneration leaves the message and its reply as they were
+		resp, status, err := regenerateReply(client, userID, original, content, req.TaskPolicy, req.Timezone)
+		if err != nil {
+			writeError(w, err.Error(), status)
+			return
+		}
+		trackMessageEdited(client, userID, resp.UserMessage, true)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	edited, err := supabase.UpdateMessageContent(client, userID, messageID, content)
+	if err != nil {
+		config.Logger.Error("Failed to edit message:", err)
+		writeError(w, "Failed to edit message", http.StatusInternalServerError)
+		return
+	}
+	trackMessageEdited(client, userID, edited, false)
+
+	writeJSON(w, http.StatusOK, types.MessageResponse{
+		Success: true,
+		Message: edited,
+	})
+}
+
+func trackMessageEdited(client *supabasego.Client, userID string, edited types.Message, regenerate bool) {
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, edited.SessionID, config.ActivityTypeMessageEdited, edited.Content, map[string]interface{}{
+			"message_id": edited.ID,
+			"regenerate": regenerate,
+		}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+}
+
+// RegenerateMessageHandler replaces the reply to a user message with a new
+// one. The ID may be the user message or the reply being replaced.
+func RegenerateMessageHandler(w http.ResponseWriter, r *http.Request) {
+	messageID := r.PathValue("id")
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeError(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	var req types.RegenerateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+	if !isValidTaskPolicy(req.TaskPolicy) {
+		writeError(w, "task_policy must be keep or rollback", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	message, err := supabase.GetMessage(client, userID, messageID)
+	if err != nil {
+		writeError(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	if message.Sender != "user" {
+		if message.UserMessageID == "" {
+			writeError(w, "Reply isn't linked to a user message", http.StatusBadRequest)
+			return
+		}
+		if message, err = supabase.GetMessage(client, userID, message.UserMessageID); err != nil {
+			writeError(w, "Message not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if status, err := checkRegenerable(client, userID, message); err != nil {
+		writeError(w, err.Error(), status)
+		return
+	}
+
+	resp, status, err := regenerateReply(client, userID, message, "", req.TaskPolicy, req.Timezone)
+	if err != nil {
+		writeError(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetMessageAlternatesHandler lists every reply to a user message, including
+// the ones replaced by regeneration
+func GetMessageAlternatesHandler(w http.ResponseWriter, r *http.Request) {
+	messageID := r.PathValue("id")
+	if _, err := uuid.Parse(messageID); err != nil {
+		writeError(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	replies, err := supabase.GetReplies(client, userID, messageID, true)
+	if err != nil {
+		config.Logger.Error("Failed to fetch alternates:", err)
+		writeError(w, "Failed to fetch alternates", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.MessageAlternatesResponse{
+		Success:    true,
+		Alternates: replies,
+	})
+}
+
+func isValidTaskPolicy(policy string) bool {
+	return policy == "" || policy == types.SupersededTasksKeep || policy == types.SupersededTasksRollback
+}
+
+// checkRegenerable allows regenerating only the latest turn, since later
+// replies were written in reply to the current one
+func checkRegenerable(client *supabasego.Client, userID string, message types.Message) (int, error) {
+	latest, err := supabase.GetLatestUserMessage(client, userID, message.SessionID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch latest message:", err)
+		return http.StatusInternalServerError, fmt.Errorf("failed to check conversation")
+	}
+	if latest.ID != message.ID {
+		return http.StatusConflict, fmt.Errorf("only the latest message can be regenerated")
+	}
+	return http.StatusOK, nil
+}
+
+// regenerateReply asks the assistant to answer userMessage again; callers
+// check it with checkRegenerable first. The new reply replaces the current
+// one, which is kept as an alternate. What the old reply did to tasks is
+// kept or rolled back according to policy; kept tasks aren't suggested again.
+// A non-empty edit is new text for userMessage: the reply is generated for
+// it, and the edit is saved only once the reply has been generated.
+func regenerateReply(client *supabasego.Client, userID string, userMessage types.Message, edit, policy, timezone string) (types.RegenerateResponse, int, error) {
+	if policy == "" {
+		policy = types.SupersededTasksKeep
+	}
+	sessionID := userMessage.SessionID
+	content := userMessage.Content
+	if edit != "" {
+		content = edit
+	}
+
+	oldReplies, err := supabase.GetReplies(client, userID, userMessage.ID, false)
+	if err != nil {
+		config.Logger.Error("Failed to fetch replies:", err)
+		return types.RegenerateResponse{}, http.StatusInternalServerError, fmt.Errorf("failed to fetch the current reply")
+	}
+	superseded := make([]string, len(oldReplies))
+	for i, reply := range oldReplies {
+		superseded[i] = reply.ID
+	}
+
+	var revisions []types.TaskRevision
+	var keptTasks []types.Task
+	if policy == types.SupersededTasksRollback {
+		revisions, err = supabase.GetMessageRevisions(client, userID, superseded)
+	} else {
+		keptTasks, err = supabase.GetTasksForMessages(client, userID, superseded)
+	}
+	if err != nil {
+		config.Logger.Error("Failed to fetch task changes from replaced reply:", err)
+		return types.RegenerateResponse{}, http.StatusInternalServerError, fmt.Errorf("failed to fetch tasks from the current reply")
+	}
+
+	// Rebuild the context as it was before this turn was answered
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+	smartContext, err := supabase.BuildSmartContext(client, sessionID, userID, profile)
+	if err != nil {
+		config.Logger.Warn("Failed to get smart context:", err)
+		smartContext.Profile = profile
+	}
+	recent := smartContext.RecentMessages[:0]
+	for _, m := range smartContext.RecentMessages {
+		if m.ID != userMessage.ID && m.UserMessageID != userMessage.ID {
+			recent = append(recent, m)
+		}
+	}
+	smartContext.RecentMessages = recent
+	// Show the tasks as they will be once the old reply is rolled back
+	smartContext.KeyTasks = supabase.RevertTasks(smartContext.KeyTasks, revisions)
+	if memories, err := supabase.RecallMemories(client, userID, content, 5); err != nil {
+		config.Logger.Warn("Failed to recall memories:", err)
+	} else {
+		smartContext.Memories = memories
+	}
+
+	structuredResp, err := llm.GenerateResponse(content, smartContext, "gemini")
+	if err != nil {
+		config.Logger.Error("Failed to regenerate AI response:", err)
+		return types.RegenerateResponse{}, http.StatusBadGateway, fmt.Errorf("couldn't generate a new reply; the current one was kept")
+	}
+	structuredResp.ActionItems = withoutKeptTasks(structuredResp.ActionItems, keptTasks)
+
+	original := userMessage
+	if edit != "" {
+		if userMessage, err = supabase.UpdateMessageContent(client, userID, userMessage.ID, edit); err != nil {
+			config.Logger.Error("Failed to edit message:", err)
+			return types.RegenerateResponse{}, http.StatusInternalServerError, fmt.Errorf("failed to edit message")
+		}
+	}
+
+	messageId, err := supabase.SaveMessage(client, userID, sessionID, "ai", userMessage.ID, structuredResp.Response, structuredResp.PromptVersion)
+	if err != nil {
+		config.Logger.Error("Failed to save regenerated message:", err)
+		if edit != "" {
+			if err := supabase.RestoreMessageContent(client, userID, original); err != nil {
+				config.Logger.Error("Failed to undo message edit:", err)
+			}
+		}
+		return types.RegenerateResponse{}, http.StatusInternalServerError, fmt.Errorf("failed to save the new reply")
+	}
+
+	if err := supabase.SupersedeMessages(client, userID, superseded); err != nil {
+		config.Logger.Warn("Failed to supersede old replies:", err)
+	}
+
+	// Undo what the replaced reply did to tasks: created tasks are deleted,
+	// updated ones restored and deleted ones brought back
+	var warnings []string
+	rolledBack, err := supabase.RevertTaskRevisions(client, userID, revisions)
+	if err != nil {
+		config.Logger.Warn("Failed to roll back task changes:", err)
+		warnings = append(warnings, "Some task changes from the replaced reply couldn't be undone")
+	}
+
+	tasks, actionWarnings := applyAssistantActions(client, userID, sessionID, messageId, userMessage.ID, timezone, smartContext, structuredResp)
+	warnings = append(warnings, actionWarnings...)
+
+	go func() {
+		if err := supabase.TouchSession(client, userID, sessionID); err != nil {
+			config.Logger.Warn("Failed to mark session active:", err)
+		}
+		if err := supabase.TrackUserActivity(client, userID, sessionID, config.ActivityTypeMessageRegenerated, structuredResp.Response, map[string]interface{}{
+			"user_message_id":   userMessage.ID,
+			"superseded":        superseded,
+			"task_policy":       policy,
+			"rolled_back_tasks": len(rolledBack),
+		}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	return types.RegenerateResponse{
+		Success:     true,
+		UserMessage: userMessage,
+		Reply: types.Message{
+			ID:            messageId,
+			UserID:        userID,
+			Sender:        "ai",
+			Content:       structuredResp.Response,
+			CreatedAt:     time.Now(),
+			SessionID:     sessionID,
+			UserMessageID: userMessage.ID,
+		},
+		ActionItems:     tasks,
+		Superseded:      superseded,
+		RolledBackTasks: rolledBack,
+		Warnings:        warnings,
+	}, http.StatusOK, nil
+}
+
+// withoutKeptTasks drops suggested tasks that restate a task the replaced
+// reply created and the user kept
+func withoutKeptTasks(items []llm.GeminiTaskItem, kept []types.Task) []llm.GeminiTaskItem {
+	if len(kept) == 0 {
+		return items
+	}
+	keys := map[string]bool{}
+	for _, t := range kept {
+		keys[titles.Key(t.Title)] = true
+	}
+	var fresh []llm.GeminiTaskItem
+	for _, item := range items {
+		if !keys[titles.Key(item.Title)] {
+			fresh = append(fresh, item)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"net/http"
+)
+
+// GetProfileHandler returns the user's timezone, locale and working hours
+func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch profile:", err)
+		writeError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.ProfileResponse{
+		Success: true,
+		Profile: profile,
+	})
+}
+
+// UpdateProfileHandler updates the fields present in the request body and
+// leaves the rest unchanged
+func UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch profile:", err)
+		writeError(w, "Failed to fetch profile", http.StatusInternalServerError)
+		return
+	}
+
+	// Decode on top of the current profile so omitted fields keep their values
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		config.Logger.Warn("Invalid profile payload:", err)
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := supabase.ValidateUserProfile(profile); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := supabase.UpsertUserProfile(client, userID, profile)
+	if err != nil {
+		config.Logger.Error("Failed to update profile:", err)
+		writeError(w, "Failed to update profile", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.ProfileResponse{
+		Success: true,
+		Profile: saved,
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SearchHandler searches the user's messages, tasks and session summaries
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		writeError(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+
+	var searchTypes []string
+	if v := q.Get("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			switch t {
+			case types.SearchTypeMessage, types.SearchTypeTask, types.SearchTypeSummary:
+				searchTypes = append(searchTypes, t)
+			default:
+				writeError(w, "Invalid search type: "+t, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	limit := 20
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > supabase.MaxSearchResults {
+			writeError(w, "Invalid limit value", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	results, err := supabase.Search(client, userID, query, searchTypes, limit)
+	if err != nil {
+		config.Logger.Error("Search failed:", err)
+		writeError(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.SearchResponse{
+		Success: true,
+		Query:   query,
+		Results: results,
+	})
+}
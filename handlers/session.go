@@ -5,11 +5,21 @@ import (
 	"clementus360/ai-helper/supabase"
 	"clementus360/ai-helper/types"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 func GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := pageParams(r, 20, 100)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	supabaseClient, userID, err := supabase.SupabaseClientFromRequest(r)
 	if err != nil {
 		config.Logger.Error("Failed to create Supabase client:", err)
@@ -17,7 +27,13 @@ func GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessions, err := supabase.GetSessions(supabaseClient, userID)
+	filter, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessions, page, err := supabase.GetSessions(supabaseClient, userID, limit, cursor, filter)
 	if err != nil {
 		config.Logger.Error("Failed to fetch sessions:", err)
 		writeError(w, "Failed to fetch sessions", http.StatusInternalServerError)
@@ -25,8 +41,138 @@ func GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, types.GetSessionsResponse{
-		Success:  true,
-		Sessions: sessions,
+		Success:    true,
+		Sessions:   sessions,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}
+
+// ForkSessionHandler branches a session at a message, copying the
+// conversation up to that point into a new session
+func ForkSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		writeError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	fromMessage := r.URL.Query().Get("from_message")
+	if fromMessage != "" {
+		if _, err := uuid.Parse(fromMessage); err != nil {
+			writeError(w, "Invalid from_message ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	branch, err := supabase.ForkSession(client, userID, sessionID, fromMessage)
+	if err != nil {
+		config.Logger.Warn("Failed to fork session:", err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			writeError(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "replaced reply"):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, "Failed to fork session", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, branch.ID, config.ActivityTypeSessionForked, branch.Title, map[string]interface{}{
+			"parent_session_id": sessionID,
+			"from_message":      fromMessage,
+		}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+	}()
+
+	writeJSON(w, http.StatusCreated, types.SessionResponse{
+		Success: true,
+		Session: branch,
+	})
+}
+
+// sessionFilter reads the view, pinned, archived and tag query parameters
+// of a session listing
+func sessionFilter(r *http.Request) (types.SessionFilter, error) {
+	q := r.URL.Query()
+	filter := types.SessionFilter{Tree: q.Get("view") == "tree"}
+
+	if v := q.Get("pinned"); v != "" {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("pinned must be true or false")
+		}
+		filter.Pinned = &pinned
+	}
+
+	switch q.Get("archived") {
+	case "", "false":
+		filter.Archived = types.SessionArchivedExclude
+	case "true":
+		filter.Archived = types.SessionArchivedOnly
+	case "all":
+		filter.Archived = types.SessionArchivedInclude
+	default:
+		return filter, fmt.Errorf("archived must be true, false or all")
+	}
+
+	switch state := q.Get("state"); state {
+	case "", types.SessionStateActive, types.SessionStateIdle, types.SessionStateClosed:
+		filter.State = state
+	default:
+		return filter, fmt.Errorf("state must be active, idle or closed")
+	}
+
+	// Tags may be repeated or comma separated
+	for _, v := range q["tag"] {
+		filter.Tags = append(filter.Tags, strings.Split(v, ",")...)
+	}
+	return filter, nil
+}
+
+// CloseSessionHandler ends a session now instead of waiting for the
+// inactivity timeout. The summary is generated in the background.
+func CloseSessionHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		writeError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, closed, err := supabase.CloseSession(client, userID, sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			writeError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		config.Logger.Error("Failed to close session:", err)
+		writeError(w, "Failed to close session", http.StatusInternalServerError)
+		return
+	}
+
+	if closed {
+		go supabase.SummarizeClosedSession(client, userID, sessionID)
+	}
+
+	writeJSON(w, http.StatusOK, types.SessionResponse{
+		Success: true,
+		Session: session,
 	})
 }
 
@@ -39,12 +185,18 @@ func UpdateSessionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var body struct {
-		Title string `json:"title"`
+	var body types.UpdateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		config.Logger.Warn("Invalid session update body:", err)
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.Title == nil && body.Pinned == nil && body.Archived == nil && body.Tags == nil {
+		writeError(w, "Nothing to update", http.StatusBadRequest)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Title) == "" {
-		config.Logger.Warn("Invalid or missing title in request body:", err)
-		writeError(w, "Invalid or missing title", http.StatusBadRequest)
+	if body.Title != nil && strings.TrimSpace(*body.Title) == "" {
+		writeError(w, "Title cannot be empty", http.StatusBadRequest)
 		return
 	}
 
@@ -55,10 +207,17 @@ func UpdateSessionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := supabase.UpdateSessionTitle(client, sessionID, userID, body.Title)
+	updated, err := supabase.UpdateSession(client, sessionID, userID, body)
 	if err != nil {
 		config.Logger.Error("Failed to update session:", err)
-		writeError(w, "Failed to update session", http.StatusInternalServerError)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			writeError(w, "Session not found", http.StatusNotFound)
+		case strings.Contains(err.Error(), "at most"):
+			writeError(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeError(w, "Failed to update session", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/ical"
+	"clementus360/ai-helper/pagination"
 	"clementus360/ai-helper/supabase"
 	"clementus360/ai-helper/types"
 	"encoding/json"
@@ -15,19 +18,31 @@ import (
 
 func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 
-	var task types.Task
+	var req types.CreateTaskRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		config.Logger.Error("Failed to decode task JSON:", err)
 		writeError(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
+	task := req.Task
 
 	// Basic validation
 	if task.Title == "" {
 		writeError(w, "Missing user_id or title", http.StatusBadRequest)
 		return
 	}
+	if task.Recurrence != "" {
+		if err := ical.ValidateRRule(task.Recurrence); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Due != "" && task.DueDate != nil {
+		writeError(w, "Provide either due or due_date, not both", http.StatusBadRequest)
+		return
+	}
 
 	// Get Supabase client from request
 	supabaseClient, userId, err := supabase.SupabaseClientFromRequest(r)
@@ -39,8 +54,23 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	task.UserID = userId // Set the user ID from the request context
 
+	profile, err := supabase.GetUserProfile(supabaseClient, userId)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+
+	// Resolve a natural-language due phrase in the user's timezone, rejecting ambiguous ones
+	if req.Due != "" {
+		due, err := dates.ParseDue(req.Due, time.Now(), dueDateOptions(profile, req.Timezone))
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		task.DueDate = &due
+	}
+
 	// Save the task
-	savedTask, err := supabase.InsertAndReturnTask(supabaseClient, task)
+	savedTask, err := supabase.InsertAndReturnTask(supabaseClient, profile, task)
 	if err != nil {
 		config.Logger.Error("Failed to save task:", err)
 		writeError(w, "Failed to create task", http.StatusInternalServerError)
@@ -53,6 +83,10 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		sessionID = *savedTask.SessionID
 	}
 
+	if err := supabase.RecordTaskRevision(supabaseClient, userId, nil, &savedTask, config.RevisionActorUser, ""); err != nil {
+		config.Logger.Warn("Failed to record task revision:", err)
+	}
+
 	go func() {
 		if err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, "task_created", savedTask.Title, map[string]interface{}{
 			"task_id":      savedTask.ID,
@@ -101,11 +135,15 @@ func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Attempt to fetch task to get session ID before deletion
 	var sessionID string
+	var existing *types.Task
 	tasks, err := supabase.GetSingleTask(supabaseClient, userId, taskID)
 	if err != nil {
 		config.Logger.Warn("Failed to fetch task before deletion:", err)
-	} else if len(tasks) > 0 && tasks[0].SessionID != nil {
-		sessionID = *tasks[0].SessionID
+	} else if len(tasks) > 0 {
+		existing = &tasks[0]
+		if existing.SessionID != nil {
+			sessionID = *existing.SessionID
+		}
 	}
 
 	if err := supabase.DeleteTask(supabaseClient, taskID, userId); err != nil {
@@ -114,6 +152,12 @@ func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if existing != nil {
+		if err := supabase.RecordTaskRevision(supabaseClient, userId, existing, nil, config.RevisionActorUser, ""); err != nil {
+			config.Logger.Warn("Failed to record task revision:", err)
+		}
+	}
+
 	// Track task deletion
 	go func() {
 		err := supabase.TrackUserActivity(supabaseClient, userId, sessionID, "task_deleted", fmt.Sprintf("Deleted task %s", taskID), map[string]interface{}{
@@ -149,6 +193,12 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "Invalid or empty update payload", http.StatusBadRequest)
 		return
 	}
+	if rule, ok := updates["recurrence"].(string); ok && rule != "" {
+		if err := ical.ValidateRRule(rule); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	client, userID, err := supabase.SupabaseClientFromRequest(r)
 	if err != nil {
@@ -160,6 +210,14 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep the current state for the revision history
+	var existing *types.Task
+	if current, err := supabase.GetSingleTask(client, userID, taskID); err != nil {
+		config.Logger.Warn("Failed to fetch task before update:", err)
+	} else if len(current) > 0 {
+		existing = &current[0]
+	}
+
 	updatedTask, err := supabase.UpdateTask(client, taskID, userID, updates)
 	if err != nil {
 		config.Logger.Error("Failed to update task:", err)
@@ -176,6 +234,12 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		sessionID = *updatedTask.SessionID
 	}
 
+	if existing != nil {
+		if err := supabase.RecordTaskRevision(client, userID, existing, &updatedTask, config.RevisionActorUser, ""); err != nil {
+			config.Logger.Warn("Failed to record task revision:", err)
+		}
+	}
+
 	go func() {
 		if err := supabase.TrackUserActivity(client, userID, sessionID, "task_updated", updatedTask.Title, map[string]interface{}{
 			"task_id": updatedTask.ID,
@@ -192,6 +256,7 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 			if err := supabase.TrackUserActivity(client, userID, sessionID, "task_completed", updatedTask.Title, map[string]interface{}{
 				"task_id":         updatedTask.ID,
 				"completion_time": time.Now(),
+				"ai_suggested":    updatedTask.AISuggested,
 			}); err != nil {
 				config.Logger.Warn("TrackUserActivity failed:", err)
 			}
@@ -203,6 +268,9 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 				if err := supabase.IncrementSessionCounter(client, sessionID, "task_completed"); err != nil {
 					config.Logger.Warn("Failed to incemment session counter:", err)
 				}
+				if err := supabase.RefreshSessionEngagement(client, sessionID, userID); err != nil {
+					config.Logger.Warn("Failed to refresh session engagement:", err)
+				}
 			}()
 		}
 	}
@@ -221,12 +289,18 @@ func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 	limitStr := q.Get("limit")
 	offsetStr := q.Get("offset")
 	search := q.Get("search")
-	sortBy := q.Get("sort_by")       // e.g., "created_at", "title", "status"
+	columnID := q.Get("column_id")   // board column ID, or "none"
+	sortBy := q.Get("sort_by")       // e.g., "created_at", "title", "status", "position"
 	sortOrder := q.Get("sort_order") // "asc" or "desc"
 
+	cursor, err := pagination.Decode(q.Get("cursor"))
+	if err != nil {
+		writeError(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
 	limit := 20 // default
 	offset := 0
-	var err error
 
 	if limitStr != "" {
 		limit, err = strconv.Atoi(limitStr)
@@ -246,6 +320,11 @@ func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if cursor.Bounded() && (offset > 0 || (sortBy != "" && sortBy != "created_at")) {
+		writeError(w, "cursor can't be combined with offset or sort_by other than created_at", http.StatusBadRequest)
+		return
+	}
+
 	supabaseClient, userId, err := supabase.SupabaseClientFromRequest(r)
 	if err != nil {
 		config.Logger.Error("Failed to create Supabase client:", err)
@@ -253,7 +332,7 @@ func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, total, err := supabase.GetTasks(supabaseClient, userId, sessionID, status, limit, offset, search, sortBy, sortOrder)
+	tasks, total, page, err := supabase.GetTasks(supabaseClient, userId, sessionID, status, columnID, limit, offset, cursor, search, sortBy, sortOrder)
 	if err != nil {
 		config.Logger.Error("Failed to fetch tasks:", err)
 		writeError(w, "Failed to fetch tasks", http.StatusInternalServerError)
@@ -261,11 +340,13 @@ func GetTasksHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusOK, types.GetTasksResponse{
-		Success: true,
-		Tasks:   tasks,
-		Limit:   limit,
-		Offset:  offset,
-		Total:   int(total),
+		Success:    true,
+		Tasks:      tasks,
+		Limit:      limit,
+		Offset:     offset,
+		Total:      int(total),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
 	})
 }
 
@@ -294,3 +375,32 @@ func GetSingleTaskHandler(w http.ResponseWriter, r *http.Request) {
 		Task:    task,
 	})
 }
+
+// GetTaskHistoryHandler returns a task's revisions, oldest first
+func GetTaskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.PathValue("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		writeError(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revisions, err := supabase.GetTaskRevisions(client, userID, taskID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch task history:", err)
+		writeError(w, "Failed to fetch task history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.TaskHistoryResponse{
+		Success:   true,
+		TaskID:    taskID,
+		Revisions: revisions,
+	})
+}
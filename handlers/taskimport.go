@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/taskimport"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxTaskImportBytes caps the size of an import request body
+const maxTaskImportBytes = 5 << 20
+
+// ImportTasksHandler imports tasks from a CSV file, a Markdown checklist or
+// a Todoist or Trello export, reporting what happened to each row
+func ImportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	var req types.TaskImportRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTaskImportBytes)).Decode(&req); err != nil {
+		config.Logger.Warn("Failed to decode import JSON:", err)
+		writeError(w, "Invalid JSON body or import larger than 5MB", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+	rows, err := taskimport.Parse(req.Format, req.Content, req.Mapping, time.Now(), dueDateOptions(profile, req.Timezone))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, saved, err := supabase.ImportTasks(client, userID, profile, rows, req.DryRun)
+	if err != nil {
+		config.Logger.Error("Failed to import tasks:", err)
+		writeError(w, "Failed to import tasks", http.StatusInternalServerError)
+		return
+	}
+
+	resp := types.TaskImportResponse{Success: true, DryRun: req.DryRun, Rows: report}
+	for _, row := range report {
+		switch row.Status {
+		case types.TaskImportImported:
+			resp.Imported++
+		case types.TaskImportSkipped:
+			resp.Skipped++
+		case types.TaskImportInvalid:
+			resp.Invalid++
+		case types.TaskImportFailed:
+			resp.Failed++
+		}
+	}
+	resp.Success = resp.Failed == 0
+
+	if len(saved) > 0 {
+		go func() {
+			if err := supabase.TrackUserActivity(client, userID, "", config.ActivityTypeTasksImported,
+				fmt.Sprintf("Imported %d tasks from %s", len(saved), req.Format),
+				map[string]interface{}{
+					"format":   req.Format,
+					"imported": len(saved),
+					"skipped":  resp.Skipped,
+					"invalid":  resp.Invalid,
+				}); err != nil {
+				config.Logger.Warn("TrackUserActivity failed:", err)
+			}
+		}()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/supabase"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GetTemplatesHandler lists the built-in templates and the user's own
+func GetTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	templates, err := supabase.GetTaskTemplates(client, userID)
+	if err != nil {
+		config.Logger.Error("Failed to fetch templates:", err)
+		writeError(w, "Failed to fetch templates", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.GetTemplatesResponse{
+		Success:   true,
+		Templates: templates,
+	})
+}
+
+// CreateTemplateHandler saves a user-defined template
+func CreateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var tmpl types.TaskTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		config.Logger.Warn("Failed to decode template JSON:", err)
+		writeError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	saved, err := supabase.CreateTaskTemplate(client, userID, tmpl)
+	if err != nil {
+		config.Logger.Warn("Failed to create template:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, types.TemplateResponse{
+		Success:  true,
+		Template: saved,
+	})
+}
+
+// DeleteTemplateHandler deletes one of the user's templates
+func DeleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	templateID := r.URL.Query().Get("id")
+	if templateID == "" {
+		writeError(w, "Missing template ID", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := supabase.DeleteTaskTemplate(client, userID, templateID); err != nil {
+		config.Logger.Warn("Failed to delete template:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, types.BaseResponse{
+		Success: true,
+		Message: "Template deleted successfully",
+	})
+}
+
+// InstantiateTemplateHandler expands a template into real tasks
+func InstantiateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req types.InstantiateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TemplateID == "" {
+		writeError(w, "Invalid JSON body or missing template_id", http.StatusBadRequest)
+		return
+	}
+
+	client, userID, err := supabase.SupabaseClientFromRequest(r)
+	if err != nil {
+		config.Logger.Error("Failed to create Supabase client:", err)
+		writeError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := supabase.GetTaskTemplate(client, userID, req.TemplateID)
+	if err != nil {
+		writeError(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	profile, err := supabase.GetUserProfile(client, userID)
+	if err != nil {
+		config.Logger.Warn("Failed to fetch user profile, using defaults:", err)
+	}
+
+	tasks, err := supabase.InstantiateTemplate(client, userID, profile, tmpl, req.Values, req.SessionID, "", config.RevisionActorUser)
+	if err != nil {
+		config.Logger.Warn("Failed to instantiate template:", err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := supabase.TrackUserActivity(client, userID, req.SessionID, config.ActivityTypeTemplateUsed,
+			fmt.Sprintf("Created %d tasks from template %s", len(tasks), tmpl.Name), map[string]interface{}{
+				"template_id": tmpl.ID,
+				"task_count":  len(tasks),
+			}); err != nil {
+			config.Logger.Warn("TrackUserActivity failed:", err)
+		}
+		if req.SessionID != "" {
+			for range tasks {
+				if err := supabase.IncrementSessionCounter(client, req.SessionID, "task_created"); err != nil {
+					config.Logger.Warn("Failed to increment task_created session counter:", err)
+				}
+			}
+		}
+	}()
+
+	writeJSON(w, http.StatusCreated, types.InstantiateTemplateResponse{
+		Success: true,
+		Tasks:   tasks,
+	})
+}
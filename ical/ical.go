@@ -0,0 +1,210 @@
+// Package ical renders tasks as an iCalendar (RFC 5545) feed that calendar
+// apps can subscribe to.
+package ical
+
+import (
+	"clementus360/ai-helper/types"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind selects the component type emitted for each task
+type Kind string
+
+const (
+	KindTodo  Kind = "todo"  // VTODO, shown as reminders/to-dos
+	KindEvent Kind = "event" // VEVENT, shown on the calendar grid
+)
+
+const (
+	timestampLayout = "20060102T150405Z"
+	maxLineOctets   = 75
+	eventDuration   = "PT30M"
+)
+
+// allowedRRuleParts are the RRULE parts accepted from clients
+var allowedRRuleParts = map[string]bool{
+	"FREQ": true, "INTERVAL": true, "COUNT": true, "UNTIL": true, "BYDAY": true,
+	"BYMONTHDAY": true, "BYMONTH": true, "BYSETPOS": true, "WKST": true,
+}
+
+var allowedFrequencies = map[string]bool{
+	"DAILY": true, "WEEKLY": true, "MONTHLY": true, "YEARLY": true,
+}
+
+// Options controls feed rendering
+type Options struct {
+	Kind Kind
+	Name string
+	Now  time.Time
+}
+
+// ValidateRRule checks that rule is a well-formed RRULE value such as
+// "FREQ=WEEKLY;BYDAY=MO,WE" using only supported parts
+func ValidateRRule(rule string) error {
+	if strings.ContainsAny(rule, "\r\n") {
+		return fmt.Errorf("recurrence must be a single line")
+	}
+	hasFreq := false
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return fmt.Errorf("invalid recurrence part %q", part)
+		}
+		key = strings.ToUpper(key)
+		if !allowedRRuleParts[key] {
+			return fmt.Errorf("unsupported recurrence part %q", key)
+		}
+		if key == "FREQ" {
+			if !allowedFrequencies[strings.ToUpper(value)] {
+				return fmt.Errorf("unsupported recurrence frequency %q", value)
+			}
+			hasFreq = true
+		}
+	}
+	if !hasFreq {
+		return fmt.Errorf("recurrence must include FREQ")
+	}
+	return nil
+}
+
+// Render builds a VCALENDAR containing one component per task with a due date
+func Render(tasks []types.Task, opts Options) string {
+	if opts.Kind == "" {
+		opts.Kind = KindEvent
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Name == "" {
+		opts.Name = "AI Helper Tasks"
+	}
+
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:-//ai-helper//Tasks//EN")
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	writeLine(&b, "X-WR-CALNAME:"+escapeText(opts.Name))
+	writeLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	writeLine(&b, "X-PUBLISHED-TTL:PT1H")
+
+	stamp := opts.Now.UTC().Format(timestampLayout)
+	for _, task := range tasks {
+		if task.DueDate == nil || task.ID == "" {
+			continue
+		}
+		if opts.Kind == KindTodo {
+			writeTodo(&b, task, stamp)
+		} else {
+			writeEvent(&b, task, stamp)
+		}
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func writeTodo(b *strings.Builder, task types.Task, stamp string) {
+	writeLine(b, "BEGIN:VTODO")
+	writeCommon(b, task, stamp)
+	due := task.DueDate.UTC().Format(timestampLayout)
+	if recurrence(task) != "" {
+		// RRULE repeats from DTSTART, so a recurring to-do needs one
+		writeLine(b, "DTSTART:"+due)
+	}
+	writeLine(b, "DUE:"+due)
+	writeLine(b, "STATUS:"+todoStatus(task.Status))
+	writeLine(b, "END:VTODO")
+}
+
+func writeEvent(b *strings.Builder, task types.Task, stamp string) {
+	writeLine(b, "BEGIN:VEVENT")
+	writeCommon(b, task, stamp)
+	writeLine(b, "DTSTART:"+task.DueDate.UTC().Format(timestampLayout))
+	writeLine(b, "DURATION:"+eventDuration)
+	writeLine(b, "TRANSP:TRANSPARENT")
+	writeLine(b, "STATUS:"+eventStatus(task.Status))
+	writeLine(b, "END:VEVENT")
+}
+
+func writeCommon(b *strings.Builder, task types.Task, stamp string) {
+	// Task IDs are stable, so calendar apps update entries in place
+	writeLine(b, "UID:"+task.ID+"@ai-helper")
+	writeLine(b, "DTSTAMP:"+stamp)
+	if !task.CreatedAt.IsZero() {
+		writeLine(b, "CREATED:"+task.CreatedAt.UTC().Format(timestampLayout))
+	}
+
+	summary := task.Title
+	if task.Status == "completed" {
+		summary = "✓ " + summary
+	}
+	writeLine(b, "SUMMARY:"+escapeText(summary))
+	if task.Description != "" {
+		writeLine(b, "DESCRIPTION:"+escapeText(task.Description))
+	}
+	if rule := recurrence(task); rule != "" {
+		writeLine(b, "RRULE:"+rule)
+	}
+}
+
+// recurrence returns the task's RRULE value without its "RRULE:" prefix, or
+// "" if it has none or it is invalid
+func recurrence(task types.Task) string {
+	if task.Recurrence == "" || ValidateRRule(task.Recurrence) != nil {
+		return ""
+	}
+	return strings.TrimPrefix(task.Recurrence, "RRULE:")
+}
+
+func todoStatus(status string) string {
+	switch status {
+	case "completed":
+		return "COMPLETED"
+	case "cancelled":
+		return "CANCELLED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+func eventStatus(status string) string {
+	if status == "cancelled" {
+		return "CANCELLED"
+	}
+	return "CONFIRMED"
+}
+
+// escapeText escapes a TEXT value per RFC 5545 section 3.3.11
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// writeLine writes a content line, folding it at 75 octets without splitting
+// multi-byte characters
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
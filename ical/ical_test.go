@@ -0,0 +1,160 @@
+package ical
+
+import (
+	"clementus360/ai-helper/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		valid bool
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO,WE", true},
+		{"RRULE:FREQ=DAILY;INTERVAL=2", true},
+		{"freq=monthly;bymonthday=1", true},
+		{"FREQ=WEEKLY;COUNT=5;UNTIL=20261231T000000Z", true},
+		{"BYDAY=MO", false},
+		{"FREQ=HOURLY", false},
+		{"FREQ=WEEKLY;BYHOUR=9", false},
+		{"FREQ=WEEKLY;BYDAY", false},
+		{"FREQ=WEEKLY;BYDAY=", false},
+		{"FREQ=DAILY\r\nX-INJECTED:1", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRRule(tt.rule)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateRRule(%q) = %v, want valid %v", tt.rule, err, tt.valid)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	due := time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	task := func(recurrence, status string) types.Task {
+		return types.Task{
+			ID:         "t1",
+			Title:      "Write report",
+			Status:     status,
+			DueDate:    &due,
+			Recurrence: recurrence,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		task    types.Task
+		kind    Kind
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "todo",
+			task:    task("", "pending"),
+			kind:    KindTodo,
+			want:    []string{"BEGIN:VTODO", "UID:t1@ai-helper", "DUE:20261016T170000Z", "STATUS:NEEDS-ACTION"},
+			notWant: []string{"DTSTART", "RRULE"},
+		},
+		{
+			name: "recurring todo starts when due",
+			task: task("FREQ=WEEKLY;BYDAY=FR", "pending"),
+			kind: KindTodo,
+			want: []string{"DTSTART:20261016T170000Z", "DUE:20261016T170000Z", "RRULE:FREQ=WEEKLY;BYDAY=FR"},
+		},
+		{
+			name:    "invalid recurrence is left out",
+			task:    task("FREQ=HOURLY", "pending"),
+			kind:    KindTodo,
+			notWant: []string{"DTSTART", "RRULE"},
+		},
+		{
+			name: "completed todo",
+			task: task("", "completed"),
+			kind: KindTodo,
+			want: []string{"SUMMARY:✓ Write report", "STATUS:COMPLETED"},
+		},
+		{
+			name: "recurring event",
+			task: task("RRULE:FREQ=DAILY", "pending"),
+			kind: KindEvent,
+			want: []string{"BEGIN:VEVENT", "DTSTART:20261016T170000Z", "DURATION:PT30M", "RRULE:FREQ=DAILY", "STATUS:CONFIRMED"},
+		},
+		{
+			name: "cancelled event",
+			task: task("", "cancelled"),
+			kind: KindEvent,
+			want: []string{"STATUS:CANCELLED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Render([]types.Task{tt.task}, Options{Kind: tt.kind, Now: now})
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			has := func(prefix string) bool {
+				for _, line := range lines {
+					if strings.HasPrefix(line, prefix) {
+						return true
+					}
+				}
+				return false
+			}
+			for _, line := range tt.want {
+				if !has(line) {
+					t.Errorf("missing %q in:\n%s", line, out)
+				}
+			}
+			for _, prefix := range tt.notWant {
+				if has(prefix) {
+					t.Errorf("unexpected %q in:\n%s", prefix, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSkipsTasksWithoutDueDate(t *testing.T) {
+	out := Render([]types.Task{{ID: "t1", Title: "Someday"}}, Options{})
+	if strings.Contains(out, "BEGIN:VEVENT") || strings.Contains(out, "BEGIN:VTODO") {
+		t.Errorf("task without a due date was rendered:\n%s", out)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a, b; c", `a\, b\; c`},
+		{`back\slash`, `back\\slash`},
+		{"two\r\nlines\nhere", `two\nlines\nhere`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLineFolds(t *testing.T) {
+	var b strings.Builder
+	writeLine(&b, "SUMMARY:"+strings.Repeat("é", 60))
+
+	for i, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d is %d octets", i, len(line))
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+	unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+	if unfolded != "SUMMARY:"+strings.Repeat("é", 60)+"\r\n" {
+		t.Errorf("folding changed the content: %q", unfolded)
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"clementus360/ai-helper/config"
+	"context"
+	"time"
+)
+
+// Every runs fn once per interval until ctx is cancelled. A failed run is
+// logged and retried at the next tick; runs never overlap.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(now time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	config.Logger.Info("Background job started: ", name)
+	for {
+		select {
+		case <-ctx.Done():
+			config.Logger.Info("Background job stopped: ", name)
+			return
+		case now := <-ticker.C:
+			if err := fn(now); err != nil {
+				config.Logger.Warn("Background job ", name, " failed: ", err)
+			}
+		}
+	}
+}
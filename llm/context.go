@@ -1,31 +1,85 @@
 package llm
 
 import (
+	"clementus360/ai-helper/prompts"
+	"clementus360/ai-helper/tokenizer"
 	"clementus360/ai-helper/types"
 )
 
-// Token estimation and context trimming
-func EstimateTokens(text string) int {
-	// Rough estimation: ~4 characters per token
-	return len(text) / 4
+// renderedSection is a section as it appears in one prompt
+type renderedSection struct {
+	section *PromptSection
+	text    string
+	tokens  int
 }
 
-func TrimContextForTokens(context types.SmartContext, maxTokens int) types.SmartContext {
-	trimmedContext := context
+// renderSections renders every non-empty section, in prompt order, cut down
+// to its budget and counted once
+func renderSections(context types.SmartContext, counter tokenizer.Counter) []renderedSection {
+	var rendered []renderedSection
+	for i := range PromptSections {
+		section := &PromptSections[i]
+		if block := section.Render(context); block != "" {
+			block = fitToBudget(block, section.Budget, section.KeepLatest, counter)
+			rendered = append(rendered, renderedSection{section, block, counter.Count(block)})
+		}
+	}
+	return rendered
+}
+
+// TrimContextForTokens drops context until the prompt for userMessage fits
+// model's PromptBudget. Sections go lowest priority first, the earlier
+// turns of the conversation oldest first once they're the lowest priority
+// left. Everything is rendered and counted once; dropping just takes its
+// tokens off the total.
+func TrimContextForTokens(context types.SmartContext, userMessage string, model Model) types.SmartContext {
+	counter := Limits(model).Tokens
+	sections := renderSections(context, counter)
+	history := recentHistory(context.RecentMessages, counter)
+
+	separator := counter.Count("\n\n")
+	coach, _ := prompts.Static(prompts.Coach)
+	total := counter.Count(coach) + turnTokens(ChatTurn{Role: RoleUser, Content: userMessage}, counter)
+	for _, s := range sections {
+		total += s.tokens + separator
+	}
+	historyTokens := make([]int, len(history))
+	for i, m := range history {
+		historyTokens[i] = turnTokens(historyTurn(m), counter)
+		total += historyTokens[i]
+	}
 
-	// Keep trimming until we're under the limit
-	for EstimateTokens(BuildSmartPrompt(trimmedContext, "test")) > maxTokens {
-		// Trim in priority order
-		if len(trimmedContext.RecentMessages) > 3 {
-			trimmedContext.RecentMessages = trimmedContext.RecentMessages[:len(trimmedContext.RecentMessages)-1]
-		} else if len(trimmedContext.KeyTasks) > 2 {
-			trimmedContext.KeyTasks = trimmedContext.KeyTasks[:len(trimmedContext.KeyTasks)-1]
-		} else if len(trimmedContext.Summary) > 200 {
-			trimmedContext.Summary = trimmedContext.Summary[:200] + "..."
-		} else {
-			break // Can't trim further
+	trimmedContext := context
+	for budget := PromptBudget(model); total > budget; {
+		i := lowestPrioritySection(sections)
+		if len(history) > 0 && (i < 0 || sections[i].section.Priority > historyPriority) {
+			total -= historyTokens[0]
+			history, historyTokens = history[1:], historyTokens[1:]
+			continue
+		}
+		if i < 0 {
+			break // Only required sections are left
 		}
+		sections[i].section.Clear(&trimmedContext)
+		total -= sections[i].tokens + separator
+		sections = append(sections[:i], sections[i+1:]...)
 	}
+	trimmedContext.RecentMessages = history
 
 	return trimmedContext
 }
+
+// lowestPrioritySection returns the index of the droppable section with
+// the lowest priority, or -1 when none is left
+func lowestPrioritySection(sections []renderedSection) int {
+	lowest := -1
+	for i, s := range sections {
+		if s.section.Clear == nil {
+			continue
+		}
+		if lowest < 0 || s.section.Priority < sections[lowest].section.Priority {
+			lowest = i
+		}
+	}
+	return lowest
+}
@@ -20,6 +20,16 @@ type GeminiStructuredResponse struct {
 	ActionItems []GeminiTaskItem   `json:"action_items"`
 	DeleteTasks []string           `json:"delete_tasks,omitempty"`
 	UpdateTasks []GeminiTaskUpdate `json:"update_tasks,omitempty"`
+
+	InstantiateTemplates []GeminiTemplateInstantiation `json:"instantiate_templates,omitempty"`
+	Remember             []string                      `json:"remember,omitempty"` // durable facts about the user
+
+	PromptVersion string `json:"-"` // coach prompt the reply was generated with
+}
+
+type GeminiTemplateInstantiation struct {
+	TemplateID string            `json:"template_id"`
+	Values     map[string]string `json:"values,omitempty"` // placeholder name -> value
 }
 
 type GeminiTaskUpdate struct {
@@ -27,7 +37,7 @@ type GeminiTaskUpdate struct {
 	Title         string     `json:"title,omitempty"`
 	Description   string     `json:"description,omitempty"`
 	Status        string     `json:"status,omitempty"`
-	DueDate       *time.Time `json:"due_date,omitempty"`
+	DueDate       *string    `json:"due_date,omitempty"` // ISO date or the user's phrase, e.g. "friday afternoon"
 	Decision      string     `json:"decision,omitempty"`
 	FollowUpDueAt *time.Time `json:"follow_up_due_at,omitempty"`
 	FollowedUp    *bool      `json:"followed_up,omitempty"`
@@ -53,56 +63,40 @@ func GeminiGenerateResponse(userInput string, context types.SmartContext) (Gemin
 	}
 
 	// Trim context to fit token limits
-	trimmedContext := TrimContextForTokens(context, 6000)
+	trimmedContext := TrimContextForTokens(context, userInput, Gemini)
 
 	// Build enhanced prompt
-	prompt := BuildSmartPrompt(trimmedContext, userInput)
+	prompt := BuildChatPrompt(trimmedContext, userInput, Gemini)
+
+	// Gemini calls the assistant "model"
+	contents := make([]map[string]interface{}, 0, len(prompt.Turns))
+	for _, turn := range prompt.Turns {
+		role := "user"
+		if turn.Role == RoleAssistant {
+			role = "model"
+		}
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": []map[string]string{{"text": turn.Content}},
+		})
+	}
 
 	// Enhanced request body with generation config for more consistent JSON
 	body := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
+		"systemInstruction": map[string]interface{}{
+			"parts": []map[string]string{{"text": prompt.System}},
 		},
+		"contents": contents,
 		"generationConfig": map[string]interface{}{
 			"temperature":     0.3,
-			"maxOutputTokens": 1000,
+			"maxOutputTokens": Limits(Gemini).MaxOutput,
 			"topP":            0.8,
 		},
 	}
 
-	jsonData, err := json.Marshal(body)
-	if err != nil {
-		return GeminiStructuredResponse{}, fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	// Create the HTTP request
-	req, err := http.NewRequest("POST", apiURL+"?key="+apiKey, bytes.NewReader(jsonData))
+	res, err := postGemini(apiKey, body)
 	if err != nil {
-		return GeminiStructuredResponse{}, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	// Add timeout to prevent hanging
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return GeminiStructuredResponse{}, fmt.Errorf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check HTTP status
-	if resp.StatusCode != http.StatusOK {
-		return GeminiStructuredResponse{}, fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-
-	var res map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return GeminiStructuredResponse{}, fmt.Errorf("failed to decode response: %v", err)
+		return GeminiStructuredResponse{}, err
 	}
 
 	// Extract text from Gemini API response
@@ -135,6 +129,79 @@ func GeminiGenerateResponse(userInput string, context types.SmartContext) (Gemin
 	return structured, nil
 }
 
+// postGemini sends a generateContent request and returns the decoded
+// response
+func postGemini(apiKey string, body map[string]interface{}) (map[string]interface{}, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", apiURL+"?key="+apiKey, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Add timeout to prevent hanging
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var res map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return res, nil
+}
+
+// generateGeminiJSON sends a single prompt and returns the JSON object in
+// the reply, for the background calls that answer in JSON
+func generateGeminiJSON(apiKey, prompt string, temperature float64, maxOutputTokens int) (string, error) {
+	body := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]string{
+					{"text": prompt},
+				},
+			},
+		},
+		"generationConfig": map[string]interface{}{
+			"temperature":     temperature,
+			"maxOutputTokens": maxOutputTokens,
+			"topP":            0.8,
+		},
+	}
+
+	res, err := postGemini(apiKey, body)
+	if err != nil {
+		return "", err
+	}
+	text, err := extractTextFromResponse(res)
+	if err != nil {
+		return "", err
+	}
+
+	jsonStr, found := extractJSONFromBraces(text)
+	if !found {
+		jsonStr, found = extractJSONFromCodeBlock(text)
+	}
+	if !found {
+		jsonStr, found = extractCompleteJSON(text)
+	}
+	if !found {
+		return "", fmt.Errorf("no valid JSON found in response: %s", text)
+	}
+	return jsonStr, nil
+}
+
 // Extract text from Gemini API response with proper error handling
 func extractTextFromResponse(res map[string]interface{}) (string, error) {
 	candidates, ok := res["candidates"].([]interface{})
@@ -510,6 +577,9 @@ func createFallbackResponse(userInput, rawText string) GeminiStructuredResponse
 		ActionItems: partialData.ActionItems,
 		DeleteTasks: partialData.DeleteTasks,
 		UpdateTasks: partialData.UpdateTasks,
+
+		InstantiateTemplates: partialData.InstantiateTemplates,
+		Remember:             partialData.Remember,
 	}
 }
 
@@ -622,120 +692,58 @@ func validateResponse(response GeminiStructuredResponse) error {
 		}
 	}
 
-	return nil
-}
-
-// GenerateSessionSummaryAndTitle generates both a summary and title in one API call
-func GenerateSessionSummaryAndTitle(messages []types.Message, context types.SmartContext) (string, string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY_SUMMARY_TITLE")
-	if apiKey == "" {
-		return "", "", fmt.Errorf("GEMINI_API_KEY_SUMMARY_TITLE not set")
-	}
-
-	// Build message log
-	var chatLog strings.Builder
-	for _, msg := range messages {
-		if msg.Sender == "user" {
-			chatLog.WriteString("User: ")
-		} else {
-			chatLog.WriteString("AI: ")
+	// Validate template instantiations
+	for i, inst := range response.InstantiateTemplates {
+		if strings.TrimSpace(inst.TemplateID) == "" {
+			return fmt.Errorf("template instantiation %d has empty template ID", i)
 		}
-		chatLog.WriteString(msg.Content)
-		chatLog.WriteString("\n")
 	}
 
-	// Prompt for both summary and title
-	prompt := fmt.Sprintf(`You are a helpful assistant. Based on the following conversation and context:
-
-Conversation:
-%s
-Context: %v
-
-Provide a JSON response with:
-- summary: A clear and concise paragraph summarizing the conversation
-- title: A short session title (<8 words)
-
-Respond in valid JSON format only. Example:
-{
-  "summary": "The user discussed communication challenges and received tasks to improve.",
-  "title": "Improving Communication Skills"
-}`, chatLog.String(), context)
-
-	body := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.3,
-			"maxOutputTokens": 300,
-			"topP":            0.8,
-		},
-	}
+	return nil
+}
 
-	jsonData, err := json.Marshal(body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %v", err)
-	}
+// SessionSummary is what the summarizer wrote for a session
+type SessionSummary struct {
+	Summary       string   `json:"summary"`
+	Title         string   `json:"title"`
+	Tags          []string `json:"tags"`
+	PromptVersion string   `json:"-"` // summary prompt it was generated with
+}
 
-	req, err := http.NewRequest("POST", apiURL+"?key="+apiKey, bytes.NewReader(jsonData))
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %v", err)
+// parseSessionSummary decodes the summarizer's JSON reply
+func parseSessionSummary(jsonStr, promptVersion string) (SessionSummary, error) {
+	var structured SessionSummary
+	if err := json.Unmarshal([]byte(jsonStr), &structured); err != nil {
+		return SessionSummary{}, fmt.Errorf("failed to parse JSON response: %v\nJSON: %s", err, jsonStr)
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", "", fmt.Errorf("request failed: %v", err)
+	if structured.Summary == "" || structured.Title == "" {
+		return SessionSummary{}, fmt.Errorf("empty summary or title in response")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
+	structured.Summary = strings.TrimSpace(structured.Summary)
+	structured.Title = strings.TrimSpace(structured.Title)
+	structured.PromptVersion = promptVersion
+	return structured, nil
+}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", "", fmt.Errorf("failed to decode response: %v", err)
+// GenerateSessionSummaryAndTitle generates a summary, title and suggested tags in one API call
+func GenerateSessionSummaryAndTitle(messages []types.Message, context types.SmartContext) (SessionSummary, error) {
+	apiKey := os.Getenv("GEMINI_API_KEY_SUMMARY_TITLE")
+	if apiKey == "" {
+		return SessionSummary{}, fmt.Errorf("GEMINI_API_KEY_SUMMARY_TITLE not set")
 	}
 
-	text, err := extractTextFromResponse(result)
+	// Prompt for both summary and title
+	prompt, version, err := summaryPrompt(messages, context)
 	if err != nil {
-		return "", "", err
+		return SessionSummary{}, err
 	}
 
-	// Use the robust JSON extraction for summary/title as well
-	jsonStr, found := extractJSONFromBraces(text)
-	if !found {
-		jsonStr, found = extractJSONFromCodeBlock(text)
-	}
-	if !found {
-		jsonStr, found = extractCompleteJSON(text)
-	}
-
-	if !found {
-		return "", "", fmt.Errorf("no valid JSON found in summary response: %s", text)
-	}
-
-	// Parse JSON response
-	var structured struct {
-		Summary string `json:"summary"`
-		Title   string `json:"title"`
-	}
-
-	if err := json.Unmarshal([]byte(jsonStr), &structured); err != nil {
-		return "", "", fmt.Errorf("failed to parse JSON response: %v\nJSON: %s", err, jsonStr)
-	}
-
-	if structured.Summary == "" || structured.Title == "" {
-		return "", "", fmt.Errorf("empty summary or title in response")
+	jsonStr, err := generateGeminiJSON(apiKey, prompt, 0.3, 300)
+	if err != nil {
+		return SessionSummary{}, fmt.Errorf("summary: %w", err)
 	}
-
-	return strings.TrimSpace(structured.Summary), strings.TrimSpace(structured.Title), nil
+	return parseSessionSummary(jsonStr, version)
 }
 
 // Backward compatibility wrapper
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/tokenizer"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"slices"
+)
+
+// Roles of the turns in a ChatPrompt
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatTurn is one message of the conversation sent to the model
+type ChatTurn struct {
+	Role    string
+	Content string
+}
+
+// ChatPrompt is a request as the providers take it: a system instruction
+// with the persona and context, then the conversation in alternating
+// turns, starting with the user and ending with their new message
+type ChatPrompt struct {
+	System string
+	Turns  []ChatTurn
+}
+
+const (
+	historyPriority = 90   // the conversation outlives every section but DATE
+	historyBudget   = 1500 // most tokens the earlier turns may use
+	turnOverhead    = 4    // role and delimiters around each turn
+)
+
+// recentHistory returns the newest messages in chronological order, at
+// most config.ContextConfig.MaxRecentMessages of them and historyBudget
+// tokens. Messages may come in either order.
+func recentHistory(messages []types.Message, counter tokenizer.Counter) []types.Message {
+	history := slices.Clone(messages)
+	slices.SortStableFunc(history, func(a, b types.Message) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	if limit := config.ContextConfig.MaxRecentMessages; limit >= 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	total := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		if total += turnTokens(historyTurn(history[i]), counter); total > historyBudget {
+			return history[i+1:]
+		}
+	}
+	return history
+}
+
+// historyTurn converts a stored message. Replies are stored as plain text,
+// so they're sent back in the JSON shape the coach must answer in; plain
+// replies in the history would teach it to drop the format.
+func historyTurn(m types.Message) ChatTurn {
+	if m.Sender == "user" {
+		return ChatTurn{Role: RoleUser, Content: m.Content}
+	}
+	reply, _ := json.Marshal(map[string]string{"response": m.Content})
+	return ChatTurn{Role: RoleAssistant, Content: string(reply)}
+}
+
+// chatTurns turns the history and the new message into alternating turns.
+// Consecutive messages from one side, such as a message whose reply
+// failed, are merged, and a conversation opened by the coach starts at
+// the user's first message.
+func chatTurns(history []types.Message, userMessage string) []ChatTurn {
+	var turns []ChatTurn
+	add := func(turn ChatTurn) {
+		switch {
+		case len(turns) == 0 && turn.Role != RoleUser:
+			// Skip replies before the user's first message
+		case len(turns) > 0 && turns[len(turns)-1].Role == turn.Role:
+			turns[len(turns)-1].Content += "\n\n" + turn.Content
+		default:
+			turns = append(turns, turn)
+		}
+	}
+	for _, m := range history {
+		add(historyTurn(m))
+	}
+	add(ChatTurn{Role: RoleUser, Content: userMessage})
+	return turns
+}
+
+func turnTokens(turn ChatTurn, counter tokenizer.Counter) int {
+	return counter.Count(turn.Content) + turnOverhead
+}
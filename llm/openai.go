@@ -29,22 +29,24 @@ func OpenAIGenerateResponse(userInput string, context types.SmartContext) (Gemin
 	}
 
 	// Trim context to fit token limits
-	trimmedContext := TrimContextForTokens(context, 6000)
+	trimmedContext := TrimContextForTokens(context, userInput, OpenAI)
 
 	// Build enhanced prompt
-	prompt := BuildSmartPrompt(trimmedContext, userInput)
+	prompt := BuildChatPrompt(trimmedContext, userInput, OpenAI)
+
+	messages := []map[string]interface{}{
+		{"role": "system", "content": prompt.System},
+	}
+	for _, turn := range prompt.Turns {
+		messages = append(messages, map[string]interface{}{"role": turn.Role, "content": turn.Content})
+	}
 
 	// OpenAI request body
 	body := map[string]interface{}{
-		"model": "gpt-3.5-turbo",
-		"messages": []map[string]interface{}{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
+		"model":       "gpt-3.5-turbo",
+		"messages":    messages,
 		"temperature": 0.3,
-		"max_tokens":  1000,
+		"max_tokens":  Limits(OpenAI).MaxOutput,
 		"top_p":       0.8,
 	}
 
@@ -136,40 +138,17 @@ func extractTextFromOpenAIResponse(res map[string]interface{}) (string, error) {
 }
 
 // OpenAI version of session summary and title generation
-func OpenAIGenerateSessionSummaryAndTitle(messages []types.Message, context types.SmartContext) (string, string, error) {
+func OpenAIGenerateSessionSummaryAndTitle(messages []types.Message, context types.SmartContext) (SessionSummary, error) {
 	apiKey := os.Getenv("OPENAI_API_KEY_SUMMARY_TITLE")
 	if apiKey == "" {
-		return "", "", fmt.Errorf("OPENAI_API_KEY_SUMMARY_TITLE not set")
-	}
-
-	// Build message log
-	var chatLog strings.Builder
-	for _, msg := range messages {
-		if msg.Sender == "user" {
-			chatLog.WriteString("User: ")
-		} else {
-			chatLog.WriteString("AI: ")
-		}
-		chatLog.WriteString(msg.Content)
-		chatLog.WriteString("\n")
+		return SessionSummary{}, fmt.Errorf("OPENAI_API_KEY_SUMMARY_TITLE not set")
 	}
 
 	// Prompt for both summary and title
-	prompt := fmt.Sprintf(`You are a helpful assistant. Based on the following conversation and context:
-
-Conversation:
-%s
-Context: %v
-
-Provide a JSON response with:
-- summary: A clear and concise paragraph summarizing the conversation
-- title: A short session title (<8 words)
-
-Respond in valid JSON format only. Example:
-{
-  "summary": "The user discussed communication challenges and received tasks to improve.",
-  "title": "Improving Communication Skills"
-}`, chatLog.String(), context)
+	prompt, version, err := summaryPrompt(messages, context)
+	if err != nil {
+		return SessionSummary{}, err
+	}
 
 	body := map[string]interface{}{
 		"model": "gpt-3.5-turbo",
@@ -186,12 +165,12 @@ Respond in valid JSON format only. Example:
 
 	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %v", err)
+		return SessionSummary{}, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
 	req, err := http.NewRequest("POST", openaiURL, bytes.NewReader(jsonData))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %v", err)
+		return SessionSummary{}, fmt.Errorf("failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+apiKey)
@@ -199,22 +178,22 @@ Respond in valid JSON format only. Example:
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("request failed: %v", err)
+		return SessionSummary{}, fmt.Errorf("request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("API returned status %d", resp.StatusCode)
+		return SessionSummary{}, fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", "", fmt.Errorf("failed to decode response: %v", err)
+		return SessionSummary{}, fmt.Errorf("failed to decode response: %v", err)
 	}
 
 	text, err := extractTextFromOpenAIResponse(result)
 	if err != nil {
-		return "", "", err
+		return SessionSummary{}, err
 	}
 
 	// Use the robust JSON extraction for summary/title as well
@@ -227,24 +206,10 @@ Respond in valid JSON format only. Example:
 	}
 
 	if !found {
-		return "", "", fmt.Errorf("no valid JSON found in summary response: %s", text)
-	}
-
-	// Parse JSON response
-	var structured struct {
-		Summary string `json:"summary"`
-		Title   string `json:"title"`
-	}
-
-	if err := json.Unmarshal([]byte(jsonStr), &structured); err != nil {
-		return "", "", fmt.Errorf("failed to parse JSON response: %v\nJSON: %s", err, jsonStr)
-	}
-
-	if structured.Summary == "" || structured.Title == "" {
-		return "", "", fmt.Errorf("empty summary or title in response")
+		return SessionSummary{}, fmt.Errorf("no valid JSON found in summary response: %s", text)
 	}
 
-	return strings.TrimSpace(structured.Summary), strings.TrimSpace(structured.Title), nil
+	return parseSessionSummary(jsonStr, version)
 }
 
 // Backward compatibility wrapper
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"clementus360/ai-helper/prompts"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxPatternItems caps how many struggles or strategies are kept
+const maxPatternItems = 5
+
+// PatternAnalysis is what the model found across a user's sessions
+type PatternAnalysis struct {
+	Struggles     []string
+	Strategies    []string
+	PromptVersion string // patterns prompt it was generated with
+}
+
+// AnalyzeStrugglesAndStrategies reads recent session summaries for what the
+// user keeps getting stuck on and what has helped them. The previous
+// patterns are passed in so lasting themes carry over between runs.
+func AnalyzeStrugglesAndStrategies(summaries []string, previous types.UserPatterns) (PatternAnalysis, error) {
+	apiKey := os.Getenv("GEMINI_API_KEY_SUMMARY_TITLE")
+	if apiKey == "" {
+		return PatternAnalysis{}, fmt.Errorf("GEMINI_API_KEY_SUMMARY_TITLE not set")
+	}
+
+	prompt, version, err := prompts.Render(prompts.Patterns, prompts.PatternsData{
+		Sessions:           summaries,
+		PreviousStruggles:  previous.CommonStruggles,
+		PreviousStrategies: previous.SuccessfulStrategies,
+		MaxItems:           maxPatternItems,
+	})
+	if err != nil {
+		return PatternAnalysis{}, err
+	}
+
+	jsonStr, err := generateGeminiJSON(apiKey, prompt, 0.2, 300)
+	if err != nil {
+		return PatternAnalysis{}, fmt.Errorf("pattern analysis: %w", err)
+	}
+
+	var structured struct {
+		Struggles  []string `json:"struggles"`
+		Strategies []string `json:"strategies"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &structured); err != nil {
+		return PatternAnalysis{}, fmt.Errorf("failed to parse JSON response: %v\nJSON: %s", err, jsonStr)
+	}
+
+	return PatternAnalysis{
+		Struggles:     cleanPatternItems(structured.Struggles),
+		Strategies:    cleanPatternItems(structured.Strategies),
+		PromptVersion: version,
+	}, nil
+}
+
+// cleanPatternItems trims items, drops blanks and repeats, and keeps at
+// most maxPatternItems
+func cleanPatternItems(items []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		key := strings.ToLower(item)
+		if item == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, item)
+		if len(out) == maxPatternItems {
+			break
+		}
+	}
+	return out
+}
@@ -1,101 +1,93 @@
 package llm
 
 import (
+	"clementus360/ai-helper/patterns"
+	"clementus360/ai-helper/prompts"
 	"clementus360/ai-helper/types"
 	"fmt"
 	"strings"
-	"time"
 )
 
-func BuildSmartPrompt(context types.SmartContext, userMessage string) string {
-	systemInstructions := `
-CRITICAL: You MUST respond in valid JSON format. No exceptions. No text outside JSON.
-
-You are a productivity coach helping people break through creative blocks and procrastination.
+// summaryPrompt asks for a session's summary, title and tags, and returns
+// the prompt's version with it
+func summaryPrompt(messages []types.Message, context types.SmartContext) (string, string, error) {
+	var chatLog strings.Builder
+	for _, msg := range messages {
+		if msg.Sender == "user" {
+			chatLog.WriteString("User: ")
+		} else {
+			chatLog.WriteString("AI: ")
+		}
+		chatLog.WriteString(msg.Content)
+		chatLog.WriteString("\n")
+	}
 
-RESPONSE FORMAT (MANDATORY):
-{
-  "response": "your message here",
-  "action_items": [{"title": "task name", "description": "details"}],
-  "delete_tasks": ["task_id"],
-  "update_tasks": [{"id": "task_id", "status": "completed"}]
+	return prompts.Render(prompts.Summary, prompts.SummaryData{Conversation: chatLog.String(), Context: context})
 }
 
-COACHING STYLE:
-- Lead with insight or perspective first, then questions if helpful
-- Be warm but direct - like a smart friend who cares about your progress
-- Share what you notice about patterns or common challenges
-- Offer specific, actionable suggestions
-- Celebrate progress genuinely
-- When someone's stuck, help them see the situation differently
-
-TASK MANAGEMENT:
-- Mark tasks "completed" when users mention doing, trying, or finishing something
-- Look for phrases like "I did", "I tried", "I finished", "I completed", "I worked on"
-- Create action items when users need concrete next steps
-- Delete only if user explicitly asks or task is clearly irrelevant
-- Update due dates when requested (format: "2025-07-13T00:00:00Z")
-- Reference tasks by title, never ID when talking to user
-
-UPDATE RULES:
-- Only include "id" + fields being changed
-- Valid statuses: "pending", "completed", "cancelled"
-- Never include empty fields
-- One task per update unless user mentions multiple
-
-TONE EXAMPLES:
-❌ "What's one small step you could take?"
-✅ "This usually comes down to [insight]. Try [specific suggestion]. How does that land with you?"
-
-❌ "How might you approach this?"
-✅ "Here's what I've noticed works: [perspective]. The key is [insight]. Want to try [specific action]?"
-
-REMEMBER: Valid JSON only. No extra text.
-`
-
-	sections := []string{}
-
-	// Add current date
-	currentDate := time.Now().Format("Monday, January 2, 2006")
-	sections = append(sections, fmt.Sprintf("DATE: %s", currentDate))
-
-	// Conversation summary
-	if context.Summary != "" {
-		sections = append(sections, fmt.Sprintf("TOPIC: %s", context.Summary))
+// BuildChatPrompt puts the coach instructions and every non-empty context
+// section, in prompt order, into the system instruction, followed by the
+// recent conversation and the user's message as turns. Budgets are counted
+// in model's vocabulary.
+func BuildChatPrompt(context types.SmartContext, userMessage string, model Model) ChatPrompt {
+	counter := Limits(model).Tokens
+
+	coach, _ := prompts.Static(prompts.Coach)
+	blocks := []string{coach}
+	for _, s := range renderSections(context, counter) {
+		blocks = append(blocks, s.text)
 	}
 
-	// Current tasks (simplified)
-	if len(context.KeyTasks) > 0 {
-		taskBlock := "TASKS:\n"
-		for _, task := range context.KeyTasks {
-			taskBlock += fmt.Sprintf("- %s (ID: %s) - %s\n", task.Title, task.ID, task.Status)
-		}
-		sections = append(sections, taskBlock)
+	return ChatPrompt{
+		System: strings.Join(blocks, "\n\n"),
+		Turns:  chatTurns(recentHistory(context.RecentMessages, counter), userMessage),
 	}
+}
 
-	// Recent conversation (last 3 exchanges max)
-	if len(context.RecentMessages) > 0 {
-		convo := "RECENT:\n"
-		limit := 3
-		if len(context.RecentMessages) < limit {
-			limit = len(context.RecentMessages)
+// formatPatterns renders the PATTERNS section, or "" when nothing has been
+// learned yet
+func formatPatterns(p types.UserPatterns) string {
+	var lines []string
+	switch p.PreferredResponseStyle {
+	case patterns.StyleTaskFocused:
+		lines = append(lines, "- Prefers concrete next steps over long discussion")
+	case patterns.StyleDiscussionFocused:
+		lines = append(lines, "- Likes to talk things through before committing to tasks")
+	}
+	if p.TimePreferences != "" {
+		lines = append(lines, fmt.Sprintf("- Timing: %s", p.TimePreferences))
+	}
+	if rate, ok := p.TaskCompletionRates[patterns.TaskTypeAll]; ok {
+		line := fmt.Sprintf("- Completes %.0f%% of tasks", rate*100)
+		var byType []string
+		if ai, ok := p.TaskCompletionRates[patterns.TaskTypeAISuggested]; ok {
+			byType = append(byType, fmt.Sprintf("%.0f%% of ones you suggest", ai*100))
 		}
-
-		for i := limit - 1; i >= 0; i-- {
-			msg := context.RecentMessages[i]
-			sender := "USER"
-			if msg.Sender != "user" {
-				sender = "YOU"
-			}
-			convo += fmt.Sprintf("%s: %s\n", sender, msg.Content)
+		if own, ok := p.TaskCompletionRates[patterns.TaskTypeSelfCreated]; ok {
+			byType = append(byType, fmt.Sprintf("%.0f%% of their own", own*100))
+		}
+		if len(byType) > 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(byType, ", "))
 		}
-		sections = append(sections, convo)
+		lines = append(lines, line)
 	}
+	if len(p.CommonStruggles) > 0 {
+		lines = append(lines, fmt.Sprintf("- Struggles with: %s", strings.Join(p.CommonStruggles, "; ")))
+	}
+	if len(p.SuccessfulStrategies) > 0 {
+		lines = append(lines, fmt.Sprintf("- What has worked: %s", strings.Join(p.SuccessfulStrategies, "; ")))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "PATTERNS:\n" + strings.Join(lines, "\n") + "\n"
+}
 
-	// Current message
-	sections = append(sections, fmt.Sprintf("USER: %s", userMessage))
-
-	fullPrompt := fmt.Sprintf("%s\n\n%s", systemInstructions, strings.Join(sections, "\n\n"))
-
-	return fullPrompt
+// formatFocusDuration renders seconds as "1h 20m" or "15m"
+func formatFocusDuration(seconds int) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
 }
@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"clementus360/ai-helper/tokenizer"
+	"clementus360/ai-helper/types"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PromptSection renders one part of the SmartContext into the prompt.
+// Sections appear in the order of PromptSections; when the prompt is over
+// its token limit, the lowest-priority sections are dropped first.
+type PromptSection struct {
+	Name       string
+	Priority   int  // higher survives trimming longer
+	Budget     int  // most tokens the section may use, 0 for no cap
+	KeepLatest bool // over budget, drop the first lines rather than the last
+
+	// Render returns the section, header included, or "" when there's
+	// nothing to say
+	Render func(ctx types.SmartContext) string
+
+	// Clear removes what the section renders from ctx. Sections without
+	// one are never dropped.
+	Clear func(ctx *types.SmartContext)
+}
+
+// PromptSections are rendered in this order. Add a section here to give
+// the coach a new piece of context.
+var PromptSections = []PromptSection{
+	{Name: "DATE", Priority: 100, Render: renderDate},
+	{Name: "PROFILE", Priority: 60, Budget: 50, Render: renderProfile, Clear: func(ctx *types.SmartContext) {
+		ctx.Profile.Locale, ctx.Profile.WorkdayStart, ctx.Profile.WorkdayEnd = "", "", ""
+	}},
+	{Name: "MEMORIES", Priority: 65, Budget: 300, Render: renderMemories, Clear: func(ctx *types.SmartContext) {
+		ctx.Memories = nil
+	}},
+	{Name: "PATTERNS", Priority: 45, Budget: 250, Render: func(ctx types.SmartContext) string {
+		return formatPatterns(ctx.UserPatterns)
+	}, Clear: func(ctx *types.SmartContext) {
+		ctx.UserPatterns = types.UserPatterns{}
+	}},
+	{Name: "SIGNALS", Priority: 55, Budget: 120, Render: renderSignals, Clear: func(ctx *types.SmartContext) {
+		ctx.PrioritySignals = nil
+	}},
+	{Name: "SESSION", Priority: 30, Budget: 60, Render: renderSessionMetrics, Clear: func(ctx *types.SmartContext) {
+		ctx.SessionMetrics = types.SessionMetrics{}
+	}},
+	{Name: "TOPIC", Priority: 70, Budget: 250, Render: renderSummary, Clear: func(ctx *types.SmartContext) {
+		ctx.Summary = ""
+	}},
+	{Name: "TASKS", Priority: 80, Budget: 600, Render: renderTasks, Clear: func(ctx *types.SmartContext) {
+		ctx.KeyTasks = nil
+	}},
+	{Name: "FOCUS", Priority: 40, Budget: 150, Render: renderFocus, Clear: func(ctx *types.SmartContext) {
+		ctx.Focus = types.FocusSummary{}
+	}},
+	{Name: "TEMPLATES", Priority: 35, Budget: 300, Render: renderTemplates, Clear: func(ctx *types.SmartContext) {
+		ctx.Templates = nil
+	}},
+}
+
+// fitToBudget cuts a section down to budget tokens, dropping whole lines
+// after the header and noting how many were left out. A one-line section
+// is shortened instead. Each line is counted once.
+func fitToBudget(block string, budget int, keepLatest bool, counter tokenizer.Counter) string {
+	if budget <= 0 || counter.Count(block) <= budget {
+		return block
+	}
+
+	lines := strings.Split(strings.TrimRight(block, "\n"), "\n")
+	if len(lines) == 1 {
+		return tokenizer.Truncate(counter, block, budget) + "..."
+	}
+
+	header, body := lines[0], lines[1:]
+	tokens := make([]int, len(body))
+	total := counter.Count(header)
+	for i, line := range body {
+		tokens[i] = counter.Count("\n" + line)
+		total += tokens[i]
+	}
+
+	dropped := 0
+	for len(body) > 1 && total > budget {
+		if keepLatest {
+			total -= tokens[0]
+			body, tokens = body[1:], tokens[1:]
+		} else {
+			total -= tokens[len(tokens)-1]
+			body, tokens = body[:len(body)-1], tokens[:len(tokens)-1]
+		}
+		dropped++
+	}
+
+	note := fmt.Sprintf("(%d more omitted)", dropped)
+	if keepLatest {
+		return strings.Join(append([]string{header, note}, body...), "\n") + "\n"
+	}
+	return strings.Join(append(append([]string{header}, body...), note), "\n") + "\n"
+}
+
+// Current date in the user's timezone
+func renderDate(ctx types.SmartContext) string {
+	loc := time.UTC
+	if ctx.Profile.Timezone != "" {
+		if userLoc, err := time.LoadLocation(ctx.Profile.Timezone); err == nil {
+			loc = userLoc
+		}
+	}
+	currentDate := time.Now().In(loc).Format("Monday, January 2, 2006 3:04PM")
+	return fmt.Sprintf("DATE: %s (%s)", currentDate, loc)
+}
+
+// User's locale and working hours, for scheduling suggestions
+func renderProfile(ctx types.SmartContext) string {
+	var lines []string
+	if ctx.Profile.Locale != "" {
+		lines = append(lines, fmt.Sprintf("LOCALE: %s", ctx.Profile.Locale))
+	}
+	if ctx.Profile.WorkdayStart != "" && ctx.Profile.WorkdayEnd != "" {
+		lines = append(lines, fmt.Sprintf("WORKING HOURS: %s-%s", ctx.Profile.WorkdayStart, ctx.Profile.WorkdayEnd))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Long-term memories relevant to this message
+func renderMemories(ctx types.SmartContext) string {
+	if len(ctx.Memories) == 0 {
+		return ""
+	}
+	block := "MEMORIES:\n"
+	for _, m := range ctx.Memories {
+		block += fmt.Sprintf("- %s\n", m.Content)
+	}
+	return block
+}
+
+// Hints worked out from the session's metrics
+func renderSignals(ctx types.SmartContext) string {
+	if len(ctx.PrioritySignals) == 0 {
+		return ""
+	}
+	block := "SIGNALS:\n"
+	for _, s := range ctx.PrioritySignals {
+		block += fmt.Sprintf("- %s\n", s)
+	}
+	return block
+}
+
+// How far this session has got
+func renderSessionMetrics(ctx types.SmartContext) string {
+	m := ctx.SessionMetrics
+	if m.MessageCount == 0 && m.TasksCreated == 0 {
+		return ""
+	}
+	line := fmt.Sprintf("SESSION: %d messages, %d tasks created, %d completed", m.MessageCount, m.TasksCreated, m.TasksCompleted)
+	if m.UserEngagementLevel != "" {
+		line += fmt.Sprintf(", engagement %s", m.UserEngagementLevel)
+	}
+	return line
+}
+
+// Conversation summary
+func renderSummary(ctx types.SmartContext) string {
+	if ctx.Summary == "" {
+		return ""
+	}
+	return fmt.Sprintf("TOPIC: %s", ctx.Summary)
+}
+
+// Current tasks (simplified)
+func renderTasks(ctx types.SmartContext) string {
+	if len(ctx.KeyTasks) == 0 {
+		return ""
+	}
+	columnNames := map[string]string{}
+	for _, column := range ctx.Columns {
+		columnNames[column.ID] = column.Name
+	}
+
+	block := "TASKS:\n"
+	for _, task := range ctx.KeyTasks {
+		block += fmt.Sprintf("- %s (ID: %s) - %s", task.Title, task.ID, task.Status)
+		if task.ColumnID != nil && columnNames[*task.ColumnID] != "" {
+			block += fmt.Sprintf(" [column: %s]", columnNames[*task.ColumnID])
+		}
+		block += "\n"
+	}
+	return block
+}
+
+// Tracked focus time
+func renderFocus(ctx types.SmartContext) string {
+	if ctx.Focus.WeekSeconds == 0 && ctx.Focus.Running == nil {
+		return ""
+	}
+	block := fmt.Sprintf("FOCUS: %s today, %s in the last 7 days\n",
+		formatFocusDuration(ctx.Focus.TodaySeconds), formatFocusDuration(ctx.Focus.WeekSeconds))
+	for _, t := range ctx.Focus.TopTasks {
+		if t.TaskTitle != "" {
+			block += fmt.Sprintf("- %s: %s over %d sessions\n", t.TaskTitle, formatFocusDuration(t.Seconds), t.Sessions)
+		}
+	}
+	if ctx.Focus.Running != nil {
+		block += "- A focus timer is running right now\n"
+	}
+	return block
+}
+
+// Templates the coach can instantiate
+func renderTemplates(ctx types.SmartContext) string {
+	if len(ctx.Templates) == 0 {
+		return ""
+	}
+	block := "TEMPLATES:\n"
+	for _, tmpl := range ctx.Templates {
+		block += fmt.Sprintf("- %s (ID: %s, %d steps)", tmpl.Name, tmpl.ID, len(tmpl.Steps))
+		if len(tmpl.Placeholders) > 0 {
+			block += fmt.Sprintf(" - placeholders: %s", strings.Join(tmpl.Placeholders, ", "))
+		}
+		block += "\n"
+	}
+	return block
+}
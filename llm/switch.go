@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"clementus360/ai-helper/prompts"
 	"clementus360/ai-helper/types"
 	"fmt"
 )
@@ -14,12 +15,18 @@ const (
 
 // GenerateResponse generates a response using the specified AI model
 func GenerateResponse(userInput string, context types.SmartContext, model Model) (GeminiStructuredResponse, error) {
+	var response GeminiStructuredResponse
+	var err error
 	switch model {
 	case OpenAI:
-		return OpenAIGenerateResponse(userInput, context)
+		response, err = OpenAIGenerateResponse(userInput, context)
 	case Gemini:
-		return GeminiGenerateResponse(userInput, context)
+		response, err = GeminiGenerateResponse(userInput, context)
 	default:
 		return GeminiStructuredResponse{}, fmt.Errorf("unsupported model: %s (supported: %s, %s)", model, OpenAI, Gemini)
 	}
+
+	// Prompts are only replaced at startup, so this is the one just used
+	response.PromptVersion = prompts.Version(prompts.Coach)
+	return response, err
 }
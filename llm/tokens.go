@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/tokenizer"
+)
+
+// ModelLimits describes how much a model can read and write per request
+type ModelLimits struct {
+	ContextWindow int               // input and output tokens together
+	MaxOutput     int               // tokens reserved for the reply
+	Tokens        tokenizer.Counter // counts tokens in the model's vocabulary
+}
+
+var modelLimits = map[Model]ModelLimits{
+	OpenAI: {ContextWindow: 16_385, MaxOutput: 1000, Tokens: tokenizer.CL100K},         // gpt-3.5-turbo
+	Gemini: {ContextWindow: 1_048_576, MaxOutput: 1000, Tokens: tokenizer.Estimator{}}, // gemini-2.0-flash
+}
+
+// Limits returns the limits of model. Unknown models get Gemini's.
+func Limits(model Model) ModelLimits {
+	if limits, ok := modelLimits[model]; ok {
+		return limits
+	}
+	return modelLimits[Gemini]
+}
+
+// CountTokens counts text in model's vocabulary
+func CountTokens(model Model, text string) int {
+	return Limits(model).Tokens.Count(text)
+}
+
+// PromptBudget is how many tokens a prompt to model may use: the configured
+// maximum, but never more than the context window leaves after the reply
+func PromptBudget(model Model) int {
+	limits := Limits(model)
+	budget := limits.ContextWindow - limits.MaxOutput
+	if configured := config.ContextConfig.MaxTokens; configured > 0 && configured < budget {
+		budget = configured
+	}
+	return budget
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/jobs"
 	"clementus360/ai-helper/middleware"
+	"clementus360/ai-helper/prompts"
 	"clementus360/ai-helper/routes"
 	"clementus360/ai-helper/supabase"
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -29,8 +32,12 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start background jobs; they stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	startBackgroundJobs(jobsCtx)
+
 	// Start server with graceful shutdown
-	startServerWithGracefulShutdown(server)
+	startServerWithGracefulShutdown(server, stopJobs)
 }
 
 // initializeApp initializes all application dependencies
@@ -40,6 +47,14 @@ func initializeApp() error {
 
 	supabase.Init()
 
+	// Prompt templates edited outside the binary
+	if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+		if err := prompts.LoadDir(dir); err != nil {
+			return fmt.Errorf("failed to load prompts from %s: %w", dir, err)
+		}
+		config.Logger.Info("Loaded prompt overrides from ", dir)
+	}
+
 	config.Logger.Info("Application initialized successfully")
 	return nil
 }
@@ -52,6 +67,13 @@ func setupRoutes() http.Handler {
 	routes.RegisterChatRoutes(mux)
 	routes.RegisterTaskRoutes(mux)
 	routes.RegisterSessionRoutes(mux)
+	routes.RegisterProfileRoutes(mux)
+	routes.RegisterTemplateRoutes(mux)
+	routes.RegisterFocusRoutes(mux)
+	routes.RegisterBoardRoutes(mux)
+	routes.RegisterSearchRoutes(mux)
+	routes.RegisterMemoryRoutes(mux)
+	routes.RegisterAccountRoutes(mux)
 
 	// Apply middleware
 	handler := middleware.CORSMiddleware(mux)
@@ -60,8 +82,24 @@ func setupRoutes() http.Handler {
 	return handler
 }
 
+// startBackgroundJobs launches the periodic maintenance jobs
+func startBackgroundJobs(ctx context.Context) {
+	go jobs.Every(ctx, "session lifecycle", config.SessionSweepInterval, func(now time.Time) error {
+		return supabase.SweepSessions(supabase.ServiceClient, now)
+	})
+	go jobs.Every(ctx, "export cleanup", time.Hour, func(now time.Time) error {
+		return supabase.CleanupExpiredExports(supabase.ServiceClient, now)
+	})
+	go jobs.Every(ctx, "account purge", 15*time.Minute, func(now time.Time) error {
+		return supabase.PurgeDueAccounts(supabase.ServiceClient, now)
+	})
+	go jobs.Every(ctx, "pattern analysis", config.PatternAnalysisInterval, func(now time.Time) error {
+		return supabase.AnalyzeDuePatterns(supabase.ServiceClient, now)
+	})
+}
+
 // startServerWithGracefulShutdown starts the server with graceful shutdown support
-func startServerWithGracefulShutdown(server *http.Server) {
+func startServerWithGracefulShutdown(server *http.Server, stopJobs context.CancelFunc) {
 	// Channel to listen for interrupt signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -78,6 +116,7 @@ func startServerWithGracefulShutdown(server *http.Server) {
 	// Wait for interrupt signal
 	<-stop
 	config.Logger.Info("Shutting down server...")
+	stopJobs()
 
 	// Create a context with timeout for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns text into a vector for similarity search. Name identifies
+// the embedding space so vectors from different embedders are never compared.
+type Embedder interface {
+	Name() string
+	Embed(text string) ([]float32, error)
+}
+
+// Default is the embedder used for new and recalled memories. Replace it at
+// startup to use a hosted embedding model.
+var Default Embedder = NewHashingEmbedder(1024)
+
+// HashingEmbedder is an offline embedder that hashes words and word pairs
+// into a fixed number of signed buckets. It needs no vocabulary or network
+// and captures word overlap, which is enough to match short facts.
+type HashingEmbedder struct {
+	Dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{Dims: dims}
+}
+
+func (e *HashingEmbedder) Name() string {
+	return "hashing-" + strconv.Itoa(e.Dims)
+}
+
+func (e *HashingEmbedder) Embed(text string) ([]float32, error) {
+	vec := make([]float32, e.Dims)
+	words := tokenize(text)
+
+	features := make([]string, 0, len(words)*2)
+	features = append(features, words...)
+	for i := 1; i < len(words); i++ {
+		features = append(features, words[i-1]+" "+words[i])
+	}
+
+	for _, f := range features {
+		h := fnv.New64a()
+		h.Write([]byte(f))
+		sum := h.Sum64()
+		index := int(sum % uint64(e.Dims))
+		if sum&(1<<63) != 0 {
+			vec[index]--
+		} else {
+			vec[index]++
+		}
+	}
+
+	normalize(vec)
+	return vec, nil
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 if their
+// lengths differ
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// stopWords are too common to say anything about relevance
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "from": true, "has": true, "have": true,
+	"i": true, "in": true, "is": true, "it": true, "its": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "so": true, "that": true, "the": true,
+	"their": true, "they": true, "this": true, "to": true, "was": true, "we": true,
+	"with": true, "you": true, "your": true, "user": true, "users": true,
+}
+
+// tokenize lowercases text, splits it into words and drops stop words.
+// A trailing "s" is trimmed so "meetings" matches "meeting".
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	for _, w := range fields {
+		if stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+func normalize(vec []float32) {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+}
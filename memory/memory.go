@@ -0,0 +1,20 @@
+package memory
+
+import "clementus360/ai-helper/types"
+
+// DuplicateThreshold is the similarity above which a new fact is treated
+// as a restatement of an existing memory
+const DuplicateThreshold = 0.85
+
+// MinRelevance is the lowest similarity a memory needs to be recalled
+const MinRelevance = 0.1
+
+// IsDuplicate reports whether vec restates one of the existing memories
+func IsDuplicate(vec []float32, embedder string, memories []types.Memory) bool {
+	for _, m := range memories {
+		if m.Embedder == embedder && Cosine(vec, m.Embedding) >= DuplicateThreshold {
+			return true
+		}
+	}
+	return false
+}
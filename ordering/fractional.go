@@ -0,0 +1,169 @@
+package ordering
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Keys are base-36 fractions written as digit strings: "h" is 17/36 and
+// "h8" sits just after it. Only 0-9 and a-z are used so that database text
+// ordering agrees with byte ordering under common collations. A key never
+// ends in "0", which guarantees there is always room between two keys.
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// MaxKeyLen is how long a key may get before its list should be respaced
+// with KeysBetween. Repeatedly inserting at the same spot adds a digit
+// every few inserts, so without respacing keys grow without bound.
+const MaxKeyLen = 16
+
+// KeyBetween returns a key that sorts strictly between a and b.
+// An empty a means "before everything", an empty b "after everything".
+func KeyBetween(a, b string) (string, error) {
+	if err := validateKey(a); err != nil {
+		return "", err
+	}
+	if err := validateKey(b); err != nil {
+		return "", err
+	}
+	if a != "" && b != "" && a >= b {
+		return "", fmt.Errorf("key %q must sort before %q", a, b)
+	}
+	return midpoint(a, b), nil
+}
+
+// KeysAfter returns n ascending keys that all sort after a
+func KeysAfter(a string, n int) ([]string, error) {
+	return KeysBetween(a, "", n)
+}
+
+// KeysBetween returns n ascending keys spread evenly between a and b, with
+// empty bounds meaning the same as in KeyBetween. The keys are as short as
+// the spacing allows while leaving room to insert between neighbours.
+func KeysBetween(a, b string, n int) ([]string, error) {
+	if err := validateKey(a); err != nil {
+		return nil, err
+	}
+	if err := validateKey(b); err != nil {
+		return nil, err
+	}
+	if a != "" && b != "" && a >= b {
+		return nil, fmt.Errorf("key %q must sort before %q", a, b)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// Work in integers of width digits, widening until there are at least
+	// base values between neighbouring keys
+	slots := big.NewInt(int64(n + 1))
+	minGap := new(big.Int).Mul(slots, big.NewInt(int64(base)))
+	width := max(len(a), len(b))
+	var lo, gap *big.Int
+	for {
+		lo = keyValue(a, width)
+		hi := new(big.Int).Exp(big.NewInt(int64(base)), big.NewInt(int64(width)), nil)
+		if b != "" {
+			hi = keyValue(b, width)
+		}
+		gap = new(big.Int).Sub(hi, lo)
+		if gap.Cmp(minGap) >= 0 {
+			break
+		}
+		width++
+	}
+
+	keys := make([]string, n)
+	for i := range keys {
+		v := new(big.Int).Mul(gap, big.NewInt(int64(i+1)))
+		v.Quo(v, slots)
+		v.Add(v, lo)
+		keys[i] = formatKey(v, width)
+	}
+	return keys, nil
+}
+
+// keyValue reads key as an integer of width digits, padding with zeros
+func keyValue(key string, width int) *big.Int {
+	v := new(big.Int)
+	for i := 0; i < width; i++ {
+		v.Mul(v, big.NewInt(int64(base)))
+		v.Add(v, big.NewInt(int64(strings.IndexByte(digits, digitAt(key, i)))))
+	}
+	return v
+}
+
+// formatKey writes v as width digits without the trailing zeros
+func formatKey(v *big.Int, width int) string {
+	text := v.Text(base)
+	text = strings.Repeat("0", width-len(text)) + text
+	return strings.TrimRight(text, "0")
+}
+
+// midpoint finds a key between a and b, treating a missing a as 0 and a
+// missing b as 1
+func midpoint(a, b string) string {
+	if b != "" {
+		// Skip the shared prefix, padding a with zeros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	// The first digits now differ
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+	digitB := base
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(digits[(digitA+digitB+1)/2])
+	}
+
+	// Consecutive first digits: stay under b's leading digit if b has more,
+	// otherwise extend a
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[digitA]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return '0'
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return fmt.Errorf("invalid position key %q", key)
+		}
+	}
+	if key[len(key)-1] == '0' {
+		return fmt.Errorf("invalid position key %q: trailing zero", key)
+	}
+	return nil
+}
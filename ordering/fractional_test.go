@@ -0,0 +1,115 @@
+package ordering
+
+import "testing"
+
+func TestKeyBetween(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"", "h"},
+		{"h", ""},
+		{"h", "i"},
+		{"h", "h1"},
+		{"a", "z"},
+		{"0h", "1"},
+		{"zz", ""},
+		{"", "01"},
+	}
+	for _, tt := range tests {
+		key, err := KeyBetween(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("KeyBetween(%q, %q) error: %v", tt.a, tt.b, err)
+			continue
+		}
+		if err := validateKey(key); err != nil || key == "" {
+			t.Errorf("KeyBetween(%q, %q) = %q, not a valid key", tt.a, tt.b, key)
+		}
+		if (tt.a != "" && key <= tt.a) || (tt.b != "" && key >= tt.b) {
+			t.Errorf("KeyBetween(%q, %q) = %q, not between them", tt.a, tt.b, key)
+		}
+	}
+
+	for _, bad := range [][2]string{{"h", "h"}, {"i", "h"}, {"h0", ""}, {"", "H"}} {
+		if key, err := KeyBetween(bad[0], bad[1]); err == nil {
+			t.Errorf("KeyBetween(%q, %q) = %q, want an error", bad[0], bad[1], key)
+		}
+	}
+}
+
+func TestKeysBetween(t *testing.T) {
+	tests := []struct {
+		a, b string
+		n    int
+	}{
+		{"", "", 1},
+		{"", "", 10},
+		{"", "", 1000},
+		{"h", "", 50},
+		{"", "1", 100},
+		{"h", "h1", 5},
+		{"a", "b", 36},
+	}
+	for _, tt := range tests {
+		keys, err := KeysBetween(tt.a, tt.b, tt.n)
+		if err != nil {
+			t.Errorf("KeysBetween(%q, %q, %d) error: %v", tt.a, tt.b, tt.n, err)
+			continue
+		}
+		if len(keys) != tt.n {
+			t.Errorf("KeysBetween(%q, %q, %d) returned %d keys", tt.a, tt.b, tt.n, len(keys))
+			continue
+		}
+		prev := tt.a
+		for i, key := range keys {
+			if err := validateKey(key); err != nil || key == "" {
+				t.Errorf("KeysBetween(%q, %q, %d)[%d] = %q, not a valid key", tt.a, tt.b, tt.n, i, key)
+			}
+			if (prev != "" && key <= prev) || (tt.b != "" && key >= tt.b) {
+				t.Errorf("KeysBetween(%q, %q, %d)[%d] = %q, out of order", tt.a, tt.b, tt.n, i, key)
+			}
+			// Every neighbouring pair must leave room for another key
+			if _, err := KeyBetween(prev, key); err != nil {
+				t.Errorf("no room before KeysBetween(%q, %q, %d)[%d] = %q: %v", tt.a, tt.b, tt.n, i, key, err)
+			}
+			prev = key
+		}
+	}
+
+	// A thousand tasks fit in short keys
+	keys, _ := KeysBetween("", "", 1000)
+	for _, key := range keys {
+		if len(key) > 3 {
+			t.Errorf("KeysBetween(\"\", \"\", 1000) produced %q, want at most 3 digits", key)
+			break
+		}
+	}
+}
+
+func TestRepeatedInsertsNeedRespacing(t *testing.T) {
+	// Inserting at the front over and over grows the first key
+	first := "h"
+	inserts := 0
+	for len(first) <= MaxKeyLen {
+		key, err := KeyBetween("", first)
+		if err != nil {
+			t.Fatalf("KeyBetween(\"\", %q) error: %v", first, err)
+		}
+		first = key
+		inserts++
+	}
+	if inserts < 20 {
+		t.Errorf("keys passed MaxKeyLen after only %d inserts", inserts)
+	}
+
+	// Respacing the list brings the keys back down
+	keys, err := KeysBetween("", "", inserts+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if len(key) > 3 {
+			t.Errorf("respaced key %q is still long", key)
+		}
+	}
+}
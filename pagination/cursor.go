@@ -0,0 +1,109 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor marks a position in a list ordered by (created_at, id), or by
+// (pinned, created_at, id) for lists that put pinned rows first. Clients
+// only ever see it encoded and pass it back unchanged.
+type Cursor struct {
+	Pinned    bool      `json:"p,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Before    bool      `json:"b,omitempty"` // page towards the start of the list
+}
+
+// Page holds the cursors for the pages either side of the one returned.
+// An empty cursor means there is nothing more in that direction.
+type Page struct {
+	NextCursor string
+	PrevCursor string
+}
+
+// Bounded reports whether the cursor points at a row. The zero cursor means
+// the start of the list, and Cursor{Before: true} its end.
+func (c Cursor) Bounded() bool {
+	return !c.CreatedAt.IsZero()
+}
+
+// Encode turns a cursor into an opaque URL-safe string
+func Encode(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode parses a cursor from Encode. An empty string is the zero cursor.
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+	if s == "" {
+		return c, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(raw, &c); err != nil || !c.Bounded() {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// Trim takes rows fetched with a limit of limit+1 in scan order, drops the
+// extra row, puts them back in list order and works out the cursors around
+// them. key returns a row's created_at and id.
+func Trim[T any](rows []T, c Cursor, limit int, key func(T) (time.Time, string)) ([]T, Page) {
+	return TrimPinned(rows, c, limit, func(row T) (bool, time.Time, string) {
+		at, id := key(row)
+		return false, at, id
+	})
+}
+
+// TrimPinned is Trim for lists ordered by (pinned, created_at, id)
+func TrimPinned[T any](rows []T, c Cursor, limit int, key func(T) (bool, time.Time, string)) ([]T, Page) {
+	var page Page
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if c.Before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	firstPinned, firstAt, firstID := key(rows[0])
+	lastPinned, lastAt, lastID := key(rows[len(rows)-1])
+	prev := Encode(Cursor{Pinned: firstPinned, CreatedAt: firstAt, ID: firstID, Before: true})
+	next := Encode(Cursor{Pinned: lastPinned, CreatedAt: lastAt, ID: lastID})
+
+	// Scanning backwards, the extra row means more before; a bounded
+	// cursor means the page we came from is after
+	if c.Before {
+		if hasMore {
+			page.PrevCursor = prev
+		}
+		if c.Bounded() {
+			page.NextCursor = next
+		}
+	} else {
+		if hasMore {
+			page.NextCursor = next
+		}
+		if c.Bounded() {
+			page.PrevCursor = prev
+		}
+	}
+	return rows, page
+}
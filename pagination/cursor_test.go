@@ -0,0 +1,57 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+type row struct {
+	pinned bool
+	at     time.Time
+	id     string
+}
+
+func TestTrimPinnedCursors(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	rows := []row{
+		{true, base, "00000000-0000-0000-0000-000000000001"},
+		{false, base.Add(time.Hour), "00000000-0000-0000-0000-000000000002"},
+		{false, base, "00000000-0000-0000-0000-000000000003"},
+	}
+	key := func(r row) (bool, time.Time, string) { return r.pinned, r.at, r.id }
+
+	page, p := TrimPinned(append([]row(nil), rows...), Cursor{}, 2, key)
+	if len(page) != 2 || p.PrevCursor != "" || p.NextCursor == "" {
+		t.Fatalf("first page = %v, %+v", page, p)
+	}
+	next, err := Decode(p.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Pinned || !next.CreatedAt.Equal(rows[1].at) || next.ID != rows[1].id || next.Before {
+		t.Errorf("next cursor = %+v, want the second row", next)
+	}
+
+	// Scanning back from the last row returns rows in scan order
+	scanned := []row{rows[1], rows[0]}
+	page, p = TrimPinned(scanned, Cursor{CreatedAt: base, ID: rows[2].id, Before: true}, 2, key)
+	if page[0] != rows[0] || page[1] != rows[1] {
+		t.Errorf("backward page = %v, want list order", page)
+	}
+	if p.PrevCursor != "" {
+		t.Errorf("backward page from the end has a prev cursor %q", p.PrevCursor)
+	}
+	after, err := Decode(p.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Pinned || after.ID != rows[1].id {
+		t.Errorf("cursor after backward page = %+v, want the second row", after)
+	}
+}
+
+func TestDecodeRejectsUnboundedCursor(t *testing.T) {
+	if _, err := Decode(Encode(Cursor{Pinned: true, ID: "00000000-0000-0000-0000-000000000001"})); err == nil {
+		t.Error("Decode accepted a cursor without a timestamp")
+	}
+}
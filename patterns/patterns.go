@@ -0,0 +1,259 @@
+// Package patterns mines a user's activity log for how they work: when they
+// get things done, which kinds of tasks they finish, and whether they use
+// the coach more for tasks or for conversation.
+package patterns
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Task types that completion rates are reported for
+const (
+	TaskTypeAISuggested = "ai_suggested"
+	TaskTypeSelfCreated = "self_created"
+	TaskTypeAll         = "all"
+)
+
+// Response styles, as stored in UserPatterns.PreferredResponseStyle
+const (
+	StyleTaskFocused       = "task_focused"
+	StyleDiscussionFocused = "discussion_focused"
+	StyleBalanced          = "balanced"
+)
+
+const (
+	minTasksForRate      = 3 // tasks of a type created before its rate means anything
+	minCompletionsForTOD = 5 // completions before a time preference is reported
+	longMessageChars     = 200
+)
+
+// Parts of the day completions are grouped into, by starting hour
+var dayParts = []struct {
+	name  string
+	start int
+}{
+	{"night", 0}, {"morning", 5}, {"afternoon", 12}, {"evening", 17}, {"night", 22},
+}
+
+// Activity types that count as working on tasks rather than talking
+var taskActions = map[string]bool{
+	config.ActivityTypeTaskCreated:   true,
+	config.ActivityTypeTaskUpdated:   true,
+	config.ActivityTypeTaskCompleted: true,
+	config.ActivityTypeTaskDeleted:   true,
+	config.ActivityTypeTasksBatch:    true,
+	config.ActivityTypeTasksImported: true,
+	config.ActivityTypeTemplateUsed:  true,
+	config.ActivityTypeFocusStarted:  true,
+}
+
+// Analyze works out the response style, time preferences and task
+// completion rates shown by activities. Times are read in loc. Struggles and
+// strategies need a language model and are left empty.
+func Analyze(activities []types.UserActivity, loc *time.Location) types.UserPatterns {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	created := map[string]int{}
+	completed := map[string]int{}
+	var completionTimes []time.Time
+	var messages, messageChars, actions int
+
+	for _, a := range activities {
+		meta := metadata(a)
+		// Templates the assistant instantiated aren't the user's doing
+		assistantTemplate := a.ActivityType == config.ActivityTypeTemplateUsed && taskType(meta) == TaskTypeAISuggested
+		if taskActions[a.ActivityType] && !assistantTemplate {
+			actions++
+		}
+
+		switch a.ActivityType {
+		case config.ActivityTypeMessage:
+			messages++
+			messageChars += len(a.Content)
+
+		case config.ActivityTypeTaskCreated:
+			created[TaskTypeSelfCreated]++
+		case config.ActivityTypeTasksCreated:
+			created[TaskTypeAISuggested] += count(meta, "task_count")
+		case config.ActivityTypeTemplateUsed:
+			created[taskType(meta)] += count(meta, "task_count")
+		case config.ActivityTypeTasksImported:
+			created[TaskTypeSelfCreated] += count(meta, "imported")
+		case config.ActivityTypeTasksBatch:
+			created[TaskTypeSelfCreated] += count(meta, "created")
+			n := count(meta, "completed")
+			for i := 0; i < n; i++ {
+				completionTimes = append(completionTimes, a.CreatedAt)
+			}
+			completed[""] += n
+
+		case config.ActivityTypeTaskCompleted:
+			completionTimes = append(completionTimes, a.CreatedAt)
+			if _, typed := meta["ai_suggested"]; typed {
+				completed[taskType(meta)]++
+			} else {
+				completed[""]++
+			}
+		case config.ActivityTypeTasksUpdated:
+			// The assistant marks tasks done when the user reports finishing them
+			n := completedUpdates(meta)
+			for i := 0; i < n; i++ {
+				completionTimes = append(completionTimes, a.CreatedAt)
+			}
+			completed[""] += n
+		}
+	}
+
+	return types.UserPatterns{
+		PreferredResponseStyle: responseStyle(messages, messageChars, actions),
+		TimePreferences:        timePreferences(completionTimes, loc),
+		TaskCompletionRates:    completionRates(created, completed),
+	}
+}
+
+// responseStyle compares how much the user works on tasks with how much
+// and how long they write
+func responseStyle(messages, messageChars, actions int) string {
+	switch {
+	case messages == 0 && actions == 0:
+		return ""
+	case messages == 0 || actions >= messages:
+		return StyleTaskFocused
+	case actions*4 < messages && messageChars/messages >= longMessageChars:
+		return StyleDiscussionFocused
+	}
+	return StyleBalanced
+}
+
+// timePreferences describes the part of the day and the weekday most
+// completions fall on, when one stands out
+func timePreferences(times []time.Time, loc *time.Location) string {
+	if len(times) < minCompletionsForTOD {
+		return ""
+	}
+
+	parts := map[string]int{}
+	days := map[time.Weekday]int{}
+	for _, t := range times {
+		t = t.In(loc)
+		parts[dayPart(t.Hour())]++
+		days[t.Weekday()]++
+	}
+
+	var notes []string
+	if part, n := top(parts); n*100/len(times) >= 40 {
+		notes = append(notes, fmt.Sprintf("finishes most tasks in the %s (%d%%)", part, n*100/len(times)))
+	}
+	byName := map[string]int{}
+	for d, n := range days {
+		byName[d.String()] = n
+	}
+	if day, n := top(byName); n*100/len(times) >= 30 {
+		notes = append(notes, fmt.Sprintf("most productive on %ss (%d%%)", day, n*100/len(times)))
+	}
+	return strings.Join(notes, "; ")
+}
+
+// completionRates divides completions by creations per task type. Rates
+// are capped at 1, since tasks created before the window may be completed
+// in it.
+func completionRates(created, completed map[string]int) map[string]float64 {
+	rates := map[string]float64{}
+	var totalCreated, totalCompleted int
+	for kind, n := range created {
+		totalCreated += n
+		if n >= minTasksForRate {
+			rates[kind] = rate(completed[kind], n)
+		}
+	}
+	for _, n := range completed {
+		totalCompleted += n
+	}
+	if totalCreated >= minTasksForRate {
+		rates[TaskTypeAll] = rate(totalCompleted, totalCreated)
+	}
+	if len(rates) == 0 {
+		return nil
+	}
+	return rates
+}
+
+func rate(done, total int) float64 {
+	r := float64(done) / float64(total)
+	if r > 1 {
+		r = 1
+	}
+	return float64(int(r*100+0.5)) / 100
+}
+
+func dayPart(hour int) string {
+	name := dayParts[0].name
+	for _, p := range dayParts {
+		if hour >= p.start {
+			name = p.name
+		}
+	}
+	return name
+}
+
+// top returns the key with the highest count, breaking ties by name so the
+// result is stable
+func top(counts map[string]int) (string, int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	best, bestN := "", 0
+	for _, k := range keys {
+		if counts[k] > bestN {
+			best, bestN = k, counts[k]
+		}
+	}
+	return best, bestN
+}
+
+func metadata(a types.UserActivity) map[string]interface{} {
+	meta := map[string]interface{}{}
+	if a.Metadata != "" {
+		_ = json.Unmarshal([]byte(a.Metadata), &meta)
+	}
+	return meta
+}
+
+// count reads a numeric metadata field
+func count(meta map[string]interface{}, key string) int {
+	if n, ok := meta[key].(float64); ok && n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// taskType tells tasks the assistant suggested from ones the user made
+func taskType(meta map[string]interface{}) string {
+	if ai, _ := meta["ai_suggested"].(bool); ai {
+		return TaskTypeAISuggested
+	}
+	return TaskTypeSelfCreated
+}
+
+// completedUpdates counts the updates in a tasks_updated activity that
+// mark a task completed
+func completedUpdates(meta map[string]interface{}) int {
+	updates, _ := meta["updates"].([]interface{})
+	n := 0
+	for _, u := range updates {
+		if update, ok := u.(map[string]interface{}); ok && update["status"] == "completed" {
+			n++
+		}
+	}
+	return n
+}
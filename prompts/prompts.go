@@ -0,0 +1,216 @@
+// Package prompts holds the text/template prompts sent to the models. The
+// templates in files/ are embedded in the binary; a directory of .tmpl
+// files with the same names can replace them at startup without a rebuild.
+//
+// Each template declares its version in a leading comment:
+//
+//	{{- /* version: 3 */ -}}
+//
+// Bump it with every change so replies can be traced to the prompt that
+// produced them. The version ID always ends in a hash of the template's
+// text, so an edited file that kept its version still gets a new ID.
+package prompts
+
+import (
+	"clementus360/ai-helper/types"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Prompt names, matching the files they're loaded from
+const (
+	Coach    = "coach"    // persona and JSON reply contract for chat replies; no data
+	Summary  = "summary"  // session summary, title and tags; SummaryData
+	Patterns = "patterns" // recurring struggles and strategies; PatternsData
+)
+
+// SummaryData fills the summary prompt
+type SummaryData struct {
+	Conversation string // one "User: " or "AI: " line per message
+	Context      types.SmartContext
+}
+
+// PatternsData fills the patterns prompt
+type PatternsData struct {
+	Sessions           []string // session summaries, newest first
+	PreviousStruggles  []string
+	PreviousStrategies []string
+	MaxItems           int
+}
+
+// sampleData is what each prompt is rendered with, and checked against
+// when loaded
+var sampleData = map[string]any{
+	Coach:    nil,
+	Summary:  SummaryData{},
+	Patterns: PatternsData{},
+}
+
+var funcs = template.FuncMap{"join": strings.Join}
+
+var versionComment = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*([\w.-]+)\s*\*/\s*-?\}\}`)
+
+//go:embed files/*.tmpl
+var embedded embed.FS
+
+// Prompt is one parsed template
+type Prompt struct {
+	Name    string
+	Version string // name@version+hash, stored with what the prompt produced
+	tmpl    *template.Template
+
+	// Prompts that take no data are rendered once, when loaded
+	static   string
+	isStatic bool
+}
+
+var (
+	mu     sync.RWMutex
+	loaded = mustLoadEmbedded()
+)
+
+// Get returns the named prompt
+func Get(name string) (*Prompt, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := loaded[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt %q", name)
+	}
+	return p, nil
+}
+
+// Render executes the named prompt with data and returns the text and the
+// prompt's version
+func Render(name string, data any) (string, string, error) {
+	p, err := Get(name)
+	if err != nil {
+		return "", "", err
+	}
+	text, err := p.Render(data)
+	return text, p.Version, err
+}
+
+// Static returns the text and version of a prompt that takes no data, such
+// as Coach. It was rendered when loaded, so it can't fail; an unknown name
+// or a prompt that needs data returns "".
+func Static(name string) (string, string) {
+	p, err := Get(name)
+	if err != nil || !p.isStatic {
+		return "", ""
+	}
+	return p.static, p.Version
+}
+
+// Version returns the version of the named prompt, or "" if there's none
+func Version(name string) string {
+	if p, err := Get(name); err == nil {
+		return p.Version
+	}
+	return ""
+}
+
+func (p *Prompt) Render(data any) (string, error) {
+	if p.isStatic {
+		return p.static, nil
+	}
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", p.Name, err)
+	}
+	return b.String(), nil
+}
+
+// LoadDir replaces embedded prompts with the .tmpl files in dir. Every
+// file must be a known prompt that parses and renders, or nothing is
+// replaced. Call it once at startup.
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+
+	overrides := map[string]*Prompt{}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+		if _, ok := sampleData[name]; !ok {
+			return fmt.Errorf("%s: unknown prompt %q", path, name)
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		p, err := parse(name, string(text))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		overrides[name] = p
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	merged := make(map[string]*Prompt, len(loaded))
+	for name, p := range loaded {
+		merged[name] = p
+	}
+	for name, p := range overrides {
+		merged[name] = p
+	}
+	loaded = merged
+	return nil
+}
+
+func mustLoadEmbedded() map[string]*Prompt {
+	prompts := map[string]*Prompt{}
+	for name := range sampleData {
+		text, err := embedded.ReadFile("files/" + name + ".tmpl")
+		if err != nil {
+			panic(err)
+		}
+		p, err := parse(name, string(text))
+		if err != nil {
+			panic(err)
+		}
+		prompts[name] = p
+	}
+	return prompts
+}
+
+// parse parses a template, works out its version and makes sure it renders
+// with the data its callers pass
+func parse(name, text string) (*Prompt, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	version := "sha-" + hex.EncodeToString(sum[:4])
+	if m := versionComment.FindStringSubmatch(text); m != nil {
+		version = m[1] + "+" + hex.EncodeToString(sum[:4])
+	}
+	p := &Prompt{Name: name, Version: name + "@" + version, tmpl: tmpl}
+
+	data := sampleData[name]
+	if data == nil {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, nil); err != nil {
+			return nil, err
+		}
+		p.static, p.isStatic = b.String(), true
+		return p, nil
+	}
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
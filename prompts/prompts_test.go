@@ -0,0 +1,52 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersionIncludesContentHash(t *testing.T) {
+	a, err := parse(Coach, "{{- /* version: 1 */ -}}\nBe kind.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parse(Coach, "{{- /* version: 1 */ -}}\nBe brief.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(a.Version, "coach@1+") {
+		t.Errorf("Version = %q, want coach@1+<hash>", a.Version)
+	}
+	if a.Version == b.Version {
+		t.Errorf("different texts with the same header share version %q", a.Version)
+	}
+
+	c, err := parse(Coach, "Be kind.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(c.Version, "coach@sha-") {
+		t.Errorf("Version without a header = %q, want coach@sha-<hash>", c.Version)
+	}
+}
+
+func TestStaticPromptIsRenderedOnLoad(t *testing.T) {
+	text, version := Static(Coach)
+	if text == "" || version == "" {
+		t.Fatalf("Static(Coach) = %q, %q", text, version)
+	}
+	if version != Version(Coach) {
+		t.Errorf("Static version %q differs from Version %q", version, Version(Coach))
+	}
+
+	// Prompts that take data aren't static
+	if text, _ := Static(Summary); text != "" {
+		t.Errorf("Static(Summary) = %q, want empty", text)
+	}
+}
+
+func TestParseRejectsTemplatesThatDontRender(t *testing.T) {
+	if _, err := parse(Patterns, "{{ .Missing }}"); err == nil {
+		t.Error("parse accepted a template referring to a field PatternsData lacks")
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterAccountRoutes registers account data export, import and deletion
+// routes
+func RegisterAccountRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /account/export", handlers.StartAccountExportHandler)
+	mux.HandleFunc("POST /account/import", handlers.ImportAccountHandler)
+	mux.HandleFunc("GET /account/jobs/{id}", handlers.GetDataJobHandler)
+	mux.HandleFunc("GET /account/jobs/{id}/download", handlers.DownloadAccountExportHandler)
+
+	// Account deletion
+	mux.HandleFunc("DELETE /account", handlers.DeleteAccountHandler)
+	mux.HandleFunc("GET /account/deletion", handlers.GetAccountDeletionHandler)
+	mux.HandleFunc("POST /account/deletion/cancel", handlers.CancelAccountDeletionHandler)
+	mux.HandleFunc("GET /account/deletions/{id}/receipt", handlers.GetDeletionReceiptHandler)
+}
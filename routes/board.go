@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterBoardRoutes registers kanban column and task ordering routes
+func RegisterBoardRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /columns", handlers.GetColumnsHandler)
+	mux.HandleFunc("POST /columns", handlers.CreateColumnHandler)
+	mux.HandleFunc("PATCH /columns/{id}", handlers.UpdateColumnHandler)
+	mux.HandleFunc("DELETE /columns/{id}", handlers.DeleteColumnHandler)
+	mux.HandleFunc("PATCH /tasks/reorder", handlers.ReorderTaskHandler)
+}
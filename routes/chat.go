@@ -9,4 +9,7 @@ import (
 func RegisterChatRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /chat", handlers.ChatHandler)
 	mux.HandleFunc("GET /chat", handlers.GetMessagesHandler)
+	mux.HandleFunc("PATCH /chat/messages/{id}", handlers.EditMessageHandler)
+	mux.HandleFunc("POST /chat/messages/{id}/regenerate", handlers.RegenerateMessageHandler)
+	mux.HandleFunc("GET /chat/messages/{id}/alternates", handlers.GetMessageAlternatesHandler)
 }
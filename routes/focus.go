@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterFocusRoutes registers time tracking and focus session routes
+func RegisterFocusRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /tasks/{id}/timer/start", handlers.StartTimerHandler)
+	mux.HandleFunc("POST /tasks/{id}/timer/stop", handlers.StopTimerHandler)
+	mux.HandleFunc("GET /focus/sessions", handlers.GetFocusSessionsHandler)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterMemoryRoutes registers long-term memory routes
+func RegisterMemoryRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /memories", handlers.GetMemoriesHandler)
+	mux.HandleFunc("DELETE /memories/{id}", handlers.DeleteMemoryHandler)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterProfileRoutes registers all user profile routes
+func RegisterProfileRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /profile", handlers.GetProfileHandler)
+	mux.HandleFunc("PATCH /profile", handlers.UpdateProfileHandler)
+}
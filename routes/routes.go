@@ -7,6 +7,13 @@ func RegisterAllRoutes(mux *http.ServeMux) {
 	RegisterChatRoutes(mux)
 	RegisterTaskRoutes(mux)
 	RegisterSessionRoutes(mux)
+	RegisterProfileRoutes(mux)
+	RegisterTemplateRoutes(mux)
+	RegisterFocusRoutes(mux)
+	RegisterBoardRoutes(mux)
+	RegisterSearchRoutes(mux)
+	RegisterMemoryRoutes(mux)
+	RegisterAccountRoutes(mux)
 }
 
 // Alternative approach - if you prefer a single registration function
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterSearchRoutes registers full-text search routes
+func RegisterSearchRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /search", handlers.SearchHandler)
+}
@@ -10,6 +10,10 @@ func RegisterSessionRoutes(mux *http.ServeMux) {
 	// Basic session operations
 	mux.HandleFunc("GET /sessions", handlers.GetSessionsHandler)
 	mux.HandleFunc("PATCH /sessions/update", handlers.UpdateSessionHandler)
+	mux.HandleFunc("POST /sessions/{id}/fork", handlers.ForkSessionHandler)
+	mux.HandleFunc("POST /sessions/{id}/close", handlers.CloseSessionHandler)
+	mux.HandleFunc("GET /sessions/{id}/export", handlers.ExportSessionHandler)
+	mux.HandleFunc("POST /sessions/import", handlers.ImportSessionHandler)
 
 	// Session deletion operations
 	mux.HandleFunc("DELETE /sessions", handlers.DeleteSessionHandler)
@@ -12,4 +12,12 @@ func RegisterTaskRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("DELETE /tasks/delete", handlers.DeleteTaskHandler)
 	mux.HandleFunc("GET /tasks", handlers.GetTasksHandler)
 	mux.HandleFunc("GET /task", handlers.GetSingleTaskHandler)
+	mux.HandleFunc("POST /tasks/batch", handlers.BatchTasksHandler)
+	mux.HandleFunc("POST /tasks/import", handlers.ImportTasksHandler)
+	mux.HandleFunc("GET /tasks/{id}/history", handlers.GetTaskHistoryHandler)
+
+	// Calendar subscription feed, authenticated by a revocable feed token
+	mux.HandleFunc("GET /tasks/calendar.ics", handlers.CalendarFeedHandler)
+	mux.HandleFunc("POST /tasks/calendar/token", handlers.CreateCalendarTokenHandler)
+	mux.HandleFunc("DELETE /tasks/calendar/token", handlers.RevokeCalendarTokenHandler)
 }
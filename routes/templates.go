@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"clementus360/ai-helper/handlers"
+	"net/http"
+)
+
+// RegisterTemplateRoutes registers all task template routes
+func RegisterTemplateRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /templates", handlers.GetTemplatesHandler)
+	mux.HandleFunc("POST /templates", handlers.CreateTemplateHandler)
+	mux.HandleFunc("DELETE /templates", handlers.DeleteTemplateHandler)
+	mux.HandleFunc("POST /templates/instantiate", handlers.InstantiateTemplateHandler)
+}
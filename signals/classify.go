@@ -0,0 +1,194 @@
+// Package signals reads mood and topics from user messages and rolls them,
+// together with message cadence and task completion, into session metrics.
+package signals
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Moods a message can be classified as
+const (
+	MoodNeutral     = "neutral"
+	MoodPositive    = "positive"
+	MoodMotivated   = "motivated"
+	MoodStressed    = "stressed"
+	MoodFrustrated  = "frustrated"
+	MoodTired       = "tired"
+	MoodOverwhelmed = "overwhelmed"
+)
+
+// Classification is what a classifier read from one message
+type Classification struct {
+	Mood      string
+	Intensity float64 // how strongly the mood came through, 0 for neutral
+	Topics    []string
+}
+
+// Classifier reads the mood and topics of a message
+type Classifier interface {
+	Classify(text string) Classification
+}
+
+// Default classifies new messages. Replace it at startup to use a hosted
+// model.
+var Default Classifier = LexiconClassifier{}
+
+// LexiconClassifier matches words against small mood and topic word lists.
+// It needs no network, handles simple negation ("not excited") and
+// intensifiers ("so tired"), and is good enough to spot a trend across a
+// session.
+type LexiconClassifier struct{}
+
+// maxMessageTopics caps how many topics one message contributes
+const maxMessageTopics = 2
+
+var moodLexicon = map[string][]string{
+	MoodPositive:    {"good", "great", "happy", "glad", "nice", "awesome", "proud", "relieved", "better", "love", "enjoyed", "fun", "excellent", "thanks", "finally"},
+	MoodMotivated:   {"motivated", "excited", "ready", "determined", "focused", "pumped", "inspired", "productive", "eager", "let's", "lets", "energized"},
+	MoodStressed:    {"stressed", "stress", "anxious", "anxiety", "worried", "worry", "nervous", "panic", "panicking", "pressure", "scared", "afraid"},
+	MoodFrustrated:  {"frustrated", "frustrating", "annoyed", "annoying", "angry", "stuck", "hate", "ugh", "useless", "pointless", "irritated"},
+	MoodTired:       {"tired", "exhausted", "drained", "sleepy", "burnt", "burned", "burnout", "fatigued", "worn", "lazy", "unmotivated", "procrastinating", "procrastinate"},
+	MoodOverwhelmed: {"overwhelmed", "overwhelming", "swamped", "buried", "drowning", "chaos", "chaotic", "behind", "overloaded", "scattered"},
+}
+
+// Two-word phrases whose mood neither word carries alone ("fed up") or
+// that read differently from their first word ("can't wait"). They are
+// matched before single words.
+var moodPhrases = map[string]string{
+	"can't wait":        MoodMotivated,
+	"cannot wait":       MoodMotivated,
+	"can't focus":       MoodFrustrated,
+	"cannot focus":      MoodFrustrated,
+	"can't concentrate": MoodFrustrated,
+	"can't stand":       MoodFrustrated,
+	"fed up":            MoodFrustrated,
+	"can't cope":        MoodOverwhelmed,
+	"cannot cope":       MoodOverwhelmed,
+	"tight deadline":    MoodStressed,
+	"missed deadline":   MoodStressed,
+}
+
+// Words that flip the mood of the next two words
+var negations = map[string]bool{"not": true, "no": true, "never": true, "don't": true, "didn't": true, "isn't": true, "wasn't": true, "hardly": true}
+
+// Words that strengthen the next mood word
+var intensifiers = map[string]bool{"very": true, "so": true, "really": true, "extremely": true, "super": true, "totally": true, "completely": true, "incredibly": true}
+
+var topicLexicon = map[string][]string{
+	"work":          {"work", "job", "boss", "meeting", "meetings", "client", "clients", "office", "project", "colleague", "coworker", "manager", "email", "emails", "report", "presentation"},
+	"career":        {"career", "interview", "resume", "cv", "application", "apply", "promotion", "hiring", "portfolio", "linkedin", "salary"},
+	"study":         {"study", "studying", "exam", "exams", "homework", "thesis", "class", "course", "lecture", "assignment", "essay", "university", "school", "revision"},
+	"health":        {"health", "sleep", "doctor", "sick", "ill", "therapy", "medication", "diet", "eating", "headache", "anxiety", "mental"},
+	"fitness":       {"gym", "workout", "exercise", "run", "running", "training", "yoga", "walk", "swim", "lift", "cardio"},
+	"finances":      {"money", "budget", "rent", "bills", "bill", "tax", "taxes", "invoice", "savings", "debt", "bank", "expenses", "pay"},
+	"relationships": {"friend", "friends", "partner", "family", "mom", "dad", "mum", "wife", "husband", "boyfriend", "girlfriend", "kids", "date", "relationship"},
+	"creative":      {"write", "writing", "novel", "blog", "draw", "drawing", "paint", "painting", "music", "song", "design", "art", "creative", "video", "podcast"},
+	"home":          {"clean", "cleaning", "laundry", "chores", "groceries", "cook", "cooking", "dishes", "apartment", "house", "move", "moving", "declutter"},
+	"planning":      {"plan", "planning", "schedule", "calendar", "priorities", "prioritize", "organize", "routine", "goals", "goal", "week", "todo", "deadline", "deadlines"},
+}
+
+// Reverse indexes built from the lexicons
+var moodWords, topicWords = index(moodLexicon), index(topicLexicon)
+
+func index(lexicon map[string][]string) map[string]string {
+	idx := map[string]string{}
+	for label, words := range lexicon {
+		for _, w := range words {
+			idx[w] = label
+		}
+	}
+	return idx
+}
+
+func (LexiconClassifier) Classify(text string) Classification {
+	words := tokenize(text)
+
+	moods := map[string]float64{}
+	topics := map[string]int{}
+	negateFor, boost := 0, 1.0
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if label, ok := topicWords[w]; ok {
+			topics[label]++
+		}
+
+		switch {
+		case negations[w]:
+			negateFor = 2
+			continue
+		case intensifiers[w]:
+			boost = 1.5
+			continue
+		}
+
+		label, ok := "", false
+		if i+1 < len(words) {
+			if label, ok = moodPhrases[w+" "+words[i+1]]; ok {
+				i++
+				if topic, isTopic := topicWords[words[i]]; isTopic {
+					topics[topic]++
+				}
+			}
+		}
+		if !ok {
+			label, ok = moodWords[w]
+		}
+		if ok {
+			if negateFor > 0 {
+				// "not happy" leans negative; "not stressed" is just calm
+				if label == MoodPositive || label == MoodMotivated {
+					moods[MoodFrustrated] += 0.5 * boost
+				}
+			} else {
+				moods[label] += boost
+			}
+		}
+		if negateFor > 0 {
+			negateFor--
+		}
+		boost = 1.0
+	}
+	if strings.Contains(text, "!") {
+		for label := range moods {
+			moods[label] *= 1.25
+		}
+	}
+
+	c := Classification{Mood: MoodNeutral, Topics: topTopics(topics, maxMessageTopics)}
+	for _, label := range sortedKeys(moods) {
+		if moods[label] > c.Intensity {
+			c.Mood, c.Intensity = label, moods[label]
+		}
+	}
+	return c
+}
+
+// tokenize lowercases text and splits it into words, keeping apostrophes
+// so contractions such as "can't" survive
+func tokenize(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "’", "'")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// topTopics returns up to n topics by count, ties broken by name
+func topTopics(counts map[string]int, n int) []string {
+	topics := sortedKeys(counts)
+	sort.SliceStable(topics, func(i, j int) bool { return counts[topics[i]] > counts[topics[j]] })
+	if len(topics) > n {
+		topics = topics[:n]
+	}
+	return topics
+}
+
+func sortedKeys[V int | float64](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
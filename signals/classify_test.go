@@ -0,0 +1,74 @@
+package signals
+
+import (
+	"clementus360/ai-helper/types"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLexiconClassifierMood(t *testing.T) {
+	tests := []struct {
+		text string
+		mood string
+	}{
+		{"I can't wait to start the new project", MoodMotivated},
+		{"Cannot wait for the weekend", MoodMotivated},
+		{"I can't make it tomorrow", MoodNeutral},
+		{"I cannot find my keys", MoodNeutral},
+		{"I can't focus on anything today", MoodFrustrated},
+		{"honestly I'm fed up with this report", MoodFrustrated},
+		{"I fed the cat before work", MoodNeutral},
+		{"the deadline is on friday", MoodNeutral},
+		{"such a tight deadline this week", MoodStressed},
+		{"I can't cope with all of it", MoodOverwhelmed},
+		{"I'm not happy with how it went", MoodFrustrated},
+		{"not stressed about it at all", MoodNeutral},
+		{"I'm not fed up, just busy", MoodNeutral},
+		{"so tired!", MoodTired},
+		{"Feeling great, thanks", MoodPositive},
+	}
+	for _, tt := range tests {
+		if got := (LexiconClassifier{}).Classify(tt.text); got.Mood != tt.mood {
+			t.Errorf("Classify(%q).Mood = %q, want %q", tt.text, got.Mood, tt.mood)
+		}
+	}
+}
+
+func TestLexiconClassifierTopics(t *testing.T) {
+	tests := []struct {
+		text   string
+		topics []string
+	}{
+		{"the deadline is on friday", []string{"planning"}},
+		{"such a tight deadline", []string{"planning"}},
+		{"my boss moved the meeting before my exam", []string{"work", "study"}},
+		{"nothing much", nil},
+	}
+	for _, tt := range tests {
+		got := (LexiconClassifier{}).Classify(tt.text).Topics
+		if !slices.Equal(got, tt.topics) && !(len(got) == 0 && len(tt.topics) == 0) {
+			t.Errorf("Classify(%q).Topics = %v, want %v", tt.text, got, tt.topics)
+		}
+	}
+}
+
+func TestIntensifierAndExclamation(t *testing.T) {
+	plain := (LexiconClassifier{}).Classify("tired")
+	strong := (LexiconClassifier{}).Classify("so tired!")
+	if strong.Intensity <= plain.Intensity {
+		t.Errorf("intensity of %q = %v, want more than %v", "so tired!", strong.Intensity, plain.Intensity)
+	}
+}
+
+func TestObserveKeepsLatestMessageTime(t *testing.T) {
+	var m types.SessionMetrics
+	later := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	earlier := later.Add(-time.Minute)
+
+	Observe(&m, Classification{Mood: MoodNeutral}, later)
+	Observe(&m, Classification{Mood: MoodNeutral}, earlier)
+	if m.LastMessageAt == nil || !m.LastMessageAt.Equal(later) {
+		t.Errorf("LastMessageAt = %v, want %v", m.LastMessageAt, later)
+	}
+}
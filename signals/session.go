@@ -0,0 +1,106 @@
+package signals
+
+import (
+	"clementus360/ai-helper/types"
+	"time"
+)
+
+// Engagement levels, as stored in SessionMetrics.UserEngagementLevel
+const (
+	EngagementLow    = "low"
+	EngagementMedium = "medium"
+	EngagementHigh   = "high"
+)
+
+const (
+	moodDecay        = 0.7       // weight older messages keep each time a new one arrives
+	neutralWeight    = 0.5       // neutral messages count for less than a clear mood
+	gapSmoothing     = 0.3       // weight of the newest gap in the moving average
+	maxGap           = time.Hour // longer pauses count as an hour so a break doesn't swamp the average
+	maxSessionTopics = 3
+)
+
+// Observe folds a user message sent at the given time into the session's
+// rolling mood, topics, cadence and engagement
+func Observe(m *types.SessionMetrics, c Classification, at time.Time) {
+	if m.MoodScores == nil {
+		m.MoodScores = map[string]float64{}
+	}
+	for mood, score := range m.MoodScores {
+		if score *= moodDecay; score < 0.01 {
+			delete(m.MoodScores, mood)
+		} else {
+			m.MoodScores[mood] = score
+		}
+	}
+	weight := c.Intensity
+	if c.Mood == MoodNeutral || weight == 0 {
+		weight = neutralWeight
+	}
+	m.MoodScores[c.Mood] += weight
+
+	m.DominantMood = ""
+	best := 0.0
+	for _, mood := range sortedKeys(m.MoodScores) {
+		if m.MoodScores[mood] > best {
+			m.DominantMood, best = mood, m.MoodScores[mood]
+		}
+	}
+
+	if len(c.Topics) > 0 {
+		if m.TopicCounts == nil {
+			m.TopicCounts = map[string]int{}
+		}
+		for _, topic := range c.Topics {
+			m.TopicCounts[topic]++
+		}
+		m.PrimaryTopics = topTopics(m.TopicCounts, maxSessionTopics)
+	}
+
+	if m.LastMessageAt != nil && at.After(*m.LastMessageAt) {
+		gap := min(at.Sub(*m.LastMessageAt), maxGap).Seconds()
+		if m.AvgMessageGap == 0 {
+			m.AvgMessageGap = gap
+		} else {
+			m.AvgMessageGap = (1-gapSmoothing)*m.AvgMessageGap + gapSmoothing*gap
+		}
+	}
+	if m.LastMessageAt == nil || at.After(*m.LastMessageAt) {
+		m.LastMessageAt = &at
+	}
+
+	m.UserEngagementLevel = Engagement(*m)
+}
+
+// Engagement rates a session from how quickly the user replies and how
+// many of its tasks they complete. Either signal is neutral until there is
+// something to measure.
+func Engagement(m types.SessionMetrics) string {
+	score := 0
+
+	switch {
+	case m.AvgMessageGap == 0:
+		score++
+	case m.AvgMessageGap <= 2*60:
+		score += 2
+	case m.AvgMessageGap <= 10*60:
+		score++
+	}
+
+	switch {
+	case m.TasksCreated == 0:
+		score++
+	case m.TasksCompleted*2 >= m.TasksCreated:
+		score += 2
+	case m.TasksCompleted > 0:
+		score++
+	}
+
+	switch {
+	case score >= 3:
+		return EngagementHigh
+	case score <= 1:
+		return EngagementLow
+	}
+	return EngagementMedium
+}
@@ -0,0 +1,328 @@
+package supabase
+
+import (
+	"bytes"
+	"clementus360/ai-helper/archive"
+	"clementus360/ai-helper/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	storage_go "github.com/supabase-community/storage-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+const (
+	// ExportBucket is the storage bucket holding export archives, one per
+	// job at <user_id>/<job_id>.zip
+	ExportBucket = "account-exports"
+
+	// ExportRetention is how long a finished export can be downloaded
+	ExportRetention = 7 * 24 * time.Hour
+
+	exportPageSize  = 1000
+	importBatchSize = 500
+
+	// rollbackBatchSize keeps ID lists within URL length limits
+	rollbackBatchSize = 100
+)
+
+// exportOrder gives, per archived table, a column to read rows in and a
+// unique column to break ties, so paging neither repeats nor skips rows
+var exportOrder = map[string][2]string{
+	"sessions":          {"created_at", "id"},
+	"messages":          {"created_at", "id"},
+	"tasks":             {"created_at", "id"},
+	"session_summaries": {"last_updated", "session_id"},
+	"session_metrics":   {"created_at", "session_id"},
+	"user_patterns":     {"created_at", "user_id"},
+	"user_activities":   {"created_at", "id"},
+	"memories":          {"created_at", "id"},
+}
+
+// StartAccountExport queues an export of all the user's data and builds the
+// archive in the background
+func StartAccountExport(client *supabase.Client, userID string) (types.DataJob, error) {
+	job, err := createDataJob(client, userID, types.DataJobExport)
+	if err != nil {
+		return types.DataJob{}, err
+	}
+	go runAccountExport(client, job)
+	return job, nil
+}
+
+func runAccountExport(client *supabase.Client, job types.DataJob) {
+	setDataJobStatus(client, job, map[string]interface{}{"status": types.DataJobRunning})
+
+	tables := map[string][]archive.Row{}
+	for _, table := range archive.Tables {
+		rows, err := fetchAllRows(client, table, job.UserID)
+		if err != nil {
+			failDataJob(client, job, err)
+			return
+		}
+		tables[table] = rows
+	}
+
+	var buf bytes.Buffer
+	if _, err := archive.Write(&buf, job.UserID, time.Now(), tables); err != nil {
+		failDataJob(client, job, err)
+		return
+	}
+	size := int64(buf.Len())
+	sum := sha256.Sum256(buf.Bytes())
+
+	contentType := "application/zip"
+	if _, err := client.Storage.UploadFile(ExportBucket, exportPath(job), &buf, storage_go.FileOptions{ContentType: &contentType}); err != nil {
+		failDataJob(client, job, fmt.Errorf("failed to store archive: %w", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	setDataJobStatus(client, job, map[string]interface{}{
+		"status":       types.DataJobCompleted,
+		"size_bytes":   size,
+		"sha256":       hex.EncodeToString(sum[:]),
+		"counts":       archive.Counts(tables),
+		"completed_at": now.Format(time.RFC3339),
+		"expires_at":   now.Add(ExportRetention).Format(time.RFC3339),
+	})
+}
+
+// DownloadAccountExport returns the archive of a finished export
+func DownloadAccountExport(client *supabase.Client, userID, jobID string) ([]byte, error) {
+	job, err := GetDataJob(client, userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Kind != types.DataJobExport {
+		return nil, fmt.Errorf("job not found")
+	}
+	if job.Status != types.DataJobCompleted {
+		return nil, fmt.Errorf("export is %s", job.Status)
+	}
+
+	data, err := client.Storage.DownloadFile(ExportBucket, exportPath(job))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive: %w", err)
+	}
+	return data, nil
+}
+
+// ValidateAccountImport checks an archive and works out what importing it
+// into the user's account would write, without writing anything
+func ValidateAccountImport(data []byte, userID string) (types.ImportReport, map[string][]archive.Row) {
+	report := types.ImportReport{Counts: map[string]int{}}
+
+	_, tables, err := archive.Read(data)
+	if err != nil {
+		report.Problems = []string{err.Error()}
+		return report, nil
+	}
+
+	remapped, skipped, problems := archive.Remap(tables, userID)
+	report.Valid = true
+	report.Counts = archive.Counts(remapped)
+	report.Problems = problems
+	if len(skipped) > 0 {
+		report.Skipped = skipped
+	}
+	return report, remapped
+}
+
+// StartAccountImport writes validated archive rows into the user's account
+// in the background
+func StartAccountImport(client *supabase.Client, userID string, tables map[string][]archive.Row) (types.DataJob, error) {
+	job, err := createDataJob(client, userID, types.DataJobImport)
+	if err != nil {
+		return types.DataJob{}, err
+	}
+	go runAccountImport(client, job, tables)
+	return job, nil
+}
+
+func runAccountImport(client *supabase.Client, job types.DataJob, tables map[string][]archive.Row) {
+	setDataJobStatus(client, job, map[string]interface{}{"status": types.DataJobRunning})
+
+	// Patterns are one row per user; keep the account's own if it has them
+	if existing, err := GetUserPatterns(client, job.UserID); err == nil && !existing.CreatedAt.IsZero() {
+		delete(tables, "user_patterns")
+	}
+
+	// A table is listed before its first batch goes in, so a failure part
+	// way through it is rolled back too
+	var written []string
+	for _, table := range archive.Tables {
+		rows := tables[table]
+		if len(rows) > 0 {
+			written = append(written, table)
+		}
+		for start := 0; start < len(rows); start += importBatchSize {
+			end := min(start+importBatchSize, len(rows))
+			if _, _, err := client.From(table).Insert(rows[start:end], false, "", "", "").Execute(); err != nil {
+				rollbackImport(client, job.UserID, tables, written)
+				failDataJob(client, job, fmt.Errorf("failed to import %s: %w", table, err))
+				return
+			}
+		}
+	}
+
+	setDataJobStatus(client, job, map[string]interface{}{
+		"status":       types.DataJobCompleted,
+		"counts":       archive.Counts(tables),
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// rollbackImport removes what a failed import wrote, newest tables first
+func rollbackImport(client *supabase.Client, userID string, tables map[string][]archive.Row, written []string) {
+	for i := len(written) - 1; i >= 0; i-- {
+		table := written[i]
+
+		// Rows without IDs of their own are found through the imported
+		// sessions, or the user for patterns
+		column, source, key := "id", table, "id"
+		switch table {
+		case "session_summaries", "session_metrics":
+			column, source = "session_id", "sessions"
+		case "user_patterns":
+			column, key = "user_id", "user_id"
+		}
+
+		var ids []string
+		for _, row := range tables[source] {
+			if id, ok := row[key].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		for start := 0; start < len(ids); start += rollbackBatchSize {
+			end := min(start+rollbackBatchSize, len(ids))
+			_, _, err := client.From(table).
+				Delete("", "").
+				Eq("user_id", userID).
+				In(column, ids[start:end]).
+				Execute()
+			if err != nil {
+				log.Printf("Failed to roll back imported %s: %v", table, err)
+				break
+			}
+		}
+	}
+}
+
+// GetDataJob returns one of the user's export or import jobs
+func GetDataJob(client *supabase.Client, userID, jobID string) (types.DataJob, error) {
+	resp, _, err := client.From("data_jobs").
+		Select("*", "", false).
+		Eq("id", jobID).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return types.DataJob{}, fmt.Errorf("failed to fetch job: %w", err)
+	}
+	var jobs []types.DataJob
+	if err := json.Unmarshal(resp, &jobs); err != nil {
+		return types.DataJob{}, fmt.Errorf("failed to decode job: %w", err)
+	}
+	if len(jobs) == 0 {
+		return types.DataJob{}, fmt.Errorf("job not found")
+	}
+	return jobs[0], nil
+}
+
+// CleanupExpiredExports deletes export archives past their retention
+func CleanupExpiredExports(client *supabase.Client, now time.Time) error {
+	resp, _, err := client.From("data_jobs").
+		Select("*", "", false).
+		Eq("kind", types.DataJobExport).
+		Eq("status", types.DataJobCompleted).
+		Lt("expires_at", now.UTC().Format(time.RFC3339)).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to fetch expired exports: %w", err)
+	}
+	var jobs []types.DataJob
+	if err := json.Unmarshal(resp, &jobs); err != nil {
+		return fmt.Errorf("failed to decode expired exports: %w", err)
+	}
+
+	for _, job := range jobs {
+		if _, err := client.Storage.RemoveFile(ExportBucket, []string{exportPath(job)}); err != nil {
+			log.Printf("Failed to remove export %s: %v", job.ID, err)
+			continue
+		}
+		setDataJobStatus(client, job, map[string]interface{}{"status": types.DataJobExpired})
+	}
+	return nil
+}
+
+// fetchAllRows reads every row the user owns in a table, a page at a time
+func fetchAllRows(client *supabase.Client, table, userID string) ([]archive.Row, error) {
+	order := exportOrder[table]
+	var rows []archive.Row
+	for offset := 0; ; offset += exportPageSize {
+		resp, _, err := client.From(table).
+			Select("*", "", false).
+			Eq("user_id", userID).
+			Order(order[0], &postgrest.OrderOpts{Ascending: true}).
+			Order(order[1], &postgrest.OrderOpts{Ascending: true}).
+			Range(offset, offset+exportPageSize-1, "").
+			Execute()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table, err)
+		}
+
+		var page []archive.Row
+		dec := json.NewDecoder(bytes.NewReader(resp))
+		dec.UseNumber()
+		if err := dec.Decode(&page); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", table, err)
+		}
+		rows = append(rows, page...)
+		if len(page) < exportPageSize {
+			return rows, nil
+		}
+	}
+}
+
+func createDataJob(client *supabase.Client, userID, kind string) (types.DataJob, error) {
+	resp, _, err := client.From("data_jobs").
+		Insert(types.DataJob{UserID: userID, Kind: kind, Status: types.DataJobPending}, false, "", "", "").
+		Execute()
+	if err != nil {
+		return types.DataJob{}, fmt.Errorf("failed to create %s job: %w", kind, err)
+	}
+	var created []types.DataJob
+	if err := json.Unmarshal(resp, &created); err != nil || len(created) == 0 {
+		return types.DataJob{}, fmt.Errorf("failed to decode %s job: %v", kind, err)
+	}
+	return created[0], nil
+}
+
+func setDataJobStatus(client *supabase.Client, job types.DataJob, updates map[string]interface{}) {
+	_, _, err := client.From("data_jobs").
+		Update(updates, "", "").
+		Eq("id", job.ID).
+		Eq("user_id", job.UserID).
+		Execute()
+	if err != nil {
+		log.Printf("Failed to update %s job %s: %v", job.Kind, job.ID, err)
+	}
+}
+
+func failDataJob(client *supabase.Client, job types.DataJob, cause error) {
+	log.Printf("%s job %s failed: %v", job.Kind, job.ID, cause)
+	setDataJobStatus(client, job, map[string]interface{}{
+		"status":       types.DataJobFailed,
+		"error":        cause.Error(),
+		"completed_at": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func exportPath(job types.DataJob) string {
+	return job.UserID + "/" + job.ID + ".zip"
+}
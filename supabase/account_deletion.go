@@ -0,0 +1,321 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/types"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	gotruetypes "github.com/supabase-community/gotrue-go/types"
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+const receiptAlgorithm = "HMAC-SHA256"
+
+// purgeTables lists every table holding user data, children before the
+// rows they refer to
+var purgeTables = []string{
+	"task_revisions",
+	"focus_sessions",
+	"user_activities",
+	"memories",
+	"tasks",
+	"messages",
+	"session_summaries",
+	"session_metrics",
+	"sessions",
+	"board_columns",
+	"task_templates",
+	"user_patterns",
+	"calendar_feed_tokens",
+	"user_profiles",
+	"data_jobs",
+}
+
+// maxPurgesPerSweep bounds the work one sweep does
+const maxPurgesPerSweep = 10
+
+// RequestAccountDeletion schedules the user's account for erasure after the
+// grace period. created is false if a deletion was already scheduled, in
+// which case that one is returned.
+func RequestAccountDeletion(client *supabase.Client, userID string) (deletion types.AccountDeletion, created bool, err error) {
+	if receiptSecret() == "" {
+		return types.AccountDeletion{}, false, fmt.Errorf("account deletion is not configured")
+	}
+
+	existing, err := GetAccountDeletion(client, userID)
+	if err == nil && existing.Status == types.AccountDeletionScheduled {
+		return existing, false, nil
+	}
+
+	now := time.Now().UTC()
+	resp, _, err := client.From("account_deletions").
+		Insert(types.AccountDeletion{
+			UserID:      userID,
+			Status:      types.AccountDeletionScheduled,
+			RequestedAt: &now,
+			PurgeAfter:  now.Add(config.AccountDeletionGrace),
+		}, false, "", "", "").
+		Execute()
+	if err != nil {
+		return types.AccountDeletion{}, false, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+	var inserted []types.AccountDeletion
+	if err := json.Unmarshal(resp, &inserted); err != nil || len(inserted) == 0 {
+		return types.AccountDeletion{}, false, fmt.Errorf("failed to decode account deletion: %v", err)
+	}
+	return inserted[0], true, nil
+}
+
+// GetAccountDeletion returns the user's most recent deletion request
+func GetAccountDeletion(client *supabase.Client, userID string) (types.AccountDeletion, error) {
+	resp, _, err := client.From("account_deletions").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Order("requested_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(1, "").
+		Execute()
+	if err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to fetch account deletion: %w", err)
+	}
+	var deletions []types.AccountDeletion
+	if err := json.Unmarshal(resp, &deletions); err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to decode account deletion: %w", err)
+	}
+	if len(deletions) == 0 {
+		return types.AccountDeletion{}, fmt.Errorf("no account deletion found")
+	}
+	return deletions[0], nil
+}
+
+// CancelAccountDeletion withdraws a scheduled deletion during the grace
+// period
+func CancelAccountDeletion(client *supabase.Client, userID string) (types.AccountDeletion, error) {
+	now := time.Now().UTC()
+	resp, _, err := client.From("account_deletions").
+		Update(map[string]interface{}{
+			"status":       types.AccountDeletionCancelled,
+			"cancelled_at": now.Format(time.RFC3339),
+		}, "", "").
+		Eq("user_id", userID).
+		Eq("status", types.AccountDeletionScheduled).
+		Gt("purge_after", now.Format(time.RFC3339)).
+		Execute()
+	if err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+	var cancelled []types.AccountDeletion
+	if err := json.Unmarshal(resp, &cancelled); err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to decode account deletion: %w", err)
+	}
+	if len(cancelled) == 0 {
+		return types.AccountDeletion{}, fmt.Errorf("no account deletion found that can still be cancelled")
+	}
+	return cancelled[0], nil
+}
+
+// PurgeDueAccounts erases the accounts whose grace period has ended. It
+// needs a client that can see every user's rows.
+func PurgeDueAccounts(client *supabase.Client, now time.Time) error {
+	resp, _, err := client.From("account_deletions").
+		Select("*", "", false).
+		Eq("status", types.AccountDeletionScheduled).
+		Lte("purge_after", now.UTC().Format(time.RFC3339)).
+		Limit(maxPurgesPerSweep, "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to fetch due account deletions: %w", err)
+	}
+	var due []types.AccountDeletion
+	if err := json.Unmarshal(resp, &due); err != nil {
+		return fmt.Errorf("failed to decode due account deletions: %w", err)
+	}
+
+	for _, deletion := range due {
+		if err := purgeAccount(client, deletion); err != nil {
+			// Left scheduled, so the next sweep retries
+			log.Printf("Failed to purge account %s: %v", deletion.UserID, err)
+		}
+	}
+	return nil
+}
+
+// purgeAccount hard deletes every row and file the user owns, then the
+// auth user, and stores a signed receipt on the deletion record. What it
+// erases is saved as it goes, so a retry after a failure adds to the
+// counts instead of starting them over.
+func purgeAccount(client *supabase.Client, deletion types.AccountDeletion) error {
+	userID := deletion.UserID
+	receipt := types.DeletionReceipt{
+		DeletionID: deletion.ID,
+		UserID:     userID,
+		Counts:     map[string]int64{},
+		Algorithm:  receiptAlgorithm,
+	}
+	if deletion.Progress != nil {
+		for table, count := range deletion.Progress.Counts {
+			receipt.Counts[table] = count
+		}
+		receipt.Files = deletion.Progress.Files
+	}
+	if deletion.RequestedAt != nil {
+		receipt.RequestedAt = deletion.RequestedAt.UTC()
+	}
+
+	// Export archives live in storage, found through their jobs. Saved
+	// progress means they are gone already.
+	if deletion.Progress == nil {
+		resp, _, err := client.From("data_jobs").
+			Select("*", "", false).
+			Eq("user_id", userID).
+			Eq("kind", types.DataJobExport).
+			Eq("status", types.DataJobCompleted).
+			Execute()
+		if err != nil {
+			return fmt.Errorf("failed to list exports: %w", err)
+		}
+		var exports []types.DataJob
+		if err := json.Unmarshal(resp, &exports); err != nil {
+			return fmt.Errorf("failed to decode exports: %w", err)
+		}
+		if len(exports) > 0 {
+			paths := make([]string, len(exports))
+			for i, job := range exports {
+				paths[i] = exportPath(job)
+			}
+			if _, err := client.Storage.RemoveFile(ExportBucket, paths); err != nil {
+				return fmt.Errorf("failed to remove exports: %w", err)
+			}
+			receipt.Files = len(paths)
+		}
+		if err := savePurgeProgress(client, deletion.ID, receipt); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range purgeTables {
+		_, count, err := client.From(table).
+			Delete("minimal", "exact").
+			Eq("user_id", userID).
+			Execute()
+		if isMissingTable(err) {
+			// Nothing can be stored in a table that isn't there
+			log.Printf("Warning: table %s is missing, skipping it in account purge", table)
+			receipt.MissingTables = append(receipt.MissingTables, table)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", table, err)
+		}
+		receipt.Counts[table] += count
+		if err := savePurgeProgress(client, deletion.ID, receipt); err != nil {
+			return err
+		}
+	}
+
+	// Erasing the login needs the service role; the data is gone either way
+	if id, err := uuid.Parse(userID); err == nil {
+		if err := client.Auth.AdminDeleteUser(gotruetypes.AdminDeleteUserRequest{UserID: id}); err != nil {
+			log.Printf("Failed to delete auth user %s: %v", userID, err)
+		} else {
+			receipt.AuthUserErased = true
+		}
+	}
+
+	receipt.PurgedAt = time.Now().UTC()
+	signature, err := signReceipt(receipt)
+	if err != nil {
+		return err
+	}
+	receipt.Signature = signature
+
+	_, _, err = client.From("account_deletions").
+		Update(map[string]interface{}{
+			"status":       types.AccountDeletionCompleted,
+			"completed_at": receipt.PurgedAt.Format(time.RFC3339),
+			"receipt":      receipt,
+		}, "", "").
+		Eq("id", deletion.ID).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to store deletion receipt: %w", err)
+	}
+	return nil
+}
+
+// savePurgeProgress records what a purge has erased so far on its deletion
+func savePurgeProgress(client *supabase.Client, deletionID string, progress types.DeletionReceipt) error {
+	_, _, err := client.From("account_deletions").
+		Update(map[string]interface{}{"progress": progress}, "minimal", "").
+		Eq("id", deletionID).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save purge progress: %w", err)
+	}
+	return nil
+}
+
+// isMissingTable reports whether a PostgREST error says the table doesn't
+// exist, in the database or in PostgREST's schema cache
+func isMissingTable(err error) bool {
+	return err != nil && (strings.HasPrefix(err.Error(), "(42P01)") || strings.HasPrefix(err.Error(), "(PGRST205)"))
+}
+
+// GetDeletionReceipt returns the receipt of a completed deletion. The
+// deletion ID is the only credential, since the account no longer exists.
+func GetDeletionReceipt(client *supabase.Client, deletionID string) (types.DeletionReceipt, error) {
+	resp, _, err := client.From("account_deletions").
+		Select("*", "", false).
+		Eq("id", deletionID).
+		Eq("status", types.AccountDeletionCompleted).
+		Execute()
+	if err != nil {
+		return types.DeletionReceipt{}, fmt.Errorf("failed to fetch deletion receipt: %w", err)
+	}
+	var deletions []types.AccountDeletion
+	if err := json.Unmarshal(resp, &deletions); err != nil {
+		return types.DeletionReceipt{}, fmt.Errorf("failed to decode deletion receipt: %w", err)
+	}
+	if len(deletions) == 0 || deletions[0].Receipt == nil {
+		return types.DeletionReceipt{}, fmt.Errorf("receipt not found")
+	}
+	return *deletions[0].Receipt, nil
+}
+
+// VerifyDeletionReceipt reports whether a receipt was signed by this
+// deployment and hasn't been altered
+func VerifyDeletionReceipt(receipt types.DeletionReceipt) bool {
+	expected, err := signReceipt(receipt)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(receipt.Signature))
+}
+
+func signReceipt(receipt types.DeletionReceipt) (string, error) {
+	secret := receiptSecret()
+	if secret == "" {
+		return "", fmt.Errorf("DELETION_RECEIPT_SECRET not set")
+	}
+	receipt.Signature = ""
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode receipt: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func receiptSecret() string {
+	return os.Getenv("DELETION_RECEIPT_SECRET")
+}
@@ -0,0 +1,250 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/ical"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+	MaxBatchOperations  = 100
+)
+
+// protectedTaskFields can't be changed through a batch update
+var protectedTaskFields = map[string]bool{"id": true, "user_id": true, "created_at": true}
+
+// ApplyTaskBatch applies create, update and delete operations in order.
+//
+// In atomic mode nothing is applied unless every operation validates, and a
+// failure part-way through undoes the operations already applied (deleting
+// created tasks, restoring updated fields and re-inserting deleted tasks).
+// PostgREST has no multi-request transactions, so the undo can fail too; an
+// operation that couldn't be undone stays successful in the results, with
+// the reason in RollbackError. In best-effort mode each operation stands alone.
+func ApplyTaskBatch(client *supabase.Client, userID string, profile types.UserProfile, mode string, ops []types.TaskBatchOperation) ([]types.TaskBatchResult, bool) {
+	results := make([]types.TaskBatchResult, len(ops))
+	snapshots := make([]*types.Task, len(ops))
+	valid := true
+
+	for i, op := range ops {
+		results[i] = types.TaskBatchResult{Index: i, Op: op.Op, ID: op.ID}
+		snapshot, err := validateBatchOperation(client, userID, op)
+		if err != nil {
+			results[i].Error = err.Error()
+			valid = false
+			continue
+		}
+		snapshots[i] = snapshot
+	}
+
+	if mode == BatchModeAtomic && !valid {
+		for i := range results {
+			if results[i].Error == "" {
+				results[i].Error = "not applied: another operation in the batch is invalid"
+			}
+		}
+		return results, false
+	}
+
+	var undo []batchUndo
+	for i, op := range ops {
+		if results[i].Error != "" {
+			continue
+		}
+
+		task, rollback, err := applyBatchOperation(client, userID, profile, op, snapshots[i])
+		if err != nil {
+			results[i].Error = err.Error()
+			if mode == BatchModeAtomic {
+				for j := i + 1; j < len(results); j++ {
+					results[j].Error = fmt.Sprintf("not applied: operation %d failed", i)
+				}
+				rollbackBatch(undo, results, i)
+				return results, true
+			}
+			continue
+		}
+
+		results[i].Success = true
+		results[i].Task = task
+		if task != nil {
+			results[i].ID = task.ID
+		}
+		undo = append(undo, batchUndo{index: i, undo: rollback})
+	}
+
+	return results, false
+}
+
+// validateBatchOperation checks an operation and, for updates and deletes,
+// returns the current task so it can be restored
+func validateBatchOperation(client *supabase.Client, userID string, op types.TaskBatchOperation) (*types.Task, error) {
+	switch op.Op {
+	case "create":
+		if op.Task == nil || op.Task.Title == "" {
+			return nil, fmt.Errorf("create requires a task with a title")
+		}
+		if op.Task.Recurrence != "" {
+			if err := ical.ValidateRRule(op.Task.Recurrence); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "update", "delete":
+		if _, err := uuid.Parse(op.ID); err != nil {
+			return nil, fmt.Errorf("invalid task ID")
+		}
+		if op.Op == "update" {
+			if len(op.Updates) == 0 {
+				return nil, fmt.Errorf("update requires at least one field")
+			}
+			for field := range op.Updates {
+				if protectedTaskFields[field] {
+					return nil, fmt.Errorf("field %q cannot be updated", field)
+				}
+			}
+			if status, ok := op.Updates["status"]; ok && !isValidTaskStatus(status) {
+				return nil, fmt.Errorf("invalid status %v", status)
+			}
+			if rule, ok := op.Updates["recurrence"].(string); ok && rule != "" {
+				if err := ical.ValidateRRule(rule); err != nil {
+					return nil, err
+				}
+			}
+		}
+		tasks, err := GetSingleTask(client, userID, op.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch task: %w", err)
+		}
+		if len(tasks) == 0 {
+			return nil, fmt.Errorf("task not found")
+		}
+		return &tasks[0], nil
+	default:
+		return nil, fmt.Errorf("unknown op %q, expected create, update or delete", op.Op)
+	}
+}
+
+// applyBatchOperation performs one operation and returns a function that undoes it
+func applyBatchOperation(client *supabase.Client, userID string, profile types.UserProfile, op types.TaskBatchOperation, snapshot *types.Task) (*types.Task, func() error, error) {
+	switch op.Op {
+	case "create":
+		task := *op.Task
+		task.ID = ""
+		task.UserID = userID
+		saved, err := InsertAndReturnTask(client, profile, task)
+		if err != nil {
+			return nil, nil, err
+		}
+		recordBatchRevision(client, userID, nil, &saved, config.RevisionActorUser)
+		return &saved, func() error {
+			if err := DeleteTask(client, saved.ID, userID); err != nil {
+				return err
+			}
+			recordBatchRevision(client, userID, &saved, nil, config.RevisionActorSystem)
+			return nil
+		}, nil
+
+	case "update":
+		previous, err := previousValues(*snapshot, op.Updates)
+		if err != nil {
+			return nil, nil, err
+		}
+		updated, err := UpdateTask(client, op.ID, userID, op.Updates)
+		if err != nil {
+			return nil, nil, err
+		}
+		recordBatchRevision(client, userID, snapshot, &updated, config.RevisionActorUser)
+		return &updated, func() error {
+			restored, err := UpdateTask(client, op.ID, userID, previous)
+			if err != nil {
+				return err
+			}
+			recordBatchRevision(client, userID, &updated, &restored, config.RevisionActorSystem)
+			return nil
+		}, nil
+
+	default: // delete
+		if err := DeleteTask(client, op.ID, userID); err != nil {
+			return nil, nil, err
+		}
+		recordBatchRevision(client, userID, snapshot, nil, config.RevisionActorUser)
+		return nil, func() error {
+			if err := restoreTask(client, *snapshot); err != nil {
+				return err
+			}
+			recordBatchRevision(client, userID, nil, snapshot, config.RevisionActorSystem)
+			return nil
+		}, nil
+	}
+}
+
+// recordBatchRevision records a revision for a batch operation or its rollback
+func recordBatchRevision(client *supabase.Client, userID string, before, after *types.Task, actor string) {
+	if err := RecordTaskRevision(client, userID, before, after, actor, ""); err != nil {
+		log.Printf("Warning: failed to record task revision: %v", err)
+	}
+}
+
+// batchUndo undoes the applied operation at index
+type batchUndo struct {
+	index int
+	undo  func() error
+}
+
+// rollbackBatch undoes applied operations, newest first. Undone operations
+// are marked unsuccessful; ones that couldn't be undone keep their task and
+// get a RollbackError.
+func rollbackBatch(undo []batchUndo, results []types.TaskBatchResult, failed int) {
+	for i := len(undo) - 1; i >= 0; i-- {
+		result := &results[undo[i].index]
+		if err := undo[i].undo(); err != nil {
+			log.Printf("Warning: failed to roll back batch operation %d: %v", undo[i].index, err)
+			result.RollbackError = fmt.Sprintf("still applied: operation %d failed and undoing this one failed: %v", failed, err)
+			continue
+		}
+		result.Success = false
+		result.Task = nil
+		result.Error = fmt.Sprintf("rolled back: operation %d failed", failed)
+	}
+}
+
+// previousValues returns the snapshot's values for the fields being updated
+func previousValues(snapshot types.Task, updates map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var current map[string]interface{}
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return nil, err
+	}
+
+	previous := map[string]interface{}{}
+	for field := range updates {
+		previous[field] = current[field] // nil when the field was unset
+	}
+	return previous, nil
+}
+
+// restoreTask re-inserts a deleted task with its original ID
+func restoreTask(client *supabase.Client, task types.Task) error {
+	_, _, err := client.From("tasks").Insert(task, false, "", "", "").Execute()
+	return err
+}
+
+func isValidTaskStatus(status interface{}) bool {
+	switch status {
+	case "pending", "completed", "cancelled":
+		return true
+	}
+	return false
+}
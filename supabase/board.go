@@ -0,0 +1,347 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/ordering"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// GetBoardColumns returns the user's columns in board order
+func GetBoardColumns(client *supabase.Client, userID string) ([]types.BoardColumn, error) {
+	resp, _, err := client.From("board_columns").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Order("position", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch board columns: %w", err)
+	}
+
+	var columns []types.BoardColumn
+	if err := json.Unmarshal(resp, &columns); err != nil {
+		return nil, fmt.Errorf("failed to decode board columns: %w", err)
+	}
+	return columns, nil
+}
+
+// CreateBoardColumn adds a column at the end of the user's board
+func CreateBoardColumn(client *supabase.Client, userID string, column types.BoardColumn) (types.BoardColumn, error) {
+	column.Name = strings.TrimSpace(column.Name)
+	if column.Name == "" {
+		return types.BoardColumn{}, fmt.Errorf("column name is required")
+	}
+	if column.Status != "" && !isValidTaskStatus(column.Status) {
+		return types.BoardColumn{}, fmt.Errorf("invalid column status %q", column.Status)
+	}
+
+	existing, err := GetBoardColumns(client, userID)
+	if err != nil {
+		return types.BoardColumn{}, err
+	}
+	last := ""
+	if len(existing) > 0 {
+		last = existing[len(existing)-1].Position
+	}
+	position, err := ordering.KeyBetween(last, "")
+	if err != nil {
+		return types.BoardColumn{}, err
+	}
+
+	column.ID = ""
+	column.UserID = userID
+	column.Position = position
+	column.CreatedAt = nil
+
+	resp, _, err := client.From("board_columns").Insert(column, false, "", "", "").Execute()
+	if err != nil {
+		return types.BoardColumn{}, fmt.Errorf("failed to create board column: %w", err)
+	}
+
+	var inserted []types.BoardColumn
+	if err := json.Unmarshal(resp, &inserted); err != nil || len(inserted) == 0 {
+		return types.BoardColumn{}, fmt.Errorf("failed to decode created board column: %v", err)
+	}
+	return inserted[0], nil
+}
+
+// UpdateBoardColumn renames a column or changes the status it maps to
+func UpdateBoardColumn(client *supabase.Client, userID, columnID string, updates map[string]interface{}) (types.BoardColumn, error) {
+	payload := map[string]interface{}{}
+	for key, value := range updates {
+		switch key {
+		case "name":
+			name, _ := value.(string)
+			if strings.TrimSpace(name) == "" {
+				return types.BoardColumn{}, fmt.Errorf("column name is required")
+			}
+			payload["name"] = strings.TrimSpace(name)
+		case "status":
+			if value != nil && value != "" && !isValidTaskStatus(value) {
+				return types.BoardColumn{}, fmt.Errorf("invalid column status %v", value)
+			}
+			if value == "" {
+				value = nil
+			}
+			payload["status"] = value
+		default:
+			return types.BoardColumn{}, fmt.Errorf("field %q cannot be updated", key)
+		}
+	}
+	if len(payload) == 0 {
+		return types.BoardColumn{}, fmt.Errorf("empty update payload")
+	}
+
+	resp, _, err := client.From("board_columns").
+		Update(payload, "", "").
+		Eq("id", columnID).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return types.BoardColumn{}, fmt.Errorf("failed to update board column: %w", err)
+	}
+
+	var updated []types.BoardColumn
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return types.BoardColumn{}, fmt.Errorf("failed to decode board column: %w", err)
+	}
+	if len(updated) == 0 {
+		return types.BoardColumn{}, fmt.Errorf("column not found")
+	}
+	return updated[0], nil
+}
+
+// DeleteBoardColumn deletes a column. Its tasks stay but leave the board;
+// they are returned as they were before the column was removed.
+func DeleteBoardColumn(client *supabase.Client, userID, columnID string) ([]types.Task, error) {
+	resp, _, err := client.From("tasks").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("column_id", columnID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch column tasks: %w", err)
+	}
+	var tasks []types.Task
+	if err := json.Unmarshal(resp, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode column tasks: %w", err)
+	}
+
+	_, _, err = client.From("tasks").
+		Update(map[string]interface{}{"column_id": nil, "position": nil}, "", "").
+		Eq("user_id", userID).
+		Eq("column_id", columnID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove tasks from column: %w", err)
+	}
+
+	_, _, err = client.From("board_columns").
+		Delete("", "").
+		Eq("id", columnID).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete board column: %w", err)
+	}
+	return tasks, nil
+}
+
+// ReorderTask moves a task into a column between two neighbours and returns
+// the task before and after the move
+func ReorderTask(client *supabase.Client, userID string, req types.ReorderTaskRequest) (types.Task, types.Task, error) {
+	found, err := GetSingleTask(client, userID, req.TaskID)
+	if err != nil {
+		return types.Task{}, types.Task{}, err
+	}
+	if len(found) == 0 {
+		return types.Task{}, types.Task{}, fmt.Errorf("task not found")
+	}
+	task := found[0]
+
+	// Resolve the target column
+	columnID := task.ColumnID
+	var column *types.BoardColumn
+	if req.ColumnID != nil {
+		columnID = nil
+		if *req.ColumnID != "" {
+			columnID = req.ColumnID
+		}
+	}
+	if columnID != nil {
+		columns, err := GetBoardColumns(client, userID)
+		if err != nil {
+			return types.Task{}, types.Task{}, err
+		}
+		for i := range columns {
+			if columns[i].ID == *columnID {
+				column = &columns[i]
+				break
+			}
+		}
+		if column == nil {
+			return types.Task{}, types.Task{}, fmt.Errorf("column not found")
+		}
+	}
+
+	siblings, err := columnTasksInOrder(client, userID, columnID, task.ID)
+	if err != nil {
+		return types.Task{}, types.Task{}, err
+	}
+
+	// Find the positions on either side of the drop point
+	indexOf := func(id string) int {
+		for i := range siblings {
+			if siblings[i].ID == id {
+				return i
+			}
+		}
+		return -1
+	}
+	// at is where the task lands among its siblings
+	lower, upper, at := "", "", len(siblings)
+	switch {
+	case req.AfterID != "" && req.BeforeID != "":
+		a, b := indexOf(req.AfterID), indexOf(req.BeforeID)
+		if a < 0 || b < 0 {
+			return types.Task{}, types.Task{}, fmt.Errorf("neighbour tasks must be in the target column")
+		}
+		if b != a+1 {
+			return types.Task{}, types.Task{}, fmt.Errorf("after_id and before_id must be adjacent")
+		}
+		lower, upper, at = siblings[a].Position, siblings[b].Position, b
+	case req.AfterID != "":
+		a := indexOf(req.AfterID)
+		if a < 0 {
+			return types.Task{}, types.Task{}, fmt.Errorf("after_id must be in the target column")
+		}
+		lower, at = siblings[a].Position, a+1
+		if a+1 < len(siblings) {
+			upper = siblings[a+1].Position
+		}
+	case req.BeforeID != "":
+		b := indexOf(req.BeforeID)
+		if b < 0 {
+			return types.Task{}, types.Task{}, fmt.Errorf("before_id must be in the target column")
+		}
+		upper, at = siblings[b].Position, b
+		if b > 0 {
+			lower = siblings[b-1].Position
+		}
+	default:
+		if len(siblings) > 0 {
+			lower = siblings[len(siblings)-1].Position
+		}
+	}
+
+	position, err := ordering.KeyBetween(lower, upper)
+	if err != nil {
+		return types.Task{}, types.Task{}, err
+	}
+
+	// Respace the column once keys get long, giving the task its share
+	if len(position) > ordering.MaxKeyLen {
+		keys, err := ordering.KeysBetween("", "", len(siblings)+1)
+		if err != nil {
+			return types.Task{}, types.Task{}, err
+		}
+		position = keys[at]
+		keys = append(keys[:at], keys[at+1:]...)
+		if err := setTaskPositions(client, userID, siblings, keys); err != nil {
+			return types.Task{}, types.Task{}, err
+		}
+	}
+
+	payload := map[string]interface{}{
+		"column_id": columnID,
+		"position":  position,
+	}
+	if column != nil && column.Status != "" {
+		payload["status"] = column.Status
+	}
+
+	updated, err := UpdateTask(client, task.ID, userID, payload)
+	if err != nil {
+		return types.Task{}, types.Task{}, err
+	}
+	return task, updated, nil
+}
+
+// columnTasksInOrder returns the tasks in a column (nil for tasks on no
+// column) in position order, excluding skipID. Tasks that were never
+// positioned are given positions after the rest, oldest first, in one
+// request.
+func columnTasksInOrder(client *supabase.Client, userID string, columnID *string, skipID string) ([]types.Task, error) {
+	query := client.From("tasks").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		Neq("id", skipID)
+	if columnID != nil {
+		query = query.Eq("column_id", *columnID)
+	} else {
+		query = query.Is("column_id", "null")
+	}
+
+	resp, _, err := query.
+		Order("position", &postgrest.OrderOpts{Ascending: true}).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch column tasks: %w", err)
+	}
+
+	var tasks []types.Task
+	if err := json.Unmarshal(resp, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode column tasks: %w", err)
+	}
+
+	// Unpositioned tasks sort last, so backfill from the first of them
+	first := len(tasks)
+	for i := range tasks {
+		if tasks[i].Position == "" {
+			first = i
+			break
+		}
+	}
+	if first == len(tasks) {
+		return tasks, nil
+	}
+
+	last := ""
+	if first > 0 {
+		last = tasks[first-1].Position
+	}
+	keys, err := ordering.KeysAfter(last, len(tasks)-first)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTaskPositions(client, userID, tasks[first:], keys); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// setTaskPositions gives tasks[i] the position keys[i], in one request
+func setTaskPositions(client *supabase.Client, userID string, tasks []types.Task, keys []string) error {
+	positions := make([]map[string]string, len(tasks))
+	for i := range tasks {
+		positions[i] = map[string]string{"id": tasks[i].ID, "position": keys[i]}
+	}
+
+	var updated int
+	if err := callRPC(client, "set_task_positions", map[string]interface{}{
+		"input_user_id":   userID,
+		"input_positions": positions,
+	}, &updated); err != nil {
+		return fmt.Errorf("failed to position tasks: %w", err)
+	}
+	for i := range tasks {
+		tasks[i].Position = keys[i]
+	}
+	return nil
+}
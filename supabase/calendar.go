@@ -0,0 +1,113 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/types"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// hashFeedToken returns the stored form of a calendar feed token
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateCalendarFeedToken revokes the user's existing feed tokens and issues a new one.
+// The plaintext token is returned once and never stored.
+func CreateCalendarFeedToken(client *supabase.Client, userID string) (string, error) {
+	if err := RevokeCalendarFeedTokens(client, userID); err != nil {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	record := types.CalendarFeedToken{
+		UserID:    userID,
+		TokenHash: hashFeedToken(token),
+	}
+	_, _, err := client.From("calendar_feed_tokens").Insert(record, false, "", "", "").Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to save feed token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RevokeCalendarFeedTokens revokes every active feed token for the user
+func RevokeCalendarFeedTokens(client *supabase.Client, userID string) error {
+	_, _, err := client.From("calendar_feed_tokens").
+		Update(map[string]interface{}{
+			"revoked_at": time.Now().Format(time.RFC3339),
+		}, "", "").
+		Eq("user_id", userID).
+		Is("revoked_at", "null").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to revoke feed tokens: %w", err)
+	}
+	return nil
+}
+
+// GetUserIDForFeedToken resolves an active feed token to its owner.
+// It must be called with the service client, since the caller has no JWT.
+func GetUserIDForFeedToken(client *supabase.Client, token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("missing feed token")
+	}
+
+	resp, _, err := client.From("calendar_feed_tokens").
+		Select("user_id", "", false).
+		Eq("token_hash", hashFeedToken(token)).
+		Is("revoked_at", "null").
+		Limit(1, "").
+		Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up feed token: %w", err)
+	}
+
+	var tokens []types.CalendarFeedToken
+	if err := json.Unmarshal(resp, &tokens); err != nil {
+		return "", fmt.Errorf("failed to decode feed token: %w", err)
+	}
+	if len(tokens) == 0 || tokens[0].UserID == "" {
+		return "", fmt.Errorf("invalid or revoked feed token")
+	}
+
+	return tokens[0].UserID, nil
+}
+
+// GetTasksWithDueDates returns the user's non-deleted tasks that have a due date
+func GetTasksWithDueDates(client *supabase.Client, userID string) ([]types.Task, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("missing user ID")
+	}
+
+	resp, _, err := client.From("tasks").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		Not("due_date", "is", "null").
+		Order("due_date", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []types.Task
+	if err := json.Unmarshal(resp, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode task data: %w", err)
+	}
+
+	return tasks, nil
+}
@@ -1,9 +1,12 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/config"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/supabase-community/postgrest-go"
@@ -11,8 +14,8 @@ import (
 )
 
 // Build smart context with enhanced data
-func BuildSmartContext(client *supabase.Client, sessionID, userID string) (types.SmartContext, error) {
-	context := types.SmartContext{}
+func BuildSmartContext(client *supabase.Client, sessionID, userID string, profile types.UserProfile) (types.SmartContext, error) {
+	context := types.SmartContext{Profile: profile}
 
 	// 1. Get session summary (existing function)
 	summary, err := GetSessionSummary(client, sessionID)
@@ -22,8 +25,8 @@ func BuildSmartContext(client *supabase.Client, sessionID, userID string) (types
 	}
 	context.Summary = summary
 
-	// 2. Get recent messages with smart filtering
-	recentMessages, err := getRecentMessagesWithPriority(client, sessionID, userID, 10)
+	// 2. Get the recent conversation, oldest first
+	recentMessages, err := getRecentMessagesWithPriority(client, sessionID, userID, config.ContextConfig.MaxRecentMessages)
 	if err != nil {
 		fmt.Printf("Warning: Could not fetch recent messages: %v\n", err)
 	}
@@ -50,20 +53,41 @@ func BuildSmartContext(client *supabase.Client, sessionID, userID string) (types
 	}
 	context.UserPatterns = patterns
 
-	// 6. Generate priority signals
+	// 6. Get templates the assistant can instantiate
+	templates, err := GetTaskTemplates(client, userID)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch task templates: %v\n", err)
+	}
+	context.Templates = templates
+
+	// 7. Get recent focus time so the coach can acknowledge real effort
+	focus, err := GetFocusSummary(client, userID, context.Profile)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch focus summary: %v\n", err)
+	}
+	context.Focus = focus
+
+	// 8. Get board columns so the coach knows where each task sits
+	columns, err := GetBoardColumns(client, userID)
+	if err != nil {
+		fmt.Printf("Warning: Could not fetch board columns: %v\n", err)
+	}
+	context.Columns = columns
+
+	// 9. Generate priority signals
 	context.PrioritySignals = generatePrioritySignals(context)
 
 	return context, nil
 }
 
 func getRecentMessagesWithPriority(client *supabase.Client, sessionID, userID string, limit int) ([]types.Message, error) {
-	// Get more messages than needed for filtering
-	messages, err := GetRecentMessages(client, sessionID, userID, limit*2)
+	messages, err := GetRecentMessages(client, sessionID, userID, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply smart filtering while maintaining chronological order
+	// GetRecentMessages returns newest first
+	slices.Reverse(messages)
 	return messages, nil
 }
 
@@ -109,8 +133,16 @@ func generatePrioritySignals(context types.SmartContext) []string {
 	var signals []string
 
 	// Analyze context and generate priority signals
-	if context.SessionMetrics.DominantMood != "" {
-		signals = append(signals, fmt.Sprintf("User's dominant mood: %s", context.SessionMetrics.DominantMood))
+	if mood := context.SessionMetrics.DominantMood; mood != "" && mood != "neutral" {
+		signals = append(signals, fmt.Sprintf("User's dominant mood: %s", mood))
+	}
+
+	if len(context.SessionMetrics.PrimaryTopics) > 0 {
+		signals = append(signals, fmt.Sprintf("Session topics: %s", strings.Join(context.SessionMetrics.PrimaryTopics, ", ")))
+	}
+
+	if context.SessionMetrics.UserEngagementLevel == "low" && context.SessionMetrics.MessageCount >= 3 {
+		signals = append(signals, "User engagement is low: keep replies short and offer one easy next step")
 	}
 
 	if context.SessionMetrics.TasksCreated > 0 && context.SessionMetrics.TasksCompleted == 0 {
@@ -0,0 +1,140 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/pagination"
+	"clementus360/ai-helper/transcript"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/supabase-go"
+)
+
+const transcriptPageSize = 200
+
+// LoadTranscript gathers a session's conversation, summary and tasks for
+// export
+func LoadTranscript(client *supabase.Client, userID, sessionID string) (types.Transcript, error) {
+	session, err := getSession(client, userID, sessionID)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+
+	summary, err := GetSessionSummary(client, sessionID)
+	if err != nil {
+		return types.Transcript{}, err
+	}
+
+	// Pages come newest first, so walk back to the start of the conversation
+	var pages [][]types.Message
+	total := 0
+	cursor := pagination.Cursor{}
+	for total < transcript.MaxMessages {
+		messages, page, err := GetMessages(client, sessionID, userID, transcriptPageSize, cursor)
+		if err != nil {
+			return types.Transcript{}, fmt.Errorf("failed to fetch messages: %w", err)
+		}
+		pages = append(pages, messages)
+		total += len(messages)
+		if page.PrevCursor == "" {
+			break
+		}
+		if cursor, err = pagination.Decode(page.PrevCursor); err != nil {
+			return types.Transcript{}, err
+		}
+	}
+	slices.Reverse(pages)
+	messages := slices.Concat(pages...)
+	if len(messages) > transcript.MaxMessages {
+		messages = messages[len(messages)-transcript.MaxMessages:]
+	}
+
+	var tasks []types.Task
+	cursor = pagination.Cursor{}
+	for {
+		batch, _, page, err := GetTasks(client, userID, sessionID, "", "", transcriptPageSize, 0, cursor, "", "created_at", "asc")
+		if err != nil {
+			return types.Transcript{}, err
+		}
+		tasks = append(tasks, batch...)
+		if page.NextCursor == "" || len(tasks) >= transcript.MaxTasks {
+			break
+		}
+		if cursor, err = pagination.Decode(page.NextCursor); err != nil {
+			return types.Transcript{}, err
+		}
+	}
+
+	if len(tasks) > transcript.MaxTasks {
+		tasks = tasks[:transcript.MaxTasks]
+	}
+
+	return transcript.Build(session, summary, messages, tasks, time.Now()), nil
+}
+
+// ImportTranscript recreates a transcript as a new session of the user's,
+// with fresh IDs. The summary is kept so the session starts with context.
+// If the messages or tasks can't be saved, the partly imported session is
+// removed.
+func ImportTranscript(client *supabase.Client, userID string, t types.Transcript) (types.Session, error) {
+	session, messages, tasks := transcript.Records(t, userID, uuid.NewString, time.Now())
+
+	resp, _, err := client.From("sessions").Insert(session, false, "", "", "").Execute()
+	if err != nil {
+		return types.Session{}, fmt.Errorf("failed to create imported session: %w", err)
+	}
+	var created []types.Session
+	if err := json.Unmarshal(resp, &created); err != nil || len(created) == 0 {
+		return types.Session{}, fmt.Errorf("failed to decode imported session: %v", err)
+	}
+
+	for start := 0; start < len(messages); start += importChunkSize {
+		chunk := messages[start:min(start+importChunkSize, len(messages))]
+		if _, _, err := client.From("messages").Insert(chunk, false, "", "", "").Execute(); err != nil {
+			removeImportedSession(client, userID, session.ID)
+			return types.Session{}, fmt.Errorf("failed to import messages: %w", err)
+		}
+	}
+	for start := 0; start < len(tasks); start += importChunkSize {
+		chunk := tasks[start:min(start+importChunkSize, len(tasks))]
+		if _, _, err := client.From("tasks").Insert(chunk, false, "", "", "").Execute(); err != nil {
+			removeImportedSession(client, userID, session.ID)
+			return types.Session{}, fmt.Errorf("failed to import tasks: %w", err)
+		}
+	}
+
+	if err := RecordTaskRevisions(client, userID, TaskCreations(tasks), config.RevisionActorUser, ""); err != nil {
+		log.Printf("Warning: failed to record revisions for imported tasks: %v", err)
+	}
+	if t.Summary != "" {
+		_, _, err := client.From("session_summaries").Insert(types.SessionSummary{
+			SessionID:   session.ID,
+			UserID:      userID,
+			Summary:     t.Summary,
+			LastUpdated: time.Now(),
+		}, false, "", "", "").Execute()
+		if err != nil {
+			log.Printf("Warning: failed to import session summary: %v", err)
+		}
+	}
+
+	return created[0], nil
+}
+
+// removeImportedSession deletes a session whose import failed part way,
+// with whatever of its tasks and messages were saved
+func removeImportedSession(client *supabase.Client, userID, sessionID string) {
+	for _, table := range []string{"tasks", "messages"} {
+		if _, _, err := client.From(table).Delete("", "").Eq("user_id", userID).Eq("session_id", sessionID).Execute(); err != nil {
+			log.Printf("Warning: failed to remove %s of incomplete import %s: %v", table, sessionID, err)
+		}
+	}
+	if _, _, err := client.From("sessions").Delete("", "").Eq("id", sessionID).Eq("user_id", userID).Execute(); err != nil {
+		log.Printf("Warning: failed to remove incomplete import %s: %v", sessionID, err)
+	}
+}
@@ -0,0 +1,305 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// Focus session kinds
+const (
+	FocusKindTimer    = "timer"
+	FocusKindPomodoro = "pomodoro"
+
+	DefaultPomodoroMinutes = 25
+	MaxPomodoroMinutes     = 120
+
+	// MaxTimerSeconds caps a plain timer, so one left running overnight
+	// doesn't count as a day of focus
+	MaxTimerSeconds = 4 * 60 * 60
+)
+
+// ErrFocusSessionRunning is returned when the user already has a running
+// focus session. A partial unique index on focus_sessions enforces this.
+var ErrFocusSessionRunning = errors.New("a focus session is already running")
+
+// GetRunningFocusSession returns the user's running focus session, or nil if none.
+// A user tracks one task at a time.
+func GetRunningFocusSession(client *supabase.Client, userID string) (*types.FocusSession, error) {
+	resp, _, err := client.From("focus_sessions").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Is("ended_at", "null").
+		Order("started_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(1, "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch running focus session: %w", err)
+	}
+
+	var sessions []types.FocusSession
+	if err := json.Unmarshal(resp, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode focus session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return &sessions[0], nil
+}
+
+// StartFocusSession starts a timer or pomodoro on a task
+func StartFocusSession(client *supabase.Client, userID, taskID string, req types.StartTimerRequest) (types.FocusSession, error) {
+	session := types.FocusSession{
+		UserID:    userID,
+		TaskID:    taskID,
+		Kind:      req.Kind,
+		StartedAt: time.Now().UTC(),
+	}
+
+	switch req.Kind {
+	case "", FocusKindTimer:
+		session.Kind = FocusKindTimer
+		if req.PlannedMinutes != 0 {
+			return types.FocusSession{}, fmt.Errorf("planned_minutes only applies to pomodoro sessions")
+		}
+	case FocusKindPomodoro:
+		session.PlannedMinutes = req.PlannedMinutes
+		if session.PlannedMinutes == 0 {
+			session.PlannedMinutes = DefaultPomodoroMinutes
+		}
+		if session.PlannedMinutes < 1 || session.PlannedMinutes > MaxPomodoroMinutes {
+			return types.FocusSession{}, fmt.Errorf("planned_minutes must be between 1 and %d", MaxPomodoroMinutes)
+		}
+	default:
+		return types.FocusSession{}, fmt.Errorf("kind must be %q or %q", FocusKindTimer, FocusKindPomodoro)
+	}
+	if req.SessionID != "" {
+		session.SessionID = &req.SessionID
+	}
+
+	resp, _, err := client.From("focus_sessions").Insert(session, false, "", "", "").Execute()
+	if err != nil {
+		if isUniqueViolation(err) {
+			return types.FocusSession{}, ErrFocusSessionRunning
+		}
+		return types.FocusSession{}, fmt.Errorf("failed to start focus session: %w", err)
+	}
+
+	var inserted []types.FocusSession
+	if err := json.Unmarshal(resp, &inserted); err != nil || len(inserted) == 0 {
+		return types.FocusSession{}, fmt.Errorf("failed to decode started focus session: %v", err)
+	}
+	return inserted[0], nil
+}
+
+// StopFocusSession ends a running session and records its duration, up to
+// the session's limit. A pomodoro counts as completed once it has run its
+// planned length.
+func StopFocusSession(client *supabase.Client, userID string, session types.FocusSession) (types.FocusSession, error) {
+	now := time.Now().UTC()
+	duration := focusSeconds(session, now)
+	completed := session.Kind == FocusKindPomodoro && duration >= session.PlannedMinutes*60
+
+	resp, _, err := client.From("focus_sessions").
+		Update(map[string]interface{}{
+			"ended_at":         now,
+			"duration_seconds": duration,
+			"completed":        completed,
+		}, "", "").
+		Eq("id", session.ID).
+		Eq("user_id", userID).
+		Is("ended_at", "null").
+		Execute()
+	if err != nil {
+		return types.FocusSession{}, fmt.Errorf("failed to stop focus session: %w", err)
+	}
+
+	var updated []types.FocusSession
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return types.FocusSession{}, fmt.Errorf("failed to decode stopped focus session: %w", err)
+	}
+	if len(updated) == 0 {
+		return types.FocusSession{}, fmt.Errorf("focus session already stopped")
+	}
+	return updated[0], nil
+}
+
+// GetFocusSessions returns the user's focus sessions started in [from, to),
+// newest first, optionally for a single task
+func GetFocusSessions(client *supabase.Client, userID string, from, to time.Time, taskID string) ([]types.FocusSession, error) {
+	query := client.From("focus_sessions").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Gte("started_at", from.UTC().Format(time.RFC3339)).
+		Lt("started_at", to.UTC().Format(time.RFC3339))
+	if taskID != "" {
+		query = query.Eq("task_id", taskID)
+	}
+
+	resp, _, err := query.
+		Order("started_at", &postgrest.OrderOpts{Ascending: false}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch focus sessions: %w", err)
+	}
+
+	var sessions []types.FocusSession
+	if err := json.Unmarshal(resp, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode focus sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// AggregateFocus totals sessions per task (most time first) and per day
+// (oldest first). Days are in loc and a session counts toward the day it
+// started on. Running sessions count the time elapsed so far.
+func AggregateFocus(sessions []types.FocusSession, loc *time.Location, now time.Time) ([]types.TaskFocusTotal, []types.DailyFocusTotal, int) {
+	perTask := map[string]*types.TaskFocusTotal{}
+	perDay := map[string]*types.DailyFocusTotal{}
+	total := 0
+
+	for _, s := range sessions {
+		seconds := focusSeconds(s, now)
+		total += seconds
+
+		task, ok := perTask[s.TaskID]
+		if !ok {
+			task = &types.TaskFocusTotal{TaskID: s.TaskID}
+			perTask[s.TaskID] = task
+		}
+		task.Seconds += seconds
+		task.Sessions++
+
+		date := s.StartedAt.In(loc).Format("2006-01-02")
+		day, ok := perDay[date]
+		if !ok {
+			day = &types.DailyFocusTotal{Date: date}
+			perDay[date] = day
+		}
+		day.Seconds += seconds
+		day.Sessions++
+	}
+
+	tasks := make([]types.TaskFocusTotal, 0, len(perTask))
+	for _, t := range perTask {
+		tasks = append(tasks, *t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Seconds != tasks[j].Seconds {
+			return tasks[i].Seconds > tasks[j].Seconds
+		}
+		return tasks[i].TaskID < tasks[j].TaskID
+	})
+
+	days := make([]types.DailyFocusTotal, 0, len(perDay))
+	for _, d := range perDay {
+		days = append(days, *d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	return tasks, days, total
+}
+
+// AttachTaskTitles fills in task titles on per-task totals
+func AttachTaskTitles(client *supabase.Client, userID string, totals []types.TaskFocusTotal) error {
+	if len(totals) == 0 {
+		return nil
+	}
+	ids := make([]string, len(totals))
+	for i, t := range totals {
+		ids[i] = t.TaskID
+	}
+
+	resp, _, err := client.From("tasks").
+		Select("id,title", "", false).
+		Eq("user_id", userID).
+		In("id", ids).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to fetch task titles: %w", err)
+	}
+
+	var tasks []types.Task
+	if err := json.Unmarshal(resp, &tasks); err != nil {
+		return fmt.Errorf("failed to decode task titles: %w", err)
+	}
+	titles := make(map[string]string, len(tasks))
+	for _, t := range tasks {
+		titles[t.ID] = t.Title
+	}
+	for i := range totals {
+		totals[i].TaskTitle = titles[totals[i].TaskID]
+	}
+	return nil
+}
+
+// GetFocusSummary totals the user's focus time today and over the last
+// seven days in their timezone, for the coach's context
+func GetFocusSummary(client *supabase.Client, userID string, profile types.UserProfile) (types.FocusSummary, error) {
+	loc := ProfileLocation(profile)
+	now := time.Now()
+	localNow := now.In(loc)
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, loc)
+	weekStart := today.AddDate(0, 0, -6)
+
+	sessions, err := GetFocusSessions(client, userID, weekStart, now.Add(time.Minute), "")
+	if err != nil {
+		return types.FocusSummary{}, err
+	}
+
+	var summary types.FocusSummary
+	perTask, perDay, total := AggregateFocus(sessions, loc, now)
+	summary.WeekSeconds = total
+	if len(perDay) > 0 && perDay[len(perDay)-1].Date == today.Format("2006-01-02") {
+		summary.TodaySeconds = perDay[len(perDay)-1].Seconds
+	}
+
+	if len(perTask) > 3 {
+		perTask = perTask[:3]
+	}
+	if err := AttachTaskTitles(client, userID, perTask); err != nil {
+		return summary, err
+	}
+	summary.TopTasks = perTask
+
+	for i := range sessions {
+		if sessions[i].EndedAt == nil {
+			summary.Running = &sessions[i]
+			break
+		}
+	}
+	return summary, nil
+}
+
+// focusSeconds is a session's tracked time, counting running sessions up to
+// now. Time past the session's limit isn't counted: the user has most likely
+// forgotten the timer.
+func focusSeconds(s types.FocusSession, now time.Time) int {
+	seconds := s.DurationSeconds
+	if s.EndedAt == nil {
+		seconds = int(now.Sub(s.StartedAt).Seconds())
+	}
+	return max(0, min(seconds, focusLimitSeconds(s)))
+}
+
+// focusLimitSeconds is the most time a session can count: a pomodoro's
+// planned length, or MaxTimerSeconds for a timer
+func focusLimitSeconds(s types.FocusSession) int {
+	if s.Kind == FocusKindPomodoro && s.PlannedMinutes > 0 {
+		return s.PlannedMinutes * 60
+	}
+	return MaxTimerSeconds
+}
+
+// isUniqueViolation reports whether a PostgREST error is a unique
+// constraint violation
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(23505)")
+}
@@ -0,0 +1,302 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// ForkSession creates a branch of a session holding a copy of its
+// conversation up to and including fromMessageID (the whole conversation if
+// empty). The branch links back to its parent and starts with the parent's
+// summary and metrics.
+func ForkSession(client *supabase.Client, userID, sourceID, fromMessageID string) (types.Session, error) {
+	source, err := getSession(client, userID, sourceID)
+	if err != nil {
+		return types.Session{}, err
+	}
+
+	// Resolve the fork point
+	var until *types.Message
+	if fromMessageID != "" {
+		m, err := GetMessage(client, userID, fromMessageID)
+		if err != nil || m.SessionID != sourceID {
+			return types.Session{}, fmt.Errorf("message not found in session")
+		}
+		if m.SupersededAt != nil {
+			return types.Session{}, fmt.Errorf("can't fork from a replaced reply")
+		}
+		until = &m
+	}
+
+	query := client.From("messages").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("session_id", sourceID).
+		Is("deleted_at", "null").
+		Is("superseded_at", "null")
+	if until != nil {
+		// Up to the fork point in (created_at, id) order, so a message
+		// sharing its timestamp is only copied if it sorts before it
+		at := fmt.Sprintf("%q", until.CreatedAt.UTC().Format(time.RFC3339Nano))
+		query = query.Or(fmt.Sprintf("created_at.lt.%s,and(created_at.eq.%s,id.lte.%s)", at, at, until.ID), "")
+	}
+	resp, _, err := query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return types.Session{}, fmt.Errorf("failed to fetch messages to copy: %w", err)
+	}
+	var messages []types.Message
+	if err := json.Unmarshal(resp, &messages); err != nil {
+		return types.Session{}, fmt.Errorf("failed to decode messages to copy: %w", err)
+	}
+
+	// Create the branch
+	now := time.Now()
+	branch := types.Session{
+		LastActiveAt:    &now,
+		UserID:          userID,
+		Title:           "Fork of " + source.Title,
+		ParentSessionID: &source.ID,
+		Tags:            source.Tags,
+		State:           types.SessionStateActive,
+	}
+	if until != nil {
+		branch.ForkedFromMessageID = &until.ID
+	} else if len(messages) > 0 {
+		branch.ForkedFromMessageID = &messages[len(messages)-1].ID
+	}
+
+	resp, _, err = client.From("sessions").Insert(branch, false, "", "", "").Execute()
+	if err != nil {
+		return types.Session{}, fmt.Errorf("failed to create fork: %w", err)
+	}
+	var created []types.Session
+	if err := json.Unmarshal(resp, &created); err != nil || len(created) == 0 {
+		return types.Session{}, fmt.Errorf("failed to decode fork: %v", err)
+	}
+	branch = created[0]
+
+	// Copy the conversation with fresh IDs, keeping replies linked to the
+	// copies of the messages they answer
+	userMessages := 0
+	if len(messages) > 0 {
+		newIDs := make(map[string]string, len(messages))
+		for _, m := range messages {
+			newIDs[m.ID] = uuid.NewString()
+		}
+		copies := make([]types.Message, len(messages))
+		for i, m := range messages {
+			copies[i] = types.Message{
+				ID:            newIDs[m.ID],
+				UserID:        userID,
+				Sender:        m.Sender,
+				Content:       m.Content,
+				CreatedAt:     m.CreatedAt,
+				SessionID:     branch.ID,
+				UserMessageID: newIDs[m.UserMessageID],
+				EditedAt:      m.EditedAt,
+				PromptVersion: m.PromptVersion,
+			}
+			if m.Sender == "user" {
+				userMessages++
+			}
+		}
+		if _, _, err := client.From("messages").Insert(copies, false, "", "", "").Execute(); err != nil {
+			// Don't leave an empty branch behind
+			if _, _, delErr := client.From("sessions").Delete("", "").Eq("id", branch.ID).Eq("user_id", userID).Execute(); delErr != nil {
+				config.Logger.Warnf("Failed to remove incomplete fork %s: %v", branch.ID, delErr)
+			}
+			return types.Session{}, fmt.Errorf("failed to copy messages: %w", err)
+		}
+	}
+
+	seedForkState(client, userID, source.ID, branch.ID, userMessages)
+	return branch, nil
+}
+
+// seedForkState copies the parent's summary and metrics onto a new branch.
+// Failures only cost the branch some context, so they are logged.
+func seedForkState(client *supabase.Client, userID, sourceID, branchID string, userMessages int) {
+	var summaries []types.SessionSummary
+	resp, _, err := client.From("session_summaries").
+		Select("summary, prompt_version", "", false).
+		Eq("session_id", sourceID).
+		Execute()
+	if err == nil {
+		err = json.Unmarshal(resp, &summaries)
+	}
+	if err != nil {
+		config.Logger.Warn("Failed to fetch summary for fork:", err)
+	}
+	if len(summaries) > 0 && summaries[0].Summary != "" {
+		_, _, err := client.From("session_summaries").Insert(types.SessionSummary{
+			SessionID:     branchID,
+			UserID:        userID,
+			Summary:       summaries[0].Summary,
+			PromptVersion: summaries[0].PromptVersion,
+			LastUpdated:   time.Now(),
+		}, false, "", "", "").Execute()
+		if err != nil {
+			config.Logger.Warn("Failed to seed fork summary:", err)
+		}
+	}
+
+	resp, _, err = client.From("session_metrics").
+		Select("*", "", false).
+		Eq("session_id", sourceID).
+		Execute()
+	if err != nil {
+		config.Logger.Warn("Failed to fetch metrics for fork:", err)
+		return
+	}
+	var metrics []types.SessionMetrics
+	if err := json.Unmarshal(resp, &metrics); err != nil || len(metrics) == 0 {
+		return
+	}
+
+	// Carry over what the parent learned, counting only the copied turns
+	seeded := metrics[0]
+	now := time.Now()
+	seeded.SessionID = branchID
+	seeded.MessageCount = userMessages
+	seeded.TasksCreated = 0
+	seeded.TasksCompleted = 0
+	seeded.AvgMessageGap = 0
+	seeded.LastMessageAt = nil
+	seeded.LastActiveAt = now
+	seeded.CreatedAt = now
+	seeded.UpdatedAt = now
+	if _, _, err := client.From("session_metrics").Insert(seeded, false, "", "", "").Execute(); err != nil {
+		config.Logger.Warn("Failed to seed fork metrics:", err)
+	}
+}
+
+// Limits on the forks nested under one page of sessions
+const (
+	maxBranchDepth = 8
+	maxBranches    = 500
+)
+
+// attachBranches nests the user's forks that match filter under the given
+// top-level sessions, oldest first. A fork that doesn't match is left out
+// and its matching forks take its place. Only the roots' descendants are
+// fetched, one level per request, down to maxBranchDepth levels and
+// maxBranches forks in all.
+func attachBranches(client *supabase.Client, userID string, roots []types.Session, filter types.SessionFilter) error {
+	children := map[string][]types.Session{}
+	parents := make([]string, len(roots))
+	for i := range roots {
+		parents[i] = roots[i].ID
+	}
+
+	fetched := 0
+	for depth := 0; depth < maxBranchDepth && len(parents) > 0 && fetched < maxBranches; depth++ {
+		resp, _, err := client.From("sessions").
+			Select("*", "", false).
+			Eq("user_id", userID).
+			Is("deleted_at", "null").
+			In("parent_session_id", parents).
+			Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+			Order("id", &postgrest.OrderOpts{Ascending: true}).
+			Limit(maxBranches-fetched, "").
+			Execute()
+		if err != nil {
+			return fmt.Errorf("failed to fetch session branches: %w", err)
+		}
+		var forks []types.Session
+		if err := json.Unmarshal(resp, &forks); err != nil {
+			return fmt.Errorf("failed to decode session branches: %w", err)
+		}
+
+		fetched += len(forks)
+		parents = parents[:0]
+		for _, f := range forks {
+			children[*f.ParentSessionID] = append(children[*f.ParentSessionID], f)
+			parents = append(parents, f.ID)
+		}
+	}
+
+	var branches func(id string) []types.Session
+	branches = func(id string) []types.Session {
+		var out []types.Session
+		spliced := false
+		for _, c := range children[id] {
+			if matchesSessionFilter(c, filter) {
+				c.Branches = branches(c.ID)
+				out = append(out, c)
+			} else if grand := branches(c.ID); len(grand) > 0 {
+				out = append(out, grand...)
+				spliced = true
+			}
+		}
+		if spliced {
+			slices.SortStableFunc(out, compareCreated)
+		}
+		return out
+	}
+	for i := range roots {
+		roots[i].Branches = branches(roots[i].ID)
+	}
+	return nil
+}
+
+// compareCreated orders sessions by (created_at, id)
+func compareCreated(a, b types.Session) int {
+	var at, bt time.Time
+	if a.CreatedAt != nil {
+		at = *a.CreatedAt
+	}
+	if b.CreatedAt != nil {
+		bt = *b.CreatedAt
+	}
+	if c := at.Compare(bt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// promoteBranches makes a session's forks top-level sessions, for when it
+// is deleted or archived and would otherwise hide them in tree view
+func promoteBranches(client *supabase.Client, userID, sessionID string) error {
+	_, _, err := client.From("sessions").
+		Update(map[string]interface{}{"parent_session_id": nil}, "", "").
+		Eq("user_id", userID).
+		Eq("parent_session_id", sessionID).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to promote session branches: %w", err)
+	}
+	return nil
+}
+
+// getSession returns one of the user's live sessions
+func getSession(client *supabase.Client, userID, sessionID string) (types.Session, error) {
+	resp, _, err := client.From("sessions").
+		Select("*", "", false).
+		Eq("id", sessionID).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		Execute()
+	if err != nil {
+		return types.Session{}, fmt.Errorf("failed to fetch session: %w", err)
+	}
+	var sessions []types.Session
+	if err := json.Unmarshal(resp, &sessions); err != nil {
+		return types.Session{}, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return types.Session{}, fmt.Errorf("session not found")
+	}
+	return sessions[0], nil
+}
@@ -0,0 +1,159 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// maxSweepCloses bounds how many sessions one sweep closes, since each close
+// costs a summarizer call
+const maxSweepCloses = 50
+
+// summarySlots bounds the summarizer calls for closed sessions running at
+// once, across requests and sweeps
+var summarySlots = make(chan struct{}, 4)
+
+// TouchSession records activity on a session, making it active again if it
+// had gone idle or been closed
+func TouchSession(client *supabase.Client, userID, sessionID string) error {
+	_, _, err := client.From("sessions").
+		Update(map[string]interface{}{
+			"state":          types.SessionStateActive,
+			"last_active_at": time.Now().UTC().Format(time.RFC3339Nano),
+			"closed_at":      nil,
+		}, "", "").
+		Eq("id", sessionID).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// CloseSession marks a session closed. closed reports whether this call
+// closed it, so callers summarize each session once; closing a closed
+// session returns it unchanged.
+func CloseSession(client *supabase.Client, userID, sessionID string) (session types.Session, closed bool, err error) {
+	resp, _, err := client.From("sessions").
+		Update(map[string]interface{}{
+			"state":     types.SessionStateClosed,
+			"closed_at": time.Now().UTC().Format(time.RFC3339Nano),
+		}, "", "").
+		Eq("id", sessionID).
+		Eq("user_id", userID).
+		Neq("state", types.SessionStateClosed).
+		Is("deleted_at", "null").
+		Execute()
+	if err != nil {
+		return types.Session{}, false, fmt.Errorf("failed to close session: %w", err)
+	}
+
+	var updated []types.Session
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return types.Session{}, false, fmt.Errorf("failed to parse close result: %w", err)
+	}
+	if len(updated) > 0 {
+		return updated[0], true, nil
+	}
+
+	// Already closed, or not there at all
+	session, err = getSession(client, userID, sessionID)
+	return session, false, err
+}
+
+// SummarizeClosedSession summarizes a session that was just closed, once a
+// summarizer slot is free
+func SummarizeClosedSession(client *supabase.Client, userID, sessionID string) {
+	summarySlots <- struct{}{}
+	defer func() { <-summarySlots }()
+
+	if err := SummarizeSession(client, sessionID, userID); err != nil {
+		log.Printf("Failed to summarize closed session %s: %v", sessionID, err)
+	}
+}
+
+// closeAndSummarize closes sessions in the background, one at a time, and
+// summarizes those it closed
+func closeAndSummarize(client *supabase.Client, userID string, sessionIDs []string) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+	go func() {
+		for _, id := range sessionIDs {
+			_, closed, err := CloseSession(client, userID, id)
+			if err != nil {
+				log.Printf("Failed to close session %s: %v", id, err)
+				continue
+			}
+			if closed {
+				SummarizeClosedSession(client, userID, id)
+			}
+		}
+	}()
+}
+
+// SweepSessions moves sessions through their lifecycle by inactivity:
+// active sessions quiet for config.SessionIdleAfter go idle, and open
+// sessions quiet for config.SessionCloseAfter are closed and summarized,
+// the longest quiet first. It needs the service client, since it works
+// across users.
+func SweepSessions(client *supabase.Client, now time.Time) error {
+	idleCutoff := now.Add(-config.SessionIdleAfter).UTC().Format(time.RFC3339Nano)
+	_, _, err := client.From("sessions").
+		Update(map[string]interface{}{"state": types.SessionStateIdle}, "", "").
+		Eq("state", types.SessionStateActive).
+		Lt("last_active_at", idleCutoff).
+		Is("deleted_at", "null").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to idle sessions: %w", err)
+	}
+
+	closeCutoff := now.Add(-config.SessionCloseAfter).UTC().Format(time.RFC3339Nano)
+	resp, _, err := client.From("sessions").
+		Select("id, user_id", "", false).
+		In("state", []string{types.SessionStateActive, types.SessionStateIdle}).
+		Lt("last_active_at", closeCutoff).
+		Is("deleted_at", "null").
+		Order("last_active_at", &postgrest.OrderOpts{Ascending: true}).
+		Limit(maxSweepCloses, "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to fetch inactive sessions: %w", err)
+	}
+	var stale []types.Session
+	if err := json.Unmarshal(resp, &stale); err != nil {
+		return fmt.Errorf("failed to decode inactive sessions: %w", err)
+	}
+
+	// Summaries run a few at a time; the sweep waits for them so runs
+	// don't overlap
+	var wg sync.WaitGroup
+	for _, s := range stale {
+		_, closed, err := CloseSession(client, s.UserID, s.ID)
+		if err != nil {
+			log.Printf("Failed to close session %s: %v", s.ID, err)
+			continue
+		}
+		if !closed {
+			continue
+		}
+		wg.Add(1)
+		go func(s types.Session) {
+			defer wg.Done()
+			SummarizeClosedSession(client, s.UserID, s.ID)
+		}(s)
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,140 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/memory"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// MaxMemories caps how many memories are listed
+const MaxMemories = 500
+
+// memoryColumns are the columns callers need; embeddings stay in Postgres
+const memoryColumns = "id, user_id, content, embedder, source_session_id, source_message_id, created_at"
+
+// GetMemories returns the user's memories, newest first, without their
+// embeddings
+func GetMemories(client *supabase.Client, userID string) ([]types.Memory, error) {
+	resp, _, err := client.From("memories").
+		Select(memoryColumns, "", false).
+		Eq("user_id", userID).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(MaxMemories, "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memories: %w", err)
+	}
+
+	var memories []types.Memory
+	if err := json.Unmarshal(resp, &memories); err != nil {
+		return nil, fmt.Errorf("failed to decode memories: %w", err)
+	}
+	return memories, nil
+}
+
+// SaveMemories embeds and stores new facts about the user, skipping any
+// that restate an existing memory or an earlier fact in the same call
+func SaveMemories(client *supabase.Client, userID, sessionID, messageID string, facts []string) error {
+	embedder := memory.Default
+	var fresh []types.Memory
+	for _, fact := range facts {
+		fact = strings.TrimSpace(fact)
+		if fact == "" {
+			continue
+		}
+		vec, err := embedder.Embed(fact)
+		if err != nil {
+			return fmt.Errorf("failed to embed memory: %w", err)
+		}
+		if memory.IsDuplicate(vec, embedder.Name(), fresh) {
+			continue
+		}
+		existing, err := matchMemories(client, userID, vec, embedder.Name(), memory.DuplicateThreshold, 1)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue
+		}
+
+		m := types.Memory{
+			UserID:    userID,
+			Content:   fact,
+			Embedding: vec,
+			Embedder:  embedder.Name(),
+			CreatedAt: time.Now(),
+		}
+		if sessionID != "" {
+			m.SourceSessionID = &sessionID
+		}
+		if messageID != "" {
+			m.SourceMessageID = &messageID
+		}
+		fresh = append(fresh, m)
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	// The rows aren't returned, since embeddings come back as pgvector text
+	_, _, err := client.From("memories").Insert(fresh, false, "", "minimal", "").Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save memories: %w", err)
+	}
+	return nil
+}
+
+// RecallMemories returns up to k of the user's memories most relevant to text
+func RecallMemories(client *supabase.Client, userID, text string, k int) ([]types.Memory, error) {
+	embedder := memory.Default
+	query, err := embedder.Embed(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return matchMemories(client, userID, query, embedder.Name(), memory.MinRelevance, k)
+}
+
+// matchMemories returns up to k of the user's memories from embedder that
+// are at least minSimilarity similar to vec, best first. Postgres ranks
+// them with pgvector; see the match_memories function.
+func matchMemories(client *supabase.Client, userID string, vec []float32, embedder string, minSimilarity float64, k int) ([]types.Memory, error) {
+	var matches []struct {
+		types.Memory
+		Similarity float64 `json:"similarity"`
+	}
+	if err := callRPC(client, "match_memories", map[string]interface{}{
+		"input_user_id":        userID,
+		"input_embedding":      vec,
+		"input_embedder":       embedder,
+		"input_min_similarity": minSimilarity,
+		"input_limit":          k,
+	}, &matches); err != nil {
+		return nil, fmt.Errorf("failed to match memories: %w", err)
+	}
+
+	memories := make([]types.Memory, len(matches))
+	for i, m := range matches {
+		memories[i] = m.Memory
+		memories[i].Score = m.Similarity
+	}
+	return memories, nil
+}
+
+// DeleteMemory deletes one of the user's memories
+func DeleteMemory(client *supabase.Client, userID, memoryID string) error {
+	_, _, err := client.From("memories").
+		Delete("", "").
+		Eq("id", memoryID).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to delete memory: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/pagination"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
@@ -10,7 +11,9 @@ import (
 	"github.com/supabase-community/supabase-go"
 )
 
-func SaveMessage(client *supabase.Client, userID, sessionID, sender, UserMessageID, content string) (string, error) {
+// SaveMessage stores a message. promptVersion is the coach prompt an AI
+// reply was generated with, "" for user messages.
+func SaveMessage(client *supabase.Client, userID, sessionID, sender, UserMessageID, content, promptVersion string) (string, error) {
 	message := types.Message{
 		UserID:        userID,
 		SessionID:     sessionID,
@@ -18,6 +21,7 @@ func SaveMessage(client *supabase.Client, userID, sessionID, sender, UserMessage
 		Content:       content,
 		UserMessageID: UserMessageID,
 		CreatedAt:     time.Now(),
+		PromptVersion: promptVersion,
 	}
 
 	var inserted []types.Message
@@ -40,26 +44,56 @@ func SaveMessage(client *supabase.Client, userID, sessionID, sender, UserMessage
 	return inserted[0].ID, nil
 }
 
-func GetMessages(client *supabase.Client, sessionID, userID string) ([]types.Message, error) {
+// GetMessages returns a page of a session's messages, oldest first. Without
+// a cursor it returns the latest page; prev_cursor then walks back in time.
+// A limit of 0 without a cursor returns the whole conversation.
+func GetMessages(client *supabase.Client, sessionID, userID string, limit int, cursor pagination.Cursor) ([]types.Message, pagination.Page, error) {
 	var messages []types.Message
 
+	if limit <= 0 && !cursor.Bounded() {
+		data, _, err := client.
+			From("messages").
+			Select("*", "", false).
+			Eq("session_id", sessionID).
+			Eq("user_id", userID).
+			Is("superseded_at", "null").
+			Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+			Order("id", &postgrest.OrderOpts{Ascending: true}).
+			Execute()
+		if err != nil {
+			return nil, pagination.Page{}, err
+		}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, pagination.Page{}, err
+		}
+		return messages, pagination.Page{}, nil
+	}
+
+	if !cursor.Bounded() {
+		cursor.Before = true // start from the end of the conversation
+	}
+
 	query := client.
 		From("messages").
 		Select("*", "", false).
 		Eq("session_id", sessionID).
 		Eq("user_id", userID).
-		Order("created_at", &postgrest.OrderOpts{Ascending: true}) // ascending
+		Is("superseded_at", "null")
+	query = keysetPage(query, cursor, false, limit)
 
 	data, _, err := query.Execute()
 	if err != nil {
-		return nil, err
+		return nil, pagination.Page{}, err
 	}
 	err = json.Unmarshal(data, &messages)
 	if err != nil {
-		return nil, err
+		return nil, pagination.Page{}, err
 	}
 
-	return messages, nil
+	messages, page := pagination.Trim(messages, cursor, limit, func(m types.Message) (time.Time, string) {
+		return m.CreatedAt, m.ID
+	})
+	return messages, page, nil
 }
 
 func GetRecentMessages(client *supabase.Client, sessionID, userID string, limit int) ([]types.Message, error) {
@@ -67,9 +101,10 @@ func GetRecentMessages(client *supabase.Client, sessionID, userID string, limit
 
 	query := client.
 		From("messages").
-		Select("sender, content, created_at, session_id", "", false).
+		Select("id, sender, content, created_at, session_id, user_message_id", "", false).
 		Eq("user_id", userID).
 		Eq("session_id", sessionID).
+		Is("superseded_at", "null").
 		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
 		Limit(limit, "") // Get double to allow for filtering
 
@@ -84,3 +119,133 @@ func GetRecentMessages(client *supabase.Client, sessionID, userID string, limit
 
 	return messages, nil
 }
+
+// GetMessage returns one of the user's messages
+func GetMessage(client *supabase.Client, userID, messageID string) (types.Message, error) {
+	resp, _, err := client.From("messages").
+		Select("*", "", false).
+		Eq("id", messageID).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return types.Message{}, fmt.Errorf("failed to fetch message: %w", err)
+	}
+
+	var messages []types.Message
+	if err := json.Unmarshal(resp, &messages); err != nil {
+		return types.Message{}, fmt.Errorf("failed to decode message: %w", err)
+	}
+	if len(messages) == 0 {
+		return types.Message{}, fmt.Errorf("message not found")
+	}
+	return messages[0], nil
+}
+
+// UpdateMessageContent replaces the text of a user message and marks it edited
+func UpdateMessageContent(client *supabase.Client, userID, messageID, content string) (types.Message, error) {
+	resp, _, err := client.From("messages").
+		Update(map[string]interface{}{
+			"content":   content,
+			"edited_at": time.Now(),
+		}, "", "").
+		Eq("id", messageID).
+		Eq("user_id", userID).
+		Eq("sender", "user").
+		Execute()
+	if err != nil {
+		return types.Message{}, fmt.Errorf("failed to edit message: %w", err)
+	}
+
+	var updated []types.Message
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return types.Message{}, fmt.Errorf("failed to decode edited message: %w", err)
+	}
+	if len(updated) == 0 {
+		return types.Message{}, fmt.Errorf("message not found")
+	}
+	return updated[0], nil
+}
+
+// RestoreMessageContent puts back a user message's text and edit time as
+// they were in original, undoing an edit whose regenerated reply failed
+func RestoreMessageContent(client *supabase.Client, userID string, original types.Message) error {
+	_, _, err := client.From("messages").
+		Update(map[string]interface{}{
+			"content":   original.Content,
+			"edited_at": original.EditedAt,
+		}, "", "").
+		Eq("id", original.ID).
+		Eq("user_id", userID).
+		Eq("sender", "user").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to restore message: %w", err)
+	}
+	return nil
+}
+
+// GetLatestUserMessage returns the newest user message in a session
+func GetLatestUserMessage(client *supabase.Client, userID, sessionID string) (types.Message, error) {
+	resp, _, err := client.From("messages").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("session_id", sessionID).
+		Eq("sender", "user").
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(1, "").
+		Execute()
+	if err != nil {
+		return types.Message{}, fmt.Errorf("failed to fetch latest message: %w", err)
+	}
+
+	var messages []types.Message
+	if err := json.Unmarshal(resp, &messages); err != nil {
+		return types.Message{}, fmt.Errorf("failed to decode latest message: %w", err)
+	}
+	if len(messages) == 0 {
+		return types.Message{}, fmt.Errorf("session has no messages")
+	}
+	return messages[0], nil
+}
+
+// GetReplies returns the AI replies to a user message, oldest first.
+// Superseded replies are only included when withAlternates is set.
+func GetReplies(client *supabase.Client, userID, userMessageID string, withAlternates bool) ([]types.Message, error) {
+	query := client.From("messages").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("user_message_id", userMessageID).
+		Eq("sender", "ai")
+	if !withAlternates {
+		query = query.Is("superseded_at", "null")
+	}
+
+	resp, _, err := query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch replies: %w", err)
+	}
+
+	var replies []types.Message
+	if err := json.Unmarshal(resp, &replies); err != nil {
+		return nil, fmt.Errorf("failed to decode replies: %w", err)
+	}
+	return replies, nil
+}
+
+// SupersedeMessages keeps replies as alternates, hidden from the conversation
+func SupersedeMessages(client *supabase.Client, userID string, messageIDs []string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	_, _, err := client.From("messages").
+		Update(map[string]interface{}{"superseded_at": time.Now()}, "", "").
+		Eq("user_id", userID).
+		In("id", messageIDs).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to supersede replies: %w", err)
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/signals"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/supabase-community/supabase-go"
@@ -33,19 +35,27 @@ func GetOrCreateSessionMetrics(client *supabase.Client, sessionID, userID string
 
 	// Create new metrics
 	newMetrics := types.SessionMetrics{
-		SessionID:           sessionID,
-		UserID:              userID,
-		MessageCount:        0,
-		TasksCreated:        0,
-		TasksCompleted:      0,
-		LastActiveAt:        time.Now(),
-		UserEngagementLevel: "medium",
-		CreatedAt:           time.Now(),
-		UpdatedAt:           time.Now(),
+		SessionID:      sessionID,
+		UserID:         userID,
+		MessageCount:   0,
+		TasksCreated:   0,
+		TasksCompleted: 0,
+		LastActiveAt:   time.Now(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
+	newMetrics.UserEngagementLevel = signals.Engagement(newMetrics)
 
 	_, _, err = client.From("session_metrics").Insert(newMetrics, false, "", "", "").Execute()
 	if err != nil {
+		// Another request may have created them first
+		resp, _, fetchErr := client.From("session_metrics").
+			Select("*", "", false).
+			Eq("session_id", sessionID).
+			Execute()
+		if fetchErr == nil && json.Unmarshal(resp, &metrics) == nil && len(metrics) > 0 {
+			return metrics[0], nil
+		}
 		return types.SessionMetrics{}, fmt.Errorf("failed to create session metrics: %w", err)
 	}
 
@@ -83,3 +93,68 @@ func IncrementSessionCounter(client *supabase.Client, sessionID, counterType str
 
 	return nil
 }
+
+// maxSignalAttempts bounds the retries when another message's signals
+// land between reading and writing the metrics
+const maxSignalAttempts = 5
+
+// RecordMessageSignals classifies a user message and folds its mood,
+// topics and timing into the session's metrics. The write only applies if
+// signals_version is still what was read, so messages sent close together
+// don't overwrite each other's signals.
+func RecordMessageSignals(client *supabase.Client, sessionID, userID, content string, sentAt time.Time) error {
+	classification := signals.Default.Classify(content)
+
+	for attempt := 0; attempt < maxSignalAttempts; attempt++ {
+		metrics, err := GetOrCreateSessionMetrics(client, sessionID, userID)
+		if err != nil {
+			return err
+		}
+		version := metrics.SignalsVersion
+		signals.Observe(&metrics, classification, sentAt)
+
+		now := time.Now()
+		resp, _, err := client.From("session_metrics").
+			Update(map[string]interface{}{
+				"dominant_mood":           metrics.DominantMood,
+				"primary_topics":          metrics.PrimaryTopics,
+				"engagement_level":        metrics.UserEngagementLevel,
+				"mood_scores":             metrics.MoodScores,
+				"topic_counts":            metrics.TopicCounts,
+				"avg_message_gap_seconds": metrics.AvgMessageGap,
+				"last_message_at":         metrics.LastMessageAt,
+				"signals_version":         version + 1,
+				"updated_at":              now,
+				"last_active_at":          now,
+			}, "", "").
+			Eq("session_id", sessionID).
+			Eq("signals_version", strconv.Itoa(version)).
+			Execute()
+		if err != nil {
+			return fmt.Errorf("failed to update session metrics: %w", err)
+		}
+		var updated []types.SessionMetrics
+		if err := json.Unmarshal(resp, &updated); err != nil {
+			return fmt.Errorf("failed to parse session metrics update: %w", err)
+		}
+		if len(updated) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("session metrics kept changing, message signals not recorded")
+}
+
+// RefreshSessionEngagement recomputes a session's engagement after its task
+// counters change
+func RefreshSessionEngagement(client *supabase.Client, sessionID, userID string) error {
+	metrics, err := GetOrCreateSessionMetrics(client, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	level := signals.Engagement(metrics)
+	if level == metrics.UserEngagementLevel {
+		return nil
+	}
+	return UpdateSessionMetrics(client, sessionID, map[string]interface{}{"engagement_level": level})
+}
@@ -0,0 +1,19 @@
+-- Per-user timezone, locale and working hours. Users without a row get the
+-- defaults in supabase/profiles.go.
+create table if not exists user_profiles (
+  user_id uuid primary key references auth.users (id) on delete cascade,
+  timezone text not null default 'UTC',
+  locale text not null default 'en-US',
+  workday_start text not null default '09:00',
+  workday_end text not null default '17:00',
+  created_at timestamptz not null default now(),
+  updated_at timestamptz not null default now()
+);
+
+alter table user_profiles enable row level security;
+
+drop policy if exists "Users manage their own profile" on user_profiles;
+create policy "Users manage their own profile" on user_profiles
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
@@ -0,0 +1,29 @@
+-- Calendar feed tokens and task recurrence for the iCalendar feed. Only a
+-- SHA-256 hash of each token is stored; the feed looks tokens up by hash
+-- with the service client, since calendar apps send no JWT.
+create table if not exists calendar_feed_tokens (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  token_hash text not null,
+  created_at timestamptz not null default now(),
+  revoked_at timestamptz
+);
+
+create unique index if not exists calendar_feed_tokens_hash_idx
+  on calendar_feed_tokens (token_hash);
+
+create index if not exists calendar_feed_tokens_active_user_idx
+  on calendar_feed_tokens (user_id)
+  where revoked_at is null;
+
+alter table calendar_feed_tokens enable row level security;
+
+drop policy if exists "Users manage their own feed tokens" on calendar_feed_tokens;
+create policy "Users manage their own feed tokens" on calendar_feed_tokens
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
+
+-- RRULE value, e.g. "FREQ=WEEKLY;BYDAY=MO"
+alter table tasks
+  add column if not exists recurrence text;
@@ -0,0 +1,35 @@
+-- Field-level history of task changes. Revisions outlive their task, so
+-- task_id has no foreign key: a deleted task's history can still be read
+-- and its deletion reverted. Revisions are never edited, so users can only
+-- read and add their own.
+create table if not exists task_revisions (
+  id uuid primary key default gen_random_uuid(),
+  task_id uuid not null,
+  user_id uuid not null references auth.users (id) on delete cascade,
+  action text not null check (action in ('created', 'updated', 'deleted')),
+  actor_type text not null check (actor_type in ('user', 'ai', 'system')),
+  message_id uuid,
+  changes jsonb not null default '{}'::jsonb,
+  created_at timestamptz not null default now()
+);
+
+-- A task's history, oldest first
+create index if not exists task_revisions_user_task_idx
+  on task_revisions (user_id, task_id, created_at);
+
+-- The AI changes made by a message, undone when its reply is regenerated
+create index if not exists task_revisions_user_message_idx
+  on task_revisions (user_id, message_id, created_at desc)
+  where message_id is not null;
+
+alter table task_revisions enable row level security;
+
+drop policy if exists "Users read their own task revisions" on task_revisions;
+create policy "Users read their own task revisions" on task_revisions
+  for select
+  using (auth.uid() = user_id);
+
+drop policy if exists "Users add their own task revisions" on task_revisions;
+create policy "Users add their own task revisions" on task_revisions
+  for insert
+  with check (auth.uid() = user_id);
@@ -0,0 +1,23 @@
+-- User-defined task templates. Built-in templates live in the templates
+-- package and are never stored, so every row has an owner.
+create table if not exists task_templates (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  name text not null,
+  description text,
+  placeholders text[] not null default '{}',
+  steps jsonb not null,
+  built_in boolean not null default false,
+  created_at timestamptz not null default now()
+);
+
+create index if not exists task_templates_user_created_idx
+  on task_templates (user_id, created_at);
+
+alter table task_templates enable row level security;
+
+drop policy if exists "Users manage their own task templates" on task_templates;
+create policy "Users manage their own task templates" on task_templates
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
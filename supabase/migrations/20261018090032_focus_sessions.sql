@@ -0,0 +1,34 @@
+-- Timers and pomodoros on tasks. Sessions are kept when their task is
+-- deleted, since a reverted deletion restores the task with the same id.
+create table if not exists focus_sessions (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  task_id uuid not null,
+  session_id uuid,
+  kind text not null check (kind in ('timer', 'pomodoro')),
+  planned_minutes integer,
+  started_at timestamptz not null default now(),
+  ended_at timestamptz,
+  duration_seconds integer not null default 0,
+  completed boolean not null default false
+);
+
+-- A user tracks one task at a time. Two starts racing each other both pass
+-- the running-session check; this makes the second insert fail.
+create unique index if not exists focus_sessions_one_running_idx
+  on focus_sessions (user_id)
+  where ended_at is null;
+
+create index if not exists focus_sessions_user_started_idx
+  on focus_sessions (user_id, started_at desc);
+
+create index if not exists focus_sessions_user_task_started_idx
+  on focus_sessions (user_id, task_id, started_at desc);
+
+alter table focus_sessions enable row level security;
+
+drop policy if exists "Users manage their own focus sessions" on focus_sessions;
+create policy "Users manage their own focus sessions" on focus_sessions
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
@@ -0,0 +1,30 @@
+-- Board columns and manual task order. Positions are fractional-index keys
+-- ("0-9a-z" digits) compared byte by byte, so they use the C collation;
+-- a locale collation would sort some keys out of order.
+create table if not exists board_columns (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  name text not null,
+  status text,
+  position text collate "C" not null,
+  created_at timestamptz not null default now()
+);
+
+create index if not exists board_columns_user_position_idx
+  on board_columns (user_id, position);
+
+alter table board_columns enable row level security;
+
+drop policy if exists "Users manage their own board columns" on board_columns;
+create policy "Users manage their own board columns" on board_columns
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
+
+-- Deleting a column takes its tasks off the board rather than deleting them
+alter table tasks
+  add column if not exists column_id uuid references board_columns (id) on delete set null,
+  add column if not exists position text collate "C";
+
+create index if not exists tasks_user_column_position_idx
+  on tasks (user_id, column_id, position);
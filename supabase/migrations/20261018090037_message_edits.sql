@@ -0,0 +1,12 @@
+-- Message edits and regenerated replies. A regeneration keeps the replaced
+-- reply as an alternate with superseded_at set; replies link to the user
+-- message they answer.
+alter table messages
+  add column if not exists user_message_id uuid,
+  add column if not exists edited_at timestamptz,
+  add column if not exists superseded_at timestamptz;
+
+-- The replies to a user message, current and replaced
+create index if not exists messages_user_message_idx
+  on messages (user_id, user_message_id)
+  where user_message_id is not null;
@@ -0,0 +1,10 @@
+-- Session forks. A fork links to the session it was forked from and the
+-- last parent message copied into it; forks of a deleted or archived
+-- session are promoted to top-level sessions by the server.
+alter table sessions
+  add column if not exists parent_session_id uuid references sessions (id) on delete set null,
+  add column if not exists forked_from_message_id uuid;
+
+create index if not exists sessions_parent_session_id_idx
+  on sessions (parent_session_id)
+  where parent_session_id is not null;
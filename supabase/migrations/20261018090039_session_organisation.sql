@@ -0,0 +1,14 @@
+-- Pinning, archiving and tagging sessions
+alter table sessions
+  add column if not exists pinned boolean not null default false,
+  add column if not exists archived_at timestamptz,
+  add column if not exists tags text[] not null default '{}';
+
+-- The session list: pinned first, then newest first
+create index if not exists sessions_user_listing_idx
+  on sessions (user_id, pinned desc, created_at desc, id desc)
+  where deleted_at is null;
+
+-- Tag filters, which match sessions carrying every listed tag
+create index if not exists sessions_tags_idx
+  on sessions using gin (tags);
@@ -0,0 +1,32 @@
+-- Account export and import jobs. Export archives themselves are kept in
+-- the account-exports storage bucket at <user_id>/<job_id>.zip.
+create table if not exists data_jobs (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  kind text not null check (kind in ('export', 'import')),
+  status text not null default 'pending'
+    check (status in ('pending', 'running', 'completed', 'failed', 'expired')),
+  error text,
+  size_bytes bigint,
+  sha256 text,
+  counts jsonb,
+  created_at timestamptz not null default now(),
+  completed_at timestamptz,
+  expires_at timestamptz
+);
+
+create index if not exists data_jobs_user_created_idx
+  on data_jobs (user_id, created_at desc);
+
+-- The cleanup of exports past their retention
+create index if not exists data_jobs_expiring_exports_idx
+  on data_jobs (expires_at)
+  where kind = 'export' and status = 'completed';
+
+alter table data_jobs enable row level security;
+
+drop policy if exists "Users manage their own data jobs" on data_jobs;
+create policy "Users manage their own data jobs" on data_jobs
+  for all
+  using (auth.uid() = user_id)
+  with check (auth.uid() = user_id);
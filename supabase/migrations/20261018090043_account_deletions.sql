@@ -0,0 +1,46 @@
+-- Account deletion requests. The record outlives the account to hold the
+-- signed receipt, so user_id has no foreign key to auth.users: the purge
+-- deletes the login itself. The purge runs with the service key; users can
+-- only request a deletion and cancel one still scheduled.
+create table if not exists account_deletions (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null,
+  status text not null default 'scheduled'
+    check (status in ('scheduled', 'cancelled', 'completed')),
+  requested_at timestamptz not null default now(),
+  purge_after timestamptz not null,
+  cancelled_at timestamptz,
+  completed_at timestamptz,
+  receipt jsonb
+);
+
+-- At most one pending deletion per user
+create unique index if not exists account_deletions_one_scheduled_idx
+  on account_deletions (user_id)
+  where status = 'scheduled';
+
+create index if not exists account_deletions_user_requested_idx
+  on account_deletions (user_id, requested_at desc);
+
+-- The purge sweep
+create index if not exists account_deletions_due_idx
+  on account_deletions (purge_after)
+  where status = 'scheduled';
+
+alter table account_deletions enable row level security;
+
+drop policy if exists "Users read their own account deletions" on account_deletions;
+create policy "Users read their own account deletions" on account_deletions
+  for select
+  using (auth.uid() = user_id);
+
+drop policy if exists "Users request their own account deletion" on account_deletions;
+create policy "Users request their own account deletion" on account_deletions
+  for insert
+  with check (auth.uid() = user_id and status = 'scheduled');
+
+drop policy if exists "Users cancel their own account deletion" on account_deletions;
+create policy "Users cancel their own account deletion" on account_deletions
+  for update
+  using (auth.uid() = user_id and status = 'scheduled')
+  with check (auth.uid() = user_id and status in ('scheduled', 'cancelled'));
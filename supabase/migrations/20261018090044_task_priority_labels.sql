@@ -0,0 +1,13 @@
+-- Task priority (1 highest to 4 lowest, 0 for none) and free-form labels,
+-- set by task imports
+alter table tasks
+  add column if not exists priority integer not null default 0,
+  add column if not exists labels text[] not null default '{}';
+
+alter table tasks drop constraint if exists tasks_priority_check;
+alter table tasks add constraint tasks_priority_check
+  check (priority between 0 and 4);
+
+-- Looking tasks up by label
+create index if not exists tasks_labels_idx
+  on tasks using gin (labels);
@@ -0,0 +1,22 @@
+-- Board positions for several tasks in one statement, used to backfill
+-- tasks that were never positioned and to respace a column whose keys
+-- have grown too long.
+--
+-- input_positions is a JSON array of {"id": "...", "position": "..."}.
+-- Runs as the caller, so row-level security limits it to their tasks.
+-- Returns how many tasks were updated.
+create or replace function set_task_positions(input_user_id uuid, input_positions jsonb)
+returns integer
+language sql
+security invoker
+as $$
+  with updated as (
+    update tasks t
+    set position = p.position
+    from jsonb_to_recordset(input_positions) as p(id uuid, position text)
+    where t.id = p.id
+      and t.user_id = input_user_id
+    returning t.id
+  )
+  select count(*)::integer from updated;
+$$;
@@ -0,0 +1,115 @@
+-- Full-text search over a user's messages, tasks and session summaries.
+
+alter table messages
+  add column if not exists search_vector tsvector
+  generated always as (to_tsvector('english', coalesce(content, ''))) stored;
+
+create index if not exists messages_search_vector_idx
+  on messages using gin (search_vector);
+
+-- Title matches rank above description matches
+alter table tasks
+  add column if not exists search_vector tsvector
+  generated always as (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+  ) stored;
+
+create index if not exists tasks_search_vector_idx
+  on tasks using gin (search_vector);
+
+alter table session_summaries
+  add column if not exists search_vector tsvector
+  generated always as (to_tsvector('english', coalesce(summary, ''))) stored;
+
+create index if not exists session_summaries_search_vector_idx
+  on session_summaries using gin (search_vector);
+
+-- Ranks the matches for input_query (websearch syntax: "phrases", or,
+-- -excluded) among the types listed in input_types and returns the best
+-- input_limit, at most 50. Hidden rows are skipped: replaced replies,
+-- deleted tasks and summaries of deleted sessions.
+--
+-- Snippets mark matches with chr(1) and chr(2) instead of tags, and those
+-- characters are removed from the text first, so the caller can escape
+-- the snippet and then turn the markers into <mark> tags.
+--
+-- Runs as the caller, so row-level security applies on top of the
+-- input_user_id filter.
+create or replace function search_content(
+  input_user_id uuid,
+  input_query text,
+  input_types text[],
+  input_limit integer
+)
+returns table (
+  type text,
+  id uuid,
+  session_id uuid,
+  title text,
+  snippet text,
+  rank real,
+  created_at timestamptz
+)
+language sql
+stable
+security invoker
+as $$
+  with q as (
+    select websearch_to_tsquery('english', input_query) as query
+  ),
+  matches as (
+    select 'message'::text as kind, m.id as row_id, m.session_id as row_session_id,
+           null::text as row_title, m.content as body,
+           ts_rank_cd(m.search_vector, q.query) as score, m.created_at as row_created_at
+    from messages m, q
+    where 'message' = any(input_types)
+      and m.user_id = input_user_id
+      and m.superseded_at is null
+      and m.search_vector @@ q.query
+
+    union all
+
+    select 'task', t.id, t.session_id,
+           t.title, concat_ws(' - ', t.title, nullif(t.description, '')),
+           ts_rank_cd(t.search_vector, q.query), t.created_at
+    from tasks t, q
+    where 'task' = any(input_types)
+      and t.user_id = input_user_id
+      and t.deleted_at is null
+      and t.search_vector @@ q.query
+
+    union all
+
+    select 'summary', s.session_id, s.session_id,
+           se.title, s.summary,
+           ts_rank_cd(s.search_vector, q.query), s.last_updated
+    from session_summaries s
+    join sessions se on se.id = s.session_id, q
+    where 'summary' = any(input_types)
+      and s.user_id = input_user_id
+      and se.deleted_at is null
+      and s.search_vector @@ q.query
+  ),
+  best as (
+    select *
+    from matches
+    order by score desc, row_created_at desc
+    limit least(greatest(input_limit, 1), 50)
+  )
+  -- Headlines are costly, so only the returned rows get one
+  select best.kind,
+         best.row_id,
+         best.row_session_id,
+         best.row_title,
+         ts_headline(
+           'english',
+           translate(best.body, chr(1) || chr(2), ''),
+           q.query,
+           'StartSel=' || chr(1) || ', StopSel=' || chr(2) || ', MaxWords=35, MinWords=15, MaxFragments=2'
+         ),
+         best.score,
+         best.row_created_at
+  from best, q
+  order by best.score desc, best.row_created_at desc;
+$$;
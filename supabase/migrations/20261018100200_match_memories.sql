@@ -0,0 +1,80 @@
+-- Long-term memories, ranked by embedding similarity with pgvector.
+
+create extension if not exists vector;
+
+create table if not exists memories (
+  id uuid primary key default gen_random_uuid(),
+  user_id uuid not null references auth.users (id) on delete cascade,
+  content text not null,
+  embedding vector not null,
+  embedder text not null,
+  source_session_id uuid,
+  source_message_id uuid,
+  created_at timestamptz not null default now()
+);
+
+-- Tables created before this migration stored embeddings as JSON or real
+-- arrays
+do $$
+declare
+  current_type text;
+begin
+  select udt_name into current_type
+  from information_schema.columns
+  where table_schema = 'public' and table_name = 'memories' and column_name = 'embedding';
+
+  if current_type in ('json', 'jsonb') then
+    alter table memories alter column embedding type vector using embedding::text::vector;
+  elsif current_type = '_float4' then
+    alter table memories alter column embedding type vector using embedding::vector;
+  end if;
+end;
+$$;
+
+-- Each user has at most a few thousand memories, so an exact scan of
+-- theirs beats an approximate index that would filter after the search.
+-- The column has no fixed dimension because the embedder can change;
+-- vectors are only compared within one embedder.
+create index if not exists memories_user_embedder_idx
+  on memories (user_id, embedder, created_at desc);
+
+-- Returns up to input_limit (at most 50) of the user's memories from
+-- input_embedder whose cosine similarity to input_embedding is at least
+-- input_min_similarity, most similar first. The embeddings themselves are
+-- not returned. Runs as the caller, so row-level security applies.
+create or replace function match_memories(
+  input_user_id uuid,
+  input_embedding vector,
+  input_embedder text,
+  input_min_similarity double precision,
+  input_limit integer
+)
+returns table (
+  id uuid,
+  user_id uuid,
+  content text,
+  embedder text,
+  source_session_id uuid,
+  source_message_id uuid,
+  created_at timestamptz,
+  similarity double precision
+)
+language sql
+stable
+security invoker
+as $$
+  select m.id,
+         m.user_id,
+         m.content,
+         m.embedder,
+         m.source_session_id,
+         m.source_message_id,
+         m.created_at,
+         1 - (m.embedding <=> input_embedding) as similarity
+  from memories m
+  where m.user_id = input_user_id
+    and m.embedder = input_embedder
+    and 1 - (m.embedding <=> input_embedding) >= input_min_similarity
+  order by m.embedding <=> input_embedding
+  limit least(greatest(input_limit, 1), 50);
+$$;
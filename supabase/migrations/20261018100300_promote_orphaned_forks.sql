@@ -0,0 +1,8 @@
+-- Forks of deleted or archived sessions are now promoted to top-level
+-- sessions when their parent goes away. This promotes the ones orphaned
+-- before that.
+update sessions c
+set parent_session_id = null
+from sessions p
+where c.parent_session_id = p.id
+  and (p.deleted_at is not null or p.archived_at is not null);
@@ -0,0 +1,27 @@
+-- Lifecycle columns for sessions. Sessions created before the lifecycle
+-- had no state or last activity, so the sweep never matched them: they are
+-- backfilled as active (or closed, if they have a closed_at) and last
+-- active when created, and the columns can no longer be null.
+
+alter table sessions
+  add column if not exists state text,
+  add column if not exists last_active_at timestamptz,
+  add column if not exists closed_at timestamptz;
+
+update sessions
+set state = case when closed_at is not null then 'closed' else 'active' end
+where state is null;
+
+update sessions
+set last_active_at = created_at
+where last_active_at is null;
+
+alter table sessions
+  alter column state set default 'active',
+  alter column state set not null,
+  alter column last_active_at set default now(),
+  alter column last_active_at set not null;
+
+create index if not exists sessions_open_last_active_idx
+  on sessions (last_active_at)
+  where state <> 'closed' and deleted_at is null;
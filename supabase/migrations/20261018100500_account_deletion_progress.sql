@@ -0,0 +1,4 @@
+-- What an account purge has erased so far, so a purge that fails part way
+-- and is retried still reports the full counts in its receipt.
+alter table account_deletions
+  add column if not exists progress jsonb;
@@ -0,0 +1,11 @@
+-- Message signals are written with compare-and-swap on this counter, so
+-- two messages classified at the same time can't overwrite each other's
+-- mood and topic scores. mood_scores decays older messages, topic_counts
+-- counts user messages per topic, and last_message_at with
+-- avg_message_gap_seconds track the pace of the conversation.
+alter table session_metrics
+  add column if not exists signals_version integer not null default 0,
+  add column if not exists mood_scores jsonb,
+  add column if not exists topic_counts jsonb,
+  add column if not exists avg_message_gap_seconds double precision not null default 0,
+  add column if not exists last_message_at timestamptz;
@@ -0,0 +1,10 @@
+-- The prompt version each reply, summary and pattern analysis was
+-- generated with. User messages leave it null.
+alter table messages
+  add column if not exists prompt_version text;
+
+alter table session_summaries
+  add column if not exists prompt_version text;
+
+alter table user_patterns
+  add column if not exists prompt_version text;
@@ -0,0 +1,60 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/pagination"
+	"fmt"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// keysetPage limits query to limit+1 rows on the cursor's side of a
+// (created_at, id) ordering. descending lists run newest first; a Before
+// cursor scans the other way, and pagination.Trim restores the order.
+func keysetPage(query *postgrest.FilterBuilder, cursor pagination.Cursor, descending bool, limit int) *postgrest.FilterBuilder {
+	scanDescending := descending != cursor.Before
+
+	if cursor.Bounded() {
+		query = query.And(keysetAfter(cursor, scanDescending), "")
+	}
+
+	return query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: !scanDescending}).
+		Order("id", &postgrest.OrderOpts{Ascending: !scanDescending}).
+		Limit(limit+1, "")
+}
+
+// pinnedKeysetPage is keysetPage for a newest-first list with pinned rows
+// ahead of the rest, ordered by (pinned, created_at, id). Use
+// pagination.TrimPinned on the result.
+func pinnedKeysetPage(query *postgrest.FilterBuilder, cursor pagination.Cursor, limit int) *postgrest.FilterBuilder {
+	scanDescending := !cursor.Before
+
+	if cursor.Bounded() {
+		within := fmt.Sprintf("and(pinned.eq.%t,%s)", cursor.Pinned, keysetAfter(cursor, scanDescending))
+		// Scanning down from a pinned row, or up from an unpinned one,
+		// runs on into the other group
+		if cursor.Pinned == scanDescending {
+			query = query.And(fmt.Sprintf("or(pinned.eq.%t,%s)", !cursor.Pinned, within), "")
+		} else {
+			query = query.And(within, "")
+		}
+	}
+
+	return query.
+		Order("pinned", &postgrest.OrderOpts{Ascending: !scanDescending}).
+		Order("created_at", &postgrest.OrderOpts{Ascending: !scanDescending}).
+		Order("id", &postgrest.OrderOpts{Ascending: !scanDescending}).
+		Limit(limit+1, "")
+}
+
+// keysetAfter is the filter for rows past the cursor's (created_at, id) in
+// scan order
+func keysetAfter(cursor pagination.Cursor, scanDescending bool) string {
+	op := "gt"
+	if scanDescending {
+		op = "lt"
+	}
+	at := fmt.Sprintf("%q", cursor.CreatedAt.UTC().Format(time.RFC3339Nano))
+	return fmt.Sprintf("or(created_at.%s.%s,and(created_at.eq.%s,id.%s.%s))", op, at, at, op, cursor.ID)
+}
@@ -1,14 +1,25 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/llm"
+	"clementus360/ai-helper/patterns"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
 )
 
+const (
+	maxPatternActivities = 2000 // activities read per analysis
+	maxPatternSummaries  = 10   // session summaries shown to the model
+	maxPatternAnalyses   = 20   // users analyzed per run, each costing a model call
+)
+
 // Get user patterns (cached insights)
 func GetUserPatterns(client *supabase.Client, userID string) (types.UserPatterns, error) {
 	resp, _, err := client.From("user_patterns").
@@ -37,6 +48,9 @@ func GetUserPatterns(client *supabase.Client, userID string) (types.UserPatterns
 func UpdateUserPatterns(client *supabase.Client, userID string, patterns types.UserPatterns) error {
 	patterns.UserID = userID
 	patterns.UpdatedAt = time.Now()
+	if patterns.CreatedAt.IsZero() {
+		patterns.CreatedAt = patterns.UpdatedAt
+	}
 
 	// Upsert patterns
 	_, _, err := client.From("user_patterns").
@@ -49,3 +63,123 @@ func UpdateUserPatterns(client *supabase.Client, userID string, patterns types.U
 
 	return nil
 }
+
+// AnalyzeUserPatterns recomputes a user's patterns from their recent
+// activity and session summaries. Struggles and strategies are kept from
+// the last analysis when the model can't be reached.
+func AnalyzeUserPatterns(client *supabase.Client, userID string, now time.Time) (types.UserPatterns, error) {
+	since := now.Add(-config.PatternLookback)
+	activities, err := GetUserActivities(client, userID, since, maxPatternActivities)
+	if err != nil {
+		return types.UserPatterns{}, err
+	}
+	previous, err := GetUserPatterns(client, userID)
+	if err != nil {
+		return types.UserPatterns{}, err
+	}
+	profile, err := GetUserProfile(client, userID)
+	if err != nil {
+		log.Printf("Warning: could not fetch user profile: %v", err)
+	}
+
+	updated := previous
+	computed := patterns.Analyze(activities, ProfileLocation(profile))
+	if computed.PreferredResponseStyle != "" {
+		updated.PreferredResponseStyle = computed.PreferredResponseStyle
+	}
+	updated.TimePreferences = computed.TimePreferences
+	updated.TaskCompletionRates = computed.TaskCompletionRates
+
+	summaries, err := recentSummaries(client, userID, since)
+	if err != nil {
+		log.Printf("Warning: could not fetch session summaries: %v", err)
+	}
+	// One session isn't enough to call anything a pattern
+	if len(summaries) >= 2 {
+		analysis, err := llm.AnalyzeStrugglesAndStrategies(summaries, previous)
+		if err != nil {
+			log.Printf("Warning: pattern analysis kept previous struggles and strategies: %v", err)
+		} else {
+			updated.CommonStruggles = analysis.Struggles
+			updated.SuccessfulStrategies = analysis.Strategies
+			updated.PromptVersion = analysis.PromptVersion
+		}
+	}
+
+	updated.LastAnalyzed = now
+	if err := UpdateUserPatterns(client, userID, updated); err != nil {
+		return types.UserPatterns{}, err
+	}
+	return updated, nil
+}
+
+// AnalyzeDuePatterns refreshes the patterns of recently active users whose
+// last analysis is older than config.PatternRefreshAfter. It needs the
+// service client, since it reads and writes every user's rows.
+func AnalyzeDuePatterns(client *supabase.Client, now time.Time) error {
+	cutoff := now.Add(-config.PatternRefreshAfter)
+	resp, _, err := client.From("sessions").
+		Select("user_id", "", false).
+		Gte("last_active_at", cutoff.UTC().Format(time.RFC3339Nano)).
+		Is("deleted_at", "null").
+		Order("last_active_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(500, "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to fetch active users: %w", err)
+	}
+	var sessions []types.Session
+	if err := json.Unmarshal(resp, &sessions); err != nil {
+		return fmt.Errorf("failed to decode active users: %w", err)
+	}
+
+	seen := map[string]bool{}
+	analyzed := 0
+	for _, s := range sessions {
+		if seen[s.UserID] || analyzed == maxPatternAnalyses {
+			continue
+		}
+		seen[s.UserID] = true
+
+		existing, err := GetUserPatterns(client, s.UserID)
+		if err != nil {
+			log.Printf("Failed to fetch patterns for %s: %v", s.UserID, err)
+			continue
+		}
+		if existing.LastAnalyzed.After(cutoff) {
+			continue
+		}
+		analyzed++
+		if _, err := AnalyzeUserPatterns(client, s.UserID, now); err != nil {
+			log.Printf("Failed to analyze patterns for %s: %v", s.UserID, err)
+		}
+	}
+	return nil
+}
+
+// recentSummaries returns the user's session summaries updated since the
+// given time, newest first
+func recentSummaries(client *supabase.Client, userID string, since time.Time) ([]string, error) {
+	resp, _, err := client.From("session_summaries").
+		Select("summary", "", false).
+		Eq("user_id", userID).
+		Gte("last_updated", since.UTC().Format(time.RFC3339Nano)).
+		Order("last_updated", &postgrest.OrderOpts{Ascending: false}).
+		Limit(maxPatternSummaries, "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch session summaries: %w", err)
+	}
+	var rows []types.SessionSummary
+	if err := json.Unmarshal(resp, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode session summaries: %w", err)
+	}
+
+	summaries := make([]string, 0, len(rows))
+	for _, r := range rows {
+		if r.Summary != "" {
+			summaries = append(summaries, r.Summary)
+		}
+	}
+	return summaries, nil
+}
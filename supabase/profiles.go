@@ -0,0 +1,126 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+// Profile defaults for users who never saved one
+const (
+	DefaultTimezone     = "UTC"
+	DefaultLocale       = "en-US"
+	DefaultWorkdayStart = "09:00"
+	DefaultWorkdayEnd   = "17:00"
+)
+
+// GetUserProfile returns the user's profile, or the defaults if none is stored
+func GetUserProfile(client *supabase.Client, userID string) (types.UserProfile, error) {
+	defaults := types.UserProfile{
+		UserID:       userID,
+		Timezone:     DefaultTimezone,
+		Locale:       DefaultLocale,
+		WorkdayStart: DefaultWorkdayStart,
+		WorkdayEnd:   DefaultWorkdayEnd,
+	}
+
+	resp, _, err := client.From("user_profiles").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return defaults, fmt.Errorf("failed to fetch user profile: %w", err)
+	}
+
+	var profiles []types.UserProfile
+	if err := json.Unmarshal(resp, &profiles); err != nil {
+		return defaults, fmt.Errorf("failed to unmarshal user profile: %w", err)
+	}
+	if len(profiles) == 0 {
+		return defaults, nil
+	}
+
+	profile := profiles[0]
+	if profile.Timezone == "" {
+		profile.Timezone = DefaultTimezone
+	}
+	if profile.Locale == "" {
+		profile.Locale = DefaultLocale
+	}
+	if _, err := dates.ParseWorkingHours(profile.WorkdayStart, profile.WorkdayEnd); err != nil {
+		profile.WorkdayStart, profile.WorkdayEnd = DefaultWorkdayStart, DefaultWorkdayEnd
+	}
+	return profile, nil
+}
+
+// ValidateUserProfile checks the profile's timezone and working hours
+func ValidateUserProfile(profile types.UserProfile) error {
+	if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "" {
+		return fmt.Errorf("unknown timezone %q", profile.Timezone)
+	}
+	if _, err := dates.ParseWorkingHours(profile.WorkdayStart, profile.WorkdayEnd); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpsertUserProfile validates and saves the user's profile
+func UpsertUserProfile(client *supabase.Client, userID string, profile types.UserProfile) (types.UserProfile, error) {
+	if err := ValidateUserProfile(profile); err != nil {
+		return types.UserProfile{}, err
+	}
+
+	now := time.Now()
+	profile.UserID = userID
+	profile.UpdatedAt = &now
+	profile.CreatedAt = nil // keep the stored creation time
+
+	resp, _, err := client.From("user_profiles").
+		Upsert(profile, "user_id", "", "").
+		Execute()
+	if err != nil {
+		return types.UserProfile{}, fmt.Errorf("failed to save user profile: %w", err)
+	}
+
+	var saved []types.UserProfile
+	if err := json.Unmarshal(resp, &saved); err != nil || len(saved) == 0 {
+		return profile, nil
+	}
+	return saved[0], nil
+}
+
+// ProfileLocation returns the profile's timezone, falling back to UTC
+func ProfileLocation(profile types.UserProfile) *time.Location {
+	return dates.LoadLocation(profile.Timezone)
+}
+
+// ProfileWorkingHours returns the profile's working hours, falling back to the defaults
+func ProfileWorkingHours(profile types.UserProfile) dates.WorkingHours {
+	hours, err := dates.ParseWorkingHours(profile.WorkdayStart, profile.WorkdayEnd)
+	if err != nil {
+		hours, _ = dates.ParseWorkingHours(DefaultWorkdayStart, DefaultWorkdayEnd)
+	}
+	return hours
+}
+
+// ProfileDueDateOptions returns due-date parsing options for the profile:
+// its timezone, with day-only phrases resolving to the end of the workday
+func ProfileDueDateOptions(profile types.UserProfile) dates.Options {
+	hours := ProfileWorkingHours(profile)
+	return dates.Options{
+		Location:      ProfileLocation(profile),
+		DefaultHour:   hours.EndHour,
+		DefaultMinute: hours.EndMinute,
+	}
+}
+
+// followUpDueAt schedules a follow-up 48 hours after from, moved into the
+// user's working hours so check-ins don't land in the middle of the night
+func followUpDueAt(profile types.UserProfile, from time.Time) time.Time {
+	due := from.Add(48 * time.Hour).In(ProfileLocation(profile))
+	return ProfileWorkingHours(profile).Clamp(due)
+}
@@ -0,0 +1,314 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// untrackedTaskFields are identity fields that never change meaningfully
+var untrackedTaskFields = map[string]bool{"id": true, "user_id": true, "created_at": true}
+
+// RecordTaskRevision stores a field-level diff between before and after.
+// A nil before records a creation and a nil after records a deletion.
+// messageID links AI changes to the message that caused them and may be empty.
+func RecordTaskRevision(client *supabase.Client, userID string, before, after *types.Task, actorType, messageID string) error {
+	return RecordTaskRevisions(client, userID, []TaskChange{{Before: before, After: after}}, actorType, messageID)
+}
+
+// TaskChange is one task's state before and after a change
+type TaskChange struct {
+	Before, After *types.Task
+}
+
+// RecordTaskRevisions stores the revisions for several changes in one insert
+func RecordTaskRevisions(client *supabase.Client, userID string, changes []TaskChange, actorType, messageID string) error {
+	var revisions []types.TaskRevision
+	for _, change := range changes {
+		revision, err := newTaskRevision(userID, change.Before, change.After, actorType, messageID)
+		if err != nil {
+			return err
+		}
+		if revision != nil {
+			revisions = append(revisions, *revision)
+		}
+	}
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	_, _, err := client.From("task_revisions").Insert(revisions, false, "", "", "").Execute()
+	if err != nil {
+		return fmt.Errorf("failed to record task revision: %w", err)
+	}
+	return nil
+}
+
+// TaskCreations returns the changes that record tasks being created
+func TaskCreations(tasks []types.Task) []TaskChange {
+	changes := make([]TaskChange, len(tasks))
+	for i := range tasks {
+		changes[i] = TaskChange{After: &tasks[i]}
+	}
+	return changes
+}
+
+// newTaskRevision builds the revision for one change, or returns nil when
+// an update changed nothing
+func newTaskRevision(userID string, before, after *types.Task, actorType, messageID string) (*types.TaskRevision, error) {
+	revision := types.TaskRevision{
+		UserID:    userID,
+		ActorType: actorType,
+		CreatedAt: time.Now(),
+	}
+
+	switch {
+	case before == nil && after == nil:
+		return nil, fmt.Errorf("revision needs a before or after state")
+	case before == nil:
+		revision.Action = "created"
+		revision.TaskID = after.ID
+	case after == nil:
+		revision.Action = "deleted"
+		revision.TaskID = before.ID
+	default:
+		revision.Action = "updated"
+		revision.TaskID = after.ID
+	}
+	if messageID != "" {
+		revision.MessageID = &messageID
+	}
+
+	changes, err := diffTasks(before, after)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Action == "updated" && len(changes) == 0 {
+		return nil, nil // nothing actually changed
+	}
+	revision.Changes = changes
+	return &revision, nil
+}
+
+// GetTaskRevisions returns a task's revisions, oldest first
+func GetTaskRevisions(client *supabase.Client, userID, taskID string) ([]types.TaskRevision, error) {
+	resp, _, err := client.From("task_revisions").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("task_id", taskID).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch task revisions: %w", err)
+	}
+
+	var revisions []types.TaskRevision
+	if err := json.Unmarshal(resp, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode task revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// diffTasks compares the JSON form of two task states field by field
+func diffTasks(before, after *types.Task) (map[string]types.FieldChange, error) {
+	from, err := taskFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := taskFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// A deletion keeps created_at so reverting it restores the original
+	deletion := before != nil && after == nil
+
+	changes := map[string]types.FieldChange{}
+	for field := range mergeKeys(from, to) {
+		if untrackedTaskFields[field] && !(deletion && field == "created_at") {
+			continue
+		}
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes[field] = types.FieldChange{From: from[field], To: to[field]}
+		}
+	}
+	return changes, nil
+}
+
+func taskFields(task *types.Task) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if task == nil {
+		return fields, nil
+	}
+	raw, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func mergeKeys(a, b map[string]interface{}) map[string]bool {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+// GetMessageRevisions returns the task revisions the assistant made in
+// messages, newest first. Undoing them is recorded under the same messages
+// as system revisions, which are left out.
+func GetMessageRevisions(client *supabase.Client, userID string, messageIDs []string) ([]types.TaskRevision, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	resp, _, err := client.From("task_revisions").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		In("message_id", messageIDs).
+		Eq("actor_type", config.RevisionActorAI).
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message revisions: %w", err)
+	}
+
+	var revisions []types.TaskRevision
+	if err := json.Unmarshal(resp, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode message revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// RevertTaskRevisions undoes revisions, which must be newest first: created
+// tasks are deleted, updated fields get their previous values back and
+// deleted tasks are restored. Each undo is recorded as a system revision
+// linked to the same message. It returns the IDs of the tasks it changed
+// and stops at the first failure.
+func RevertTaskRevisions(client *supabase.Client, userID string, revisions []types.TaskRevision) ([]string, error) {
+	var reverted []string
+	for _, rev := range revisions {
+		found, err := GetSingleTask(client, userID, rev.TaskID)
+		if err != nil {
+			return reverted, err
+		}
+		var current *types.Task
+		if len(found) > 0 {
+			current = &found[0]
+		}
+
+		before, err := RevertTask(rev, current)
+		if err != nil {
+			return reverted, err
+		}
+		switch {
+		case before == nil && current == nil:
+			continue // already gone
+		case before == nil:
+			err = DeleteTask(client, rev.TaskID, userID)
+		case current == nil:
+			err = restoreTask(client, *before)
+		default:
+			previous := map[string]interface{}{}
+			for field, change := range rev.Changes {
+				previous[field] = change.From
+			}
+			var restored types.Task
+			if restored, err = UpdateTask(client, rev.TaskID, userID, previous); err == nil {
+				before = &restored
+			}
+		}
+		if err != nil {
+			return reverted, fmt.Errorf("failed to revert task %s: %w", rev.TaskID, err)
+		}
+		reverted = append(reverted, rev.TaskID)
+
+		messageID := ""
+		if rev.MessageID != nil {
+			messageID = *rev.MessageID
+		}
+		if err := RecordTaskRevision(client, userID, current, before, config.RevisionActorSystem, messageID); err != nil {
+			log.Printf("Warning: failed to record task revision: %v", err)
+		}
+	}
+	return reverted, nil
+}
+
+// RevertTasks applies the undo of revisions (newest first) to tasks in
+// memory, the way RevertTaskRevisions would in the database
+func RevertTasks(tasks []types.Task, revisions []types.TaskRevision) []types.Task {
+	reverted := slices.Clone(tasks)
+	for _, rev := range revisions {
+		i := slices.IndexFunc(reverted, func(t types.Task) bool { return t.ID == rev.TaskID })
+		var current *types.Task
+		if i >= 0 {
+			current = &reverted[i]
+		}
+		before, err := RevertTask(rev, current)
+		switch {
+		case err != nil:
+			continue
+		case before == nil && i >= 0:
+			reverted = slices.Delete(reverted, i, i+1)
+		case before != nil && i >= 0:
+			reverted[i] = *before
+		case before != nil:
+			reverted = append(reverted, *before)
+		}
+	}
+	return reverted
+}
+
+// RevertTask returns the task as it was before rev, given its current
+// state, or nil if it didn't exist. current is nil for a task that no
+// longer exists.
+func RevertTask(rev types.TaskRevision, current *types.Task) (*types.Task, error) {
+	var fields map[string]interface{}
+	switch rev.Action {
+	case "created":
+		return nil, nil
+	case "updated":
+		if current == nil {
+			return nil, nil // deleted since, so there's nothing to revert
+		}
+		var err error
+		if fields, err = taskFields(current); err != nil {
+			return nil, err
+		}
+	case "deleted":
+		fields = map[string]interface{}{"id": rev.TaskID, "user_id": rev.UserID}
+	default:
+		return nil, fmt.Errorf("unknown revision action %q", rev.Action)
+	}
+
+	for field, change := range rev.Changes {
+		fields[field] = change.From
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var before types.Task
+	if err := json.Unmarshal(raw, &before); err != nil {
+		return nil, err
+	}
+	if before.CreatedAt.IsZero() {
+		before.CreatedAt = time.Now() // deletions recorded before created_at was kept
+	}
+	return &before, nil
+}
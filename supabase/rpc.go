@@ -0,0 +1,35 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+// callRPC calls a Postgres function and decodes its result into out, which
+// may be nil. Rpc reports neither transport errors nor HTTP statuses, only
+// the response body, so an empty body or a PostgREST error object is
+// returned as an error. Functions called this way must return a value.
+func callRPC(client *supabase.Client, name string, args map[string]interface{}, out interface{}) error {
+	body := client.Rpc(name, "", args)
+	if body == "" {
+		return fmt.Errorf("%s: no response from the database", name)
+	}
+
+	var rpcErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &rpcErr) == nil && rpcErr.Message != "" {
+		return fmt.Errorf("%s: %s (%s)", name, rpcErr.Message, rpcErr.Code)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(body), out); err != nil {
+		return fmt.Errorf("%s: failed to decode result: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/types"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+// MaxSearchResults caps a single search
+const MaxSearchResults = 50
+
+// search_content marks the matches in a snippet with these control
+// characters rather than tags, so the text around them can be escaped
+const (
+	snippetStart = "\x01"
+	snippetStop  = "\x02"
+)
+
+// Search runs a ranked full-text search over the user's messages, tasks and
+// session summaries. It calls the search_content Postgres function (see
+// migrations), which matches websearch_to_tsquery(query) against tsvector
+// columns on each table, ranks with ts_rank_cd and builds snippets with
+// ts_headline. searchTypes limits the result types; empty means all.
+func Search(client *supabase.Client, userID, query string, searchTypes []string, limit int) ([]types.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("missing search query")
+	}
+	if limit < 1 || limit > MaxSearchResults {
+		limit = MaxSearchResults
+	}
+	if len(searchTypes) == 0 {
+		searchTypes = []string{types.SearchTypeMessage, types.SearchTypeTask, types.SearchTypeSummary}
+	}
+
+	var results []types.SearchResult
+	if err := callRPC(client, "search_content", map[string]interface{}{
+		"input_user_id": userID,
+		"input_query":   query,
+		"input_types":   searchTypes,
+		"input_limit":   limit,
+	}, &results); err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+	return results, nil
+}
+
+// highlightSnippet escapes a snippet as HTML and turns the match markers
+// into <mark> tags, so <mark> is the only markup in it
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(snippetStart, "<mark>", snippetStop, "</mark>").Replace(snippet)
+}
+
+// quoteFilterValue quotes user input for use inside a PostgREST logical
+// filter such as or=(...), so commas, dots and parentheses can't change it
+func quoteFilterValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
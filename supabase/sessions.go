@@ -1,13 +1,18 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/config"
 	"clementus360/ai-helper/llm"
+	"clementus360/ai-helper/pagination"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
 	"log"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
@@ -18,40 +23,63 @@ const (
 	SUMMARY_UPDATE_THRESHOLD = 20
 )
 
-// GetOrCreateActiveSession returns recent session ID or creates a new session
-func GetOrCreateActiveSession(client *supabase.Client, userID string, forceNew bool) (string, error) {
-	cutoff := time.Now().Add(-24 * time.Hour)
-	var sessions []types.Session
+const (
+	MaxSessionTags   = 8
+	MaxSessionTagLen = 32
+)
 
+// GetOrCreateActiveSession returns the user's most recently active open
+// session, or starts a new one if there is none or forceNew is set. Open
+// sessions past the inactivity timeout are closed on the way, as are all
+// of them when forceNew asks for a fresh start.
+func GetOrCreateActiveSession(client *supabase.Client, userID string, profile types.UserProfile, forceNew bool) (string, error) {
+	now := time.Now().In(ProfileLocation(profile))
+
+	var sessions []types.Session
 	resp, _, err := client.From("sessions").
-		Select("id, user_id, title, created_at", "", false).
+		Select("*", "", false).
 		Eq("user_id", userID).
-		Gte("created_at", cutoff.Format(time.RFC3339)).
-		Order("created_at", nil).
-		Limit(1, "").
+		In("state", []string{types.SessionStateActive, types.SessionStateIdle}).
+		Is("parent_session_id", "null"). // forks are only continued explicitly
+		Is("archived_at", "null").
+		Is("deleted_at", "null").
+		Order("last_active_at", &postgrest.OrderOpts{Ascending: false}).
 		Execute()
-
 	if err != nil {
 		return "", err
 	}
-
 	if err := json.Unmarshal(resp, &sessions); err != nil {
 		return "", err
 	}
 
-	// If forceNew is false and we have recent sessions, return the first one
-	// This allows us to reuse existing sessions without creating new ones
-	// unless the user explicitly requests a new session
-	if !forceNew {
-		if len(sessions) > 0 {
-			return sessions[0].ID, nil
+	var current *types.Session
+	var stale []string
+	for i := range sessions {
+		s := &sessions[i]
+		fresh := s.LastActiveAt != nil && now.Sub(*s.LastActiveAt) < config.SessionCloseAfter
+		if fresh && !forceNew {
+			if current == nil {
+				current = s
+			}
+			continue
+		}
+		stale = append(stale, s.ID)
+	}
+	closeAndSummarize(client, userID, stale)
+
+	if current != nil {
+		if err := TouchSession(client, userID, current.ID); err != nil {
+			log.Printf("Failed to mark session %s active: %v", current.ID, err)
 		}
+		return current.ID, nil
 	}
 
 	// Create new session
 	newSession := types.Session{
-		UserID: userID,
-		Title:  time.Now().Format("Jan 2, 3:04PM"),
+		UserID:       userID,
+		Title:        now.Format("Jan 2, 3:04PM"),
+		State:        types.SessionStateActive,
+		LastActiveAt: &now,
 		// Do NOT set CreatedAt
 	}
 
@@ -107,17 +135,43 @@ func GetSessionContext(client *supabase.Client, sessionID, userID string) (types
 
 // UpdateSessionSummaryIfNeeded checks whether a summary update is needed
 func UpdateSessionSummaryIfNeeded(client *supabase.Client, sessionID, userID string) error {
+	newMessages, err := messagesSinceSummary(client, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if newMessages < SUMMARY_UPDATE_THRESHOLD {
+		return nil
+	}
+	return summarizeSession(client, sessionID, userID, 5)
+}
+
+// SummarizeSession brings a session's summary, title and tags up to date
+// with its conversation, if anything was said since the last summary
+func SummarizeSession(client *supabase.Client, sessionID, userID string) error {
+	newMessages, err := messagesSinceSummary(client, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if newMessages == 0 {
+		return nil
+	}
+	return summarizeSession(client, sessionID, userID, 2)
+}
+
+// messagesSinceSummary counts the messages added since the session was last
+// summarized
+func messagesSinceSummary(client *supabase.Client, sessionID, userID string) (int, error) {
 	// Get last summary update
 	summaryResp, _, err := client.From("session_summaries").
 		Select("last_updated", "", false).
 		Eq("session_id", sessionID).
 		Execute()
 	if err != nil {
-		return fmt.Errorf("failed to fetch session summaries: %w", err)
+		return 0, fmt.Errorf("failed to fetch session summaries: %w", err)
 	}
 	var summaries []types.SessionSummary
 	if err := json.Unmarshal(summaryResp, &summaries); err != nil {
-		return fmt.Errorf("failed to parse session summaries: %w", err)
+		return 0, fmt.Errorf("failed to parse session summaries: %w", err)
 	}
 
 	var lastUpdate time.Time
@@ -130,24 +184,29 @@ func UpdateSessionSummaryIfNeeded(client *supabase.Client, sessionID, userID str
 		Select("id", "", false).
 		Eq("user_id", userID).
 		Eq("session_id", sessionID).
+		Is("superseded_at", "null").
 		Gt("created_at", lastUpdate.Format(time.RFC3339)).
 		Execute()
 	if err != nil {
-		return fmt.Errorf("failed to count messages: %w", err)
+		return 0, fmt.Errorf("failed to count messages: %w", err)
 	}
 	var newMessages []types.Message
 	if err := json.Unmarshal(countResp, &newMessages); err != nil {
-		return fmt.Errorf("failed to parse messages: %w", err)
-	}
-	if len(newMessages) < SUMMARY_UPDATE_THRESHOLD {
-		return nil
+		return 0, fmt.Errorf("failed to parse messages: %w", err)
 	}
+	return len(newMessages), nil
+}
 
-	// Get all messages for summary
+// summarizeSession regenerates the summary and title from the whole
+// conversation. Conversations shorter than minMessages are left alone.
+func summarizeSession(client *supabase.Client, sessionID, userID string, minMessages int) error {
+	// Get all messages for summary, leaving out replies replaced by a
+	// regeneration
 	allResp, _, err := client.From("messages").
 		Select("sender, content, created_at", "", false).
 		Eq("user_id", userID).
 		Eq("session_id", sessionID).
+		Is("superseded_at", "null").
 		Order("created_at", nil).
 		Execute()
 	if err != nil {
@@ -157,28 +216,33 @@ func UpdateSessionSummaryIfNeeded(client *supabase.Client, sessionID, userID str
 	if err := json.Unmarshal(allResp, &messages); err != nil {
 		return fmt.Errorf("failed to parse messages: %w", err)
 	}
-	if len(messages) < 5 {
+	if len(messages) < minMessages {
 		return nil
 	}
 
 	// Generate smart context
-	smartContext, err := BuildSmartContext(client, sessionID, userID)
+	profile, err := GetUserProfile(client, userID)
+	if err != nil {
+		log.Printf("Failed to fetch user profile, using defaults: %v", err)
+	}
+	smartContext, err := BuildSmartContext(client, sessionID, userID, profile)
 	if err != nil {
 		return fmt.Errorf("failed to build smart context: %w", err)
 	}
 
-	// Generate summary and title
-	summary, title, err := llm.GenerateSessionSummaryAndTitle(messages, smartContext)
+	// Generate summary, title and tags
+	generated, err := llm.GenerateSessionSummaryAndTitle(messages, smartContext)
 	if err != nil {
 		return fmt.Errorf("failed to generate summary and title: %w", err)
 	}
 
 	// Save summary
 	data := types.SessionSummary{
-		SessionID:   sessionID,
-		UserID:      userID,
-		Summary:     summary,
-		LastUpdated: time.Now(),
+		SessionID:     sessionID,
+		UserID:        userID,
+		Summary:       generated.Summary,
+		PromptVersion: generated.PromptVersion,
+		LastUpdated:   time.Now(),
 	}
 	_, _, err = client.From("session_summaries").
 		Upsert(data, "", "", "").
@@ -188,36 +252,111 @@ func UpdateSessionSummaryIfNeeded(client *supabase.Client, sessionID, userID str
 	}
 
 	// Save session title
-	_, err = UpdateSessionTitle(client, sessionID, userID, title)
+	_, err = UpdateSessionTitle(client, sessionID, userID, generated.Title)
 	if err != nil {
 		return fmt.Errorf("failed to update session title: %w", err)
 	}
 
+	// Suggested tags only fill in for sessions the user hasn't tagged
+	if tags := normalizeTags(generated.Tags); len(tags) > 0 {
+		session, err := getSession(client, userID, sessionID)
+		if err != nil {
+			return err
+		}
+		if len(session.Tags) == 0 {
+			_, _, err := client.From("sessions").
+				Update(map[string]interface{}{"tags": tags}, "", "").
+				Eq("id", sessionID).
+				Eq("user_id", userID).
+				Execute()
+			if err != nil {
+				return fmt.Errorf("failed to save suggested tags: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
-func GetSessions(client *supabase.Client, userID string) ([]types.Session, error) {
+// GetSessions returns a page of the user's sessions matching filter, pinned
+// ones first and then newest first. In tree view the page holds only
+// top-level sessions, each with its matching forks nested under Branches.
+func GetSessions(client *supabase.Client, userID string, limit int, cursor pagination.Cursor, filter types.SessionFilter) ([]types.Session, pagination.Page, error) {
 	if userID == "" {
-		return nil, fmt.Errorf("missing user ID")
+		return nil, pagination.Page{}, fmt.Errorf("missing user ID")
 	}
 
 	query := client.From("sessions").
 		Select("*", "", false).
 		Eq("user_id", userID).
-		Is("deleted_at", "null").
-		Order("created_at", &postgrest.OrderOpts{Ascending: false})
+		Is("deleted_at", "null")
+	if filter.Tree {
+		query = query.Is("parent_session_id", "null")
+	}
+	if filter.Pinned != nil {
+		query = query.Eq("pinned", strconv.FormatBool(*filter.Pinned))
+	}
+	switch filter.Archived {
+	case types.SessionArchivedExclude:
+		query = query.Is("archived_at", "null")
+	case types.SessionArchivedOnly:
+		query = query.Not("archived_at", "is", "null")
+	}
+	if tags := normalizeTags(filter.Tags); len(tags) > 0 {
+		query = query.Contains("tags", tags)
+	}
+	if filter.State != "" {
+		query = query.Eq("state", filter.State)
+	}
+	query = pinnedKeysetPage(query, cursor, limit)
 
 	resp, _, err := query.Execute()
 	if err != nil {
-		return nil, err
+		return nil, pagination.Page{}, err
 	}
 
 	var sessions []types.Session
 	if err := json.Unmarshal(resp, &sessions); err != nil {
-		return nil, fmt.Errorf("failed to decode session data: %w", err)
+		return nil, pagination.Page{}, fmt.Errorf("failed to decode session data: %w", err)
 	}
 
-	return sessions, nil
+	sessions, page := pagination.TrimPinned(sessions, cursor, limit, func(s types.Session) (bool, time.Time, string) {
+		if s.CreatedAt == nil {
+			return s.Pinned, time.Time{}, s.ID
+		}
+		return s.Pinned, *s.CreatedAt, s.ID
+	})
+
+	if filter.Tree {
+		if err := attachBranches(client, userID, sessions, filter); err != nil {
+			return nil, pagination.Page{}, err
+		}
+	}
+	return sessions, page, nil
+}
+
+// matchesSessionFilter applies the same tests as GetSessions' query to a
+// session already fetched
+func matchesSessionFilter(s types.Session, filter types.SessionFilter) bool {
+	if filter.Pinned != nil && s.Pinned != *filter.Pinned {
+		return false
+	}
+	switch filter.Archived {
+	case types.SessionArchivedExclude:
+		if s.ArchivedAt != nil {
+			return false
+		}
+	case types.SessionArchivedOnly:
+		if s.ArchivedAt == nil {
+			return false
+		}
+	}
+	for _, tag := range normalizeTags(filter.Tags) {
+		if !slices.Contains(s.Tags, tag) {
+			return false
+		}
+	}
+	return filter.State == "" || s.State == filter.State
 }
 
 func GetSessionSummary(client *supabase.Client, sessionID string) (string, error) {
@@ -265,6 +404,94 @@ func UpdateSessionTitle(client *supabase.Client, sessionID, userID, newTitle str
 	return updated[0], nil
 }
 
+// UpdateSession applies a user's changes to a session's title, pin,
+// archive state or tags
+func UpdateSession(client *supabase.Client, sessionID, userID string, req types.UpdateSessionRequest) (types.Session, error) {
+	payload := map[string]interface{}{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return types.Session{}, fmt.Errorf("title cannot be empty")
+		}
+		payload["title"] = title
+	}
+	if req.Pinned != nil {
+		payload["pinned"] = *req.Pinned
+	}
+	if req.Archived != nil {
+		if *req.Archived {
+			payload["archived_at"] = time.Now().UTC().Format(time.RFC3339)
+		} else {
+			payload["archived_at"] = nil
+		}
+	}
+	if req.Tags != nil {
+		tags := normalizeTags(*req.Tags)
+		if len(tags) > MaxSessionTags {
+			return types.Session{}, fmt.Errorf("a session can have at most %d tags", MaxSessionTags)
+		}
+		payload["tags"] = tags
+	}
+	if len(payload) == 0 {
+		return types.Session{}, fmt.Errorf("empty update payload")
+	}
+
+	resp, _, err := client.From("sessions").
+		Update(payload, "", "").
+		Eq("id", sessionID).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		Execute()
+	if err != nil {
+		return types.Session{}, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	var updated []types.Session
+	if err := json.Unmarshal(resp, &updated); err != nil {
+		return types.Session{}, fmt.Errorf("failed to parse update result: %w", err)
+	}
+	if len(updated) == 0 {
+		return types.Session{}, fmt.Errorf("session not found")
+	}
+
+	// Forks of an archived session become top-level sessions and stay put
+	// if it is unarchived
+	if req.Archived != nil && *req.Archived {
+		if err := promoteBranches(client, userID, sessionID); err != nil {
+			log.Printf("Warning: %v for archived session %s", err, sessionID)
+		}
+	}
+	return updated[0], nil
+}
+
+// normalizeTags lowercases tags, joins words with dashes and drops anything
+// other than letters, digits and dashes, removing blanks and duplicates.
+// Tags longer than MaxSessionTagLen are cut short.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		var b strings.Builder
+		for _, word := range strings.Fields(strings.ToLower(tag)) {
+			if b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			for _, r := range word {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+					b.WriteRune(r)
+				}
+			}
+		}
+		t := strings.Trim(b.String(), "-")
+		if runes := []rune(t); len(runes) > MaxSessionTagLen {
+			t = strings.TrimRight(string(runes[:MaxSessionTagLen]), "-")
+		}
+		if t != "" && !slices.Contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized
+}
+
 // DeleteSession soft deletes a session and all related data
 func DeleteSession(client *supabase.Client, sessionID, userID string) error {
 	if sessionID == "" || userID == "" {
@@ -288,6 +515,11 @@ func DeleteSession(client *supabase.Client, sessionID, userID string) error {
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
+	// Its forks live on as top-level sessions
+	if err := promoteBranches(client, userID, sessionID); err != nil {
+		log.Printf("Warning: %v for deleted session %s", err, sessionID)
+	}
+
 	// Soft delete related messages
 	_, _, err = client.From("messages").
 		Update(map[string]interface{}{
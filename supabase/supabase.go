@@ -13,12 +13,18 @@ import (
 
 var Client *supabase.Client
 
+// ServiceClient uses the service role key and bypasses row-level security.
+// It is for work done on no user's behalf: background jobs, the calendar
+// feed and deletion receipts.
+var ServiceClient *supabase.Client
+
 func Init() {
 	apiURL := os.Getenv("SUPABASE_URL")
 	apiKey := os.Getenv("SUPABASE_KEY")
+	serviceKey := os.Getenv("SUPABASE_SERVICE_KEY")
 
-	if apiURL == "" || apiKey == "" {
-		config.Logger.Fatal("SUPABASE_URL or SUPABASE_KEY is missing")
+	if apiURL == "" || apiKey == "" || serviceKey == "" {
+		config.Logger.Fatal("SUPABASE_URL, SUPABASE_KEY or SUPABASE_SERVICE_KEY is missing")
 	}
 
 	var err error
@@ -26,6 +32,10 @@ func Init() {
 	if err != nil {
 		config.Logger.Fatal("Failed to create Supabase client:", err)
 	}
+	ServiceClient, err = supabase.NewClient(apiURL, serviceKey, &supabase.ClientOptions{})
+	if err != nil {
+		config.Logger.Fatal("Failed to create Supabase service client:", err)
+	}
 }
 
 func SupabaseClientFromRequest(r *http.Request) (*supabase.Client, string, error) {
@@ -0,0 +1,125 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/taskimport"
+	"clementus360/ai-helper/titles"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+const (
+	importPageSize  = 1000
+	importChunkSize = 200
+)
+
+// ImportTasks saves the parsed rows as the user's tasks and reports what
+// happened to each. Rows whose title matches an existing task, or an
+// earlier row, are skipped. A dry run reports without saving.
+func ImportTasks(client *supabase.Client, userID string, profile types.UserProfile, rows []taskimport.Row, dryRun bool) ([]types.TaskImportRow, []types.Task, error) {
+	existing, err := existingTaskTitles(client, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := make([]types.TaskImportRow, len(rows))
+	firstRow := map[string]int{}
+	var pending []types.Task
+	var pendingIdx []int
+	for i, row := range rows {
+		report[i] = types.TaskImportRow{Row: row.Row, Title: row.Task.Title}
+		key := titles.Key(row.Task.Title)
+		switch {
+		case row.Problem != "":
+			report[i].Status = types.TaskImportInvalid
+			report[i].Reason = row.Problem
+		case row.Skip != "":
+			report[i].Status = types.TaskImportSkipped
+			report[i].Reason = row.Skip
+		case existing[key] != "":
+			report[i].Status = types.TaskImportSkipped
+			report[i].Reason = "duplicate of an existing task"
+			report[i].TaskID = existing[key]
+		case firstRow[key] != 0:
+			report[i].Status = types.TaskImportSkipped
+			report[i].Reason = fmt.Sprintf("duplicate of row %d", firstRow[key])
+		default:
+			report[i].Status = types.TaskImportImported
+			firstRow[key] = row.Row
+			task := row.Task
+			task.AISuggested = false
+			pending = append(pending, task)
+			pendingIdx = append(pendingIdx, i)
+		}
+	}
+	if dryRun {
+		return report, nil, nil
+	}
+
+	var saved []types.Task
+	for start := 0; start < len(pending); start += importChunkSize {
+		end := min(start+importChunkSize, len(pending))
+		chunk, err := InsertTasks(client, userID, profile, pending[start:end])
+		if err != nil {
+			// Earlier chunks are already saved, so report them and mark the rest
+			for _, idx := range pendingIdx[start:] {
+				report[idx].Status = types.TaskImportFailed
+				report[idx].Reason = "failed to save"
+			}
+			log.Printf("Warning: task import stopped after %d tasks: %v", len(saved), err)
+			break
+		}
+		if err := RecordTaskRevisions(client, userID, TaskCreations(chunk), config.RevisionActorUser, ""); err != nil {
+			log.Printf("Warning: failed to record task revisions: %v", err)
+		}
+		for j := range chunk {
+			report[pendingIdx[start+j]].TaskID = chunk[j].ID
+		}
+		saved = append(saved, chunk...)
+	}
+	if len(saved) == 0 && len(pending) > 0 {
+		return nil, nil, fmt.Errorf("failed to save imported tasks")
+	}
+	return report, saved, nil
+}
+
+// existingTaskTitles maps the duplicate key of each of the user's tasks to
+// its ID
+func existingTaskTitles(client *supabase.Client, userID string) (map[string]string, error) {
+	existing := map[string]string{}
+	for offset := 0; ; offset += importPageSize {
+		resp, _, err := client.From("tasks").
+			Select("id, title", "", false).
+			Eq("user_id", userID).
+			Is("deleted_at", "null").
+			Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+			Order("id", &postgrest.OrderOpts{Ascending: true}).
+			Range(offset, offset+importPageSize-1, "").
+			Execute()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch existing tasks: %w", err)
+		}
+
+		var page []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		}
+		if err := json.Unmarshal(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode existing tasks: %w", err)
+		}
+		for _, t := range page {
+			key := titles.Key(t.Title)
+			if _, ok := existing[key]; !ok {
+				existing[key] = t.ID
+			}
+		}
+		if len(page) < importPageSize {
+			return existing, nil
+		}
+	}
+}
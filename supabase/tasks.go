@@ -1,6 +1,7 @@
 package supabase
 
 import (
+	"clementus360/ai-helper/pagination"
 	"clementus360/ai-helper/types"
 	"encoding/json"
 	"fmt"
@@ -11,9 +12,10 @@ import (
 	"github.com/supabase-community/supabase-go"
 )
 
-// SaveTasks saves multiple tasks for a user, applying defaults
-func SaveTasks(client *supabase.Client, userID string, items []types.Task) error {
+// SaveTasks saves multiple tasks for a user, applying defaults, and returns the saved rows
+func SaveTasks(client *supabase.Client, userID string, profile types.UserProfile, items []types.Task) ([]types.Task, error) {
 	// Assuming the Task struct includes Title and Description
+	followUp := followUpDueAt(profile, time.Now())
 	for i := range items {
 		items[i].UserID = userID
 		items[i].Status = "pending"
@@ -23,16 +25,60 @@ func SaveTasks(client *supabase.Client, userID string, items []types.Task) error
 			items[i].CreatedAt = time.Now()
 		}
 		if items[i].FollowUpDueAt.IsZero() {
-			items[i].FollowUpDueAt = time.Now().Add(48 * time.Hour)
+			items[i].FollowUpDueAt = followUp
 		}
 	}
 
-	_, _, err := client.From("tasks").Insert(items, false, "", "", "").Execute()
-	return err
+	resp, _, err := client.From("tasks").Insert(items, false, "", "", "").Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []types.Task
+	if err := json.Unmarshal(resp, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode inserted tasks: %w", err)
+	}
+	return saved, nil
 }
 
-// InsertAndReturnTask inserts a task and returns the saved task with defaults applied
-func InsertAndReturnTask(client *supabase.Client, task types.Task) (types.Task, error) {
+// InsertTasks inserts several tasks at once, applying the same defaults as
+// InsertAndReturnTask but keeping each task's AISuggested flag
+func InsertTasks(client *supabase.Client, userID string, profile types.UserProfile, items []types.Task) ([]types.Task, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	followUp := followUpDueAt(profile, now)
+	for i := range items {
+		items[i].UserID = userID
+		items[i].FollowedUp = false
+		if items[i].Status == "" {
+			items[i].Status = "pending"
+		}
+		if items[i].CreatedAt.IsZero() {
+			items[i].CreatedAt = now
+		}
+		if items[i].FollowUpDueAt.IsZero() {
+			items[i].FollowUpDueAt = followUp
+		}
+	}
+
+	resp, _, err := client.From("tasks").Insert(items, false, "", "", "").Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []types.Task
+	if err := json.Unmarshal(resp, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode inserted tasks: %w", err)
+	}
+	return saved, nil
+}
+
+// InsertAndReturnTask inserts a task and returns the saved task with defaults
+// applied; profile schedules its follow-up
+func InsertAndReturnTask(client *supabase.Client, profile types.UserProfile, task types.Task) (types.Task, error) {
 	// Ensure defaults
 	if task.Status == "" {
 		task.Status = "pending"
@@ -41,7 +87,7 @@ func InsertAndReturnTask(client *supabase.Client, task types.Task) (types.Task,
 		task.CreatedAt = time.Now()
 	}
 	if task.FollowUpDueAt.IsZero() {
-		task.FollowUpDueAt = task.CreatedAt.Add(48 * time.Hour)
+		task.FollowUpDueAt = followUpDueAt(profile, task.CreatedAt)
 	}
 
 	task.AISuggested = false
@@ -113,14 +159,24 @@ func UpdateTask(client *supabase.Client, taskID, userID string, updates map[stri
 	return updated[0], nil
 }
 
-// GetTasks retrieves all tasks for a user, optionally filtering by status
-func GetTasks(client *supabase.Client, userID, sessionID, status string, limit, offset int, search, sortBy, sortOrder string) ([]types.Task, int64, error) {
+// GetTasks retrieves all tasks for a user, optionally filtering by status.
+// columnID filters to one board column, or to tasks on no column when "none".
+// Unless an offset or a sort column other than created_at is given, pages
+// are keyset-paginated from cursor, newest first by default. The total is
+// only counted for the first page.
+func GetTasks(client *supabase.Client, userID, sessionID, status, columnID string, limit, offset int, cursor pagination.Cursor, search, sortBy, sortOrder string) ([]types.Task, int64, pagination.Page, error) {
 	if userID == "" {
-		return nil, 0, fmt.Errorf("missing user ID")
+		return nil, 0, pagination.Page{}, fmt.Errorf("missing user ID")
+	}
+
+	keyset := offset == 0 && (sortBy == "" || sortBy == "created_at")
+	countType := "exact"
+	if cursor.Bounded() {
+		countType = ""
 	}
 
 	query := client.From("tasks").
-		Select("*", "exact", false).
+		Select("*", countType, false).
 		Is("deleted_at", "null").
 		Eq("user_id", userID)
 
@@ -130,35 +186,58 @@ func GetTasks(client *supabase.Client, userID, sessionID, status string, limit,
 	if status != "" {
 		query = query.Eq("status", status)
 	}
-	if limit > 0 {
-		query = query.Limit(limit, "")
-	}
-	if offset > 0 {
-		query = query.Range(offset, offset+limit-1, "")
+	if columnID == "none" {
+		query = query.Is("column_id", "null")
+	} else if columnID != "" {
+		query = query.Eq("column_id", columnID)
 	}
 	if search != "" {
 		// Match title or description with case-insensitive partial match
-		query = query.Or(fmt.Sprintf("title.ilike.*%s*,description.ilike.*%s*", search, search), "")
+		pattern := quoteFilterValue("*" + search + "*")
+		query = query.Or(fmt.Sprintf("title.ilike.%s,description.ilike.%s", pattern, pattern), "")
 	}
-	if sortBy != "" {
+
+	if keyset {
+		order := strings.ToLower(sortOrder)
+		descending := order == "desc" || (sortBy == "" && order != "asc")
+		query = keysetPage(query, cursor, descending, limit)
+	} else {
+		if offset > 0 {
+			query = query.Range(offset, offset+limit-1, "")
+		} else if limit > 0 {
+			query = query.Limit(limit, "")
+		}
+		if sortBy == "" {
+			sortBy, sortOrder = "created_at", "desc"
+		}
 		direction := "asc"
 		if strings.ToLower(sortOrder) == "desc" {
 			direction = "desc"
 		}
 		query = query.Order(sortBy, &postgrest.OrderOpts{Ascending: direction == "asc"})
+		if sortBy == "position" {
+			// Tasks never dragged have no position; keep them in creation order
+			query = query.Order("created_at", &postgrest.OrderOpts{Ascending: true})
+		}
 	}
 
 	resp, count, err := query.Execute()
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	var tasks []types.Task
 	if err := json.Unmarshal(resp, &tasks); err != nil {
-		return nil, 0, fmt.Errorf("failed to decode task data: %w", err)
+		return nil, 0, pagination.Page{}, fmt.Errorf("failed to decode task data: %w", err)
 	}
 
-	return tasks, count, nil
+	var page pagination.Page
+	if keyset {
+		tasks, page = pagination.Trim(tasks, cursor, limit, func(t types.Task) (time.Time, string) {
+			return t.CreatedAt, t.ID
+		})
+	}
+	return tasks, count, page, nil
 }
 
 // GetTasks retrieves one task for a user
@@ -185,3 +264,26 @@ func GetSingleTask(client *supabase.Client, userID, taskID string) ([]types.Task
 
 	return task, nil
 }
+
+// GetTasksForMessages returns the user's live tasks created by any of the given messages
+func GetTasksForMessages(client *supabase.Client, userID string, messageIDs []string) ([]types.Task, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	resp, _, err := client.From("tasks").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Is("deleted_at", "null").
+		In("message_id", messageIDs).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message tasks: %w", err)
+	}
+
+	var tasks []types.Task
+	if err := json.Unmarshal(resp, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode message tasks: %w", err)
+	}
+	return tasks, nil
+}
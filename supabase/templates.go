@@ -0,0 +1,129 @@
+package supabase
+
+import (
+	"clementus360/ai-helper/config"
+	"clementus360/ai-helper/templates"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+	"github.com/supabase-community/supabase-go"
+)
+
+// GetTaskTemplates returns the built-in templates followed by the user's own
+func GetTaskTemplates(client *supabase.Client, userID string) ([]types.TaskTemplate, error) {
+	all := append([]types.TaskTemplate{}, templates.Builtins...)
+
+	resp, _, err := client.From("task_templates").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return all, fmt.Errorf("failed to fetch task templates: %w", err)
+	}
+
+	var own []types.TaskTemplate
+	if err := json.Unmarshal(resp, &own); err != nil {
+		return all, fmt.Errorf("failed to decode task templates: %w", err)
+	}
+
+	return append(all, own...), nil
+}
+
+// GetTaskTemplate returns a built-in template or one of the user's own
+func GetTaskTemplate(client *supabase.Client, userID, templateID string) (types.TaskTemplate, error) {
+	if tmpl, ok := templates.FindBuiltin(templateID); ok {
+		return tmpl, nil
+	}
+	if strings.HasPrefix(templateID, templates.BuiltinIDPrefix) {
+		return types.TaskTemplate{}, fmt.Errorf("template not found")
+	}
+
+	resp, _, err := client.From("task_templates").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Eq("id", templateID).
+		Execute()
+	if err != nil {
+		return types.TaskTemplate{}, fmt.Errorf("failed to fetch task template: %w", err)
+	}
+
+	var found []types.TaskTemplate
+	if err := json.Unmarshal(resp, &found); err != nil {
+		return types.TaskTemplate{}, fmt.Errorf("failed to decode task template: %w", err)
+	}
+	if len(found) == 0 {
+		return types.TaskTemplate{}, fmt.Errorf("template not found")
+	}
+	return found[0], nil
+}
+
+// CreateTaskTemplate validates and saves a user-defined template
+func CreateTaskTemplate(client *supabase.Client, userID string, tmpl types.TaskTemplate) (types.TaskTemplate, error) {
+	if err := templates.Validate(tmpl); err != nil {
+		return types.TaskTemplate{}, err
+	}
+	tmpl.ID = ""
+	tmpl.UserID = userID
+	tmpl.BuiltIn = false
+	tmpl.CreatedAt = nil
+
+	resp, _, err := client.From("task_templates").Insert(tmpl, false, "", "", "").Execute()
+	if err != nil {
+		return types.TaskTemplate{}, fmt.Errorf("failed to save task template: %w", err)
+	}
+
+	var saved []types.TaskTemplate
+	if err := json.Unmarshal(resp, &saved); err != nil || len(saved) == 0 {
+		return types.TaskTemplate{}, fmt.Errorf("failed to decode saved task template")
+	}
+	return saved[0], nil
+}
+
+// DeleteTaskTemplate deletes one of the user's templates. Built-ins can't be deleted.
+func DeleteTaskTemplate(client *supabase.Client, userID, templateID string) error {
+	if strings.HasPrefix(templateID, templates.BuiltinIDPrefix) {
+		return fmt.Errorf("built-in templates can't be deleted")
+	}
+
+	_, _, err := client.From("task_templates").
+		Delete("", "").
+		Eq("id", templateID).
+		Eq("user_id", userID).
+		Execute()
+	return err
+}
+
+// InstantiateTemplate expands a template into tasks in the user's timezone,
+// saves them and records their creation. actor is a config.RevisionActor*
+// value; messageID links AI-created tasks to the message that created them.
+func InstantiateTemplate(client *supabase.Client, userID string, profile types.UserProfile, tmpl types.TaskTemplate, values map[string]string, sessionID, messageID, actor string) ([]types.Task, error) {
+	tasks, err := templates.Instantiate(tmpl, values, time.Now(), ProfileDueDateOptions(profile))
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].AISuggested = actor == config.RevisionActorAI
+		if sessionID != "" {
+			tasks[i].SessionID = &sessionID
+		}
+		if messageID != "" {
+			tasks[i].MessageID = &messageID
+		}
+	}
+
+	saved, err := InsertTasks(client, userID, profile, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save template tasks: %w", err)
+	}
+
+	if err := RecordTaskRevisions(client, userID, TaskCreations(saved), actor, messageID); err != nil {
+		log.Printf("Warning: failed to record task revisions: %v", err)
+	}
+	return saved, nil
+}
@@ -0,0 +1,138 @@
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Header names tried for each field the mapping leaves unset
+var csvHeaderGuesses = map[string][]string{
+	"title":       {"title", "name", "task", "content", "summary", "subject"},
+	"description": {"description", "notes", "note", "details", "desc", "body"},
+	"due":         {"due", "due date", "due_date", "deadline", "date"},
+	"priority":    {"priority", "prio", "importance"},
+	"labels":      {"labels", "label", "tags", "tag", "categories", "category"},
+	"completed":   {"completed", "done", "status", "state", "checked"},
+}
+
+// parseCSV reads a CSV file with a header row. Row numbers are the file
+// line each record starts on.
+func parseCSV(content string, mapping *types.CSVMapping, now time.Time, opts dates.Options) ([]Row, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff")))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var records [][]string
+	var lines []int
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV needs a header row and at least one task")
+	}
+
+	if mapping == nil {
+		mapping = &types.CSVMapping{}
+	}
+	columns, err := csvColumns(records[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+	sep := mapping.LabelSeparator
+	if sep == "" {
+		sep = ","
+	}
+
+	var rows []Row
+	for i, record := range records[1:] {
+		field := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		task := types.Task{
+			Title:       field("title"),
+			Description: field("description"),
+			Priority:    parsePriority(field("priority")),
+		}
+		if labels := field("labels"); labels != "" {
+			task.Labels = cleanLabels(strings.Split(labels, sep))
+		}
+		row := newRow(lines[i+1], task)
+
+		if status, ok := parseCompleted(field("completed")); ok {
+			row.Task.Status = status
+		} else {
+			row.Problem = fmt.Sprintf("unreadable completed value %q", field("completed"))
+		}
+		due, err := parseDue(field("due"), now, opts)
+		if err != nil {
+			row.Problem = err.Error()
+		}
+		row.Task.DueDate = due
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvColumns finds the column of each field: the header the mapping names,
+// or else the first header matching a common name
+func csvColumns(header []string, mapping *types.CSVMapping) (map[string]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if _, ok := index[h]; !ok {
+			index[h] = i
+		}
+	}
+
+	mapped := map[string]string{
+		"title":       mapping.Title,
+		"description": mapping.Description,
+		"due":         mapping.Due,
+		"priority":    mapping.Priority,
+		"labels":      mapping.Labels,
+		"completed":   mapping.Completed,
+	}
+
+	columns := map[string]int{}
+	for field, name := range mapped {
+		if name != "" {
+			idx, ok := index[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("mapping for %s names column %q, which isn't in the header", field, name)
+			}
+			columns[field] = idx
+			continue
+		}
+		for _, guess := range csvHeaderGuesses[field] {
+			if idx, ok := index[guess]; ok {
+				columns[field] = idx
+				break
+			}
+		}
+	}
+
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("no title column found; set mapping.title to the header holding task titles")
+	}
+	return columns, nil
+}
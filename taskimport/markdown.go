@@ -0,0 +1,102 @@
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	checkboxLine = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s*(.*)$`)
+	hashTag      = regexp.MustCompile(`(^|\s)#([\p{L}\p{N}_/-]+)`)
+	dueMarker    = regexp.MustCompile(`(?:^|\s)(?:due:\s*|📅\s*)(\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2})?)`)
+)
+
+// Priority markers used by the Obsidian Tasks plugin
+var markdownPriorities = []struct {
+	marker   string
+	priority int
+}{
+	{"🔺", 1}, {"⏫", 2}, {"🔼", 3}, {"🔽", 4}, {"⏬", 4},
+}
+
+// parseMarkdown reads "- [ ]" and "- [x]" checklist items. #tags become
+// labels, "due:2025-06-01" or "📅 2025-06-01" sets the due date, and
+// indented lines below an item that aren't items themselves become its
+// description. Row numbers are file lines.
+func parseMarkdown(content string, now time.Time, opts dates.Options) ([]Row, error) {
+	var rows []Row
+	itemIndent := -1
+	var notes []string
+
+	flush := func() {
+		if len(rows) > 0 && len(notes) > 0 {
+			last := &rows[len(rows)-1]
+			last.Task.Description = strings.TrimSpace(strings.Join(notes, "\n"))
+		}
+		notes = nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		m := checkboxLine.FindStringSubmatch(line)
+		if m == nil {
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			if itemIndent >= 0 && indent > itemIndent && strings.TrimSpace(line) != "" {
+				notes = append(notes, strings.TrimSpace(line))
+			} else if strings.TrimSpace(line) != "" {
+				flush()
+				itemIndent = -1
+			}
+			continue
+		}
+
+		flush()
+		itemIndent = len(m[1])
+		row := markdownRow(i+1, m[3], now, opts)
+		if m[2] != " " {
+			row.Task.Status = "completed"
+		}
+		rows = append(rows, row)
+	}
+	flush()
+	return rows, nil
+}
+
+func markdownRow(n int, text string, now time.Time, opts dates.Options) Row {
+	var task types.Task
+	var problem string
+
+	if m := dueMarker.FindStringSubmatch(text); m != nil {
+		due, err := parseDue(m[1], now, opts)
+		if err != nil {
+			problem = err.Error()
+		}
+		task.DueDate = due
+		text = strings.Replace(text, strings.TrimSpace(m[0]), "", 1)
+	}
+
+	for _, p := range markdownPriorities {
+		if strings.Contains(text, p.marker) {
+			if task.Priority == 0 {
+				task.Priority = p.priority
+			}
+			text = strings.ReplaceAll(text, p.marker, "")
+		}
+	}
+
+	var labels []string
+	for _, m := range hashTag.FindAllStringSubmatch(text, -1) {
+		labels = append(labels, m[2])
+	}
+	task.Labels = cleanLabels(labels)
+	text = hashTag.ReplaceAllString(text, "$1")
+
+	task.Title = strings.Join(strings.Fields(text), " ")
+	row := newRow(n, task)
+	if problem != "" {
+		row.Problem = problem
+	}
+	return row
+}
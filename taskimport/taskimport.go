@@ -0,0 +1,140 @@
+// Package taskimport reads tasks exported from other tools (CSV files,
+// Markdown checklists, Todoist and Trello JSON exports) into types.Task.
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRows caps how many tasks one import may contain
+const MaxRows = 1000
+
+// Row is one task read from the source. Skip or Problem explain why it
+// shouldn't be imported; Task is only meaningful when both are empty.
+type Row struct {
+	Row     int // line number, or 1-based position in a JSON export
+	Task    types.Task
+	Skip    string
+	Problem string
+}
+
+// Parse reads content in the given format. now and opts resolve due dates
+// that carry no timezone or time of day.
+func Parse(format, content string, mapping *types.CSVMapping, now time.Time, opts dates.Options) ([]Row, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is empty")
+	}
+
+	var rows []Row
+	var err error
+	switch format {
+	case types.TaskImportCSV:
+		rows, err = parseCSV(content, mapping, now, opts)
+	case types.TaskImportMarkdown:
+		rows, err = parseMarkdown(content, now, opts)
+	case types.TaskImportTodoist:
+		rows, err = parseTodoist(content, now, opts)
+	case types.TaskImportTrello:
+		rows, err = parseTrello(content, now, opts)
+	default:
+		return nil, fmt.Errorf("format must be csv, markdown, todoist or trello")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no tasks found")
+	}
+	if len(rows) > MaxRows {
+		return nil, fmt.Errorf("too many tasks, the limit is %d", MaxRows)
+	}
+	return rows, nil
+}
+
+// parseDue accepts absolute dates and the phrases dates.ParseDue knows.
+// Imported tasks may already be overdue, so dates in the past are kept.
+func parseDue(value string, now time.Time, opts dates.Options) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	due, err := dates.ParseDue(value, now, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unreadable due date %q", value)
+	}
+	return &due, nil
+}
+
+// parsePriority reads 1-4, p1-p4 or a word such as "high". Anything that
+// doesn't name a priority is 0.
+func parsePriority(value string) int {
+	v := strings.ToLower(strings.TrimSpace(value))
+	v = strings.TrimSpace(strings.TrimPrefix(v, "priority"))
+	v = strings.TrimLeft(v, ":-_ ")
+	switch v {
+	case "1", "p1", "urgent", "highest", "critical":
+		return 1
+	case "2", "p2", "high":
+		return 2
+	case "3", "p3", "medium", "med":
+		return 3
+	case "4", "p4", "low", "lowest":
+		return 4
+	}
+	return 0
+}
+
+// parseCompleted reads a completed column, which may hold a boolean or a
+// status. Cancelled tasks keep their own status.
+func parseCompleted(value string) (string, bool) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	switch v {
+	case "", "false", "no", "n", "0", "todo", "to do", "open", "pending", "incomplete", "not started", "in progress":
+		return "pending", true
+	case "cancelled", "canceled":
+		return "cancelled", true
+	}
+	if b, err := strconv.ParseBool(v); err == nil && b {
+		return "completed", true
+	}
+	switch v {
+	case "yes", "y", "x", "done", "complete", "completed", "checked", "finished", "closed":
+		return "completed", true
+	}
+	return "", false
+}
+
+// cleanLabels trims labels, drops a leading '#' and removes duplicates
+// regardless of case
+func cleanLabels(labels []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, l := range labels {
+		l = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "#"))
+		if l == "" || seen[strings.ToLower(l)] {
+			continue
+		}
+		seen[strings.ToLower(l)] = true
+		out = append(out, l)
+	}
+	return out
+}
+
+// newRow validates a task read from the source
+func newRow(n int, task types.Task) Row {
+	task.Title = strings.TrimSpace(task.Title)
+	task.Description = strings.TrimSpace(task.Description)
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+	row := Row{Row: n, Task: task}
+	if task.Title == "" {
+		row.Problem = "missing title"
+	}
+	return row
+}
@@ -0,0 +1,234 @@
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		mapping types.CSVMapping
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:   "guessed headers",
+			header: []string{"Name", "Notes", "Due Date", "Priority", "Tags", "Done"},
+			want: map[string]int{
+				"title": 0, "description": 1, "due": 2, "priority": 3, "labels": 4, "completed": 5,
+			},
+		},
+		{
+			name:   "first guess wins",
+			header: []string{"Summary", "Title"},
+			want:   map[string]int{"title": 1},
+		},
+		{
+			name:   "duplicate header uses the first column",
+			header: []string{"Task", "Task"},
+			want:   map[string]int{"title": 0},
+		},
+		{
+			name:    "mapping overrides guesses",
+			header:  []string{"Title", "Heading", " Deadline "},
+			mapping: types.CSVMapping{Title: "heading", Due: "DEADLINE"},
+			want:    map[string]int{"title": 1, "due": 2},
+		},
+		{
+			name:    "mapped column missing",
+			header:  []string{"Title"},
+			mapping: types.CSVMapping{Due: "when"},
+			wantErr: true,
+		},
+		{
+			name:    "no title column",
+			header:  []string{"Notes", "Due"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := csvColumns(tt.header, &tt.mapping)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCleanLabels(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{in: []string{"work", " home "}, want: []string{"work", "home"}},
+		{in: []string{"#urgent", " # later"}, want: []string{"urgent", "later"}},
+		{in: []string{"Work", "work", "#WORK"}, want: []string{"Work"}},
+		{in: []string{"", " ", "#"}, want: nil},
+		{in: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := cleanLabels(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("cleanLabels(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCompleted(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "pending", true},
+		{"no", "pending", true},
+		{"FALSE", "pending", true},
+		{"In Progress", "pending", true},
+		{"yes", "completed", true},
+		{"TRUE", "completed", true},
+		{"1", "completed", true},
+		{"x", "completed", true},
+		{" Done ", "completed", true},
+		{"canceled", "cancelled", true},
+		{"cancelled", "cancelled", true},
+		{"maybe", "", false},
+		{"2", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseCompleted(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseCompleted(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"1", 1},
+		{"P2", 2},
+		{"Priority: medium", 3},
+		{"priority-4", 4},
+		{" low ", 4},
+		{"urgent", 1},
+		{"", 0},
+		{"5", 0},
+		{"someday", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parsePriority(tt.value); got != tt.want {
+			t.Errorf("parsePriority(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	opts := dates.Options{Location: time.UTC}
+	content := "\ufeffTitle,Due,Labels,Completed\n" +
+		"Write report,2026-10-20,\"work, #writing\",no\n" +
+		"\n" +
+		"File taxes,2026-04-15,home,yes\n" +
+		",,,\n" +
+		"Call mum,,home;family,maybe\n" +
+		",2026-10-21,,\n" +
+		"Renew passport,someday-ish,,\n"
+
+	rows, err := Parse(types.TaskImportCSV, content, nil, now, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5: %+v", len(rows), rows)
+	}
+
+	report := rows[0]
+	if report.Row != 2 || report.Problem != "" || report.Task.Status != "pending" {
+		t.Errorf("report row: %+v", report)
+	}
+	if !reflect.DeepEqual(report.Task.Labels, []string{"work", "writing"}) {
+		t.Errorf("report labels: %q", report.Task.Labels)
+	}
+	if want := time.Date(2026, 10, 20, 17, 0, 0, 0, time.UTC); report.Task.DueDate == nil || !report.Task.DueDate.Equal(want) {
+		t.Errorf("report due: %v, want %v", report.Task.DueDate, want)
+	}
+
+	// Imported tasks may already be overdue
+	taxes := rows[1]
+	if taxes.Row != 4 || taxes.Problem != "" || taxes.Task.Status != "completed" {
+		t.Errorf("taxes row: %+v", taxes)
+	}
+	if want := time.Date(2026, 4, 15, 17, 0, 0, 0, time.UTC); taxes.Task.DueDate == nil || !taxes.Task.DueDate.Equal(want) {
+		t.Errorf("taxes due: %v, want %v", taxes.Task.DueDate, want)
+	}
+
+	call := rows[2]
+	if !strings.Contains(call.Problem, "completed") {
+		t.Errorf("call problem: %q", call.Problem)
+	}
+	if !reflect.DeepEqual(call.Task.Labels, []string{"home;family"}) {
+		t.Errorf("call labels: %q", call.Task.Labels)
+	}
+
+	if rows[3].Problem != "missing title" {
+		t.Errorf("untitled problem: %q", rows[3].Problem)
+	}
+	if !strings.Contains(rows[4].Problem, "due date") {
+		t.Errorf("passport problem: %q", rows[4].Problem)
+	}
+}
+
+func TestParseCSVLabelSeparator(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	content := "Task,Tags\nCall mum,home; family ;;Home\n"
+	mapping := &types.CSVMapping{LabelSeparator: ";"}
+
+	rows, err := Parse(types.TaskImportCSV, content, mapping, now, dates.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"home", "family"}; !reflect.DeepEqual(rows[0].Task.Labels, want) {
+		t.Errorf("labels: %q, want %q", rows[0].Task.Labels, want)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{"empty", types.TaskImportCSV, "  \n"},
+		{"header only", types.TaskImportCSV, "Title,Due\n"},
+		{"unknown format", "xlsx", "Title\nA\n"},
+		{"no checklist items", types.TaskImportMarkdown, "# Notes\nnothing here\n"},
+		{"too many rows", types.TaskImportCSV, "Title\n" + strings.Repeat("task\n", MaxRows+1)},
+	}
+
+	for _, tt := range tests {
+		if rows, err := Parse(tt.format, tt.content, nil, now, dates.Options{}); err == nil {
+			t.Errorf("%s: expected an error, got %d rows", tt.name, len(rows))
+		}
+	}
+}
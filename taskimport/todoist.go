@@ -0,0 +1,107 @@
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type todoistTask struct {
+	Content     string       `json:"content"`
+	Description string       `json:"description"`
+	Priority    int          `json:"priority"` // 4 is the most urgent
+	Labels      []string     `json:"labels"`
+	IsCompleted bool         `json:"is_completed"`
+	Checked     any          `json:"checked"`    // bool, or 0/1 in older exports
+	IsDeleted   any          `json:"is_deleted"` // likewise
+	Due         *todoistDue  `json:"due"`
+	Deadline    *todoistDate `json:"deadline"`
+}
+
+type todoistDue struct {
+	Date     string `json:"date"`
+	Datetime string `json:"datetime"`
+	Timezone string `json:"timezone"`
+}
+
+type todoistDate struct {
+	Date string `json:"date"`
+}
+
+// parseTodoist reads tasks from the Todoist API: a bare array of tasks, or
+// an object listing them under items (sync), results or tasks
+func parseTodoist(content string, now time.Time, opts dates.Options) ([]Row, error) {
+	var tasks []todoistTask
+	if err := json.Unmarshal([]byte(content), &tasks); err != nil {
+		var wrapped struct {
+			Items   []todoistTask `json:"items"`
+			Results []todoistTask `json:"results"`
+			Tasks   []todoistTask `json:"tasks"`
+		}
+		if err := json.Unmarshal([]byte(content), &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid Todoist export: %w", err)
+		}
+		tasks = append(append(wrapped.Items, wrapped.Results...), wrapped.Tasks...)
+	}
+
+	rows := make([]Row, 0, len(tasks))
+	for i, t := range tasks {
+		task := types.Task{
+			Title:       t.Content,
+			Description: t.Description,
+			Labels:      cleanLabels(t.Labels),
+		}
+		// Todoist's p1 is sent as priority 4
+		if t.Priority >= 1 && t.Priority <= 4 {
+			task.Priority = 5 - t.Priority
+		}
+		if t.IsCompleted || truthy(t.Checked) {
+			task.Status = "completed"
+		}
+
+		row := newRow(i+1, task)
+		if truthy(t.IsDeleted) {
+			row.Skip = "deleted in Todoist"
+		}
+
+		due, err := todoistDueDate(t, now, opts)
+		if err != nil {
+			row.Problem = err.Error()
+		}
+		row.Task.DueDate = due
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// todoistDueDate prefers the exact due time, then the due day, then the
+// deadline. A due timezone overrides the import's for floating times.
+func todoistDueDate(t todoistTask, now time.Time, opts dates.Options) (*time.Time, error) {
+	if t.Due != nil {
+		if t.Due.Timezone != "" {
+			opts.Location = dates.LoadLocation(t.Due.Timezone)
+		}
+		if t.Due.Datetime != "" {
+			return parseDue(t.Due.Datetime, now, opts)
+		}
+		if t.Due.Date != "" {
+			return parseDue(t.Due.Date, now, opts)
+		}
+	}
+	if t.Deadline != nil && t.Deadline.Date != "" {
+		return parseDue(t.Deadline.Date, now, opts)
+	}
+	return nil, nil
+}
+
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package taskimport
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type trelloBoard struct {
+	Cards      []trelloCard      `json:"cards"`
+	Lists      []trelloList      `json:"lists"`
+	Checklists []trelloChecklist `json:"checklists"`
+}
+
+type trelloCard struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"desc"`
+	Due         string        `json:"due"`
+	DueComplete bool          `json:"dueComplete"`
+	Closed      bool          `json:"closed"`
+	IDList      string        `json:"idList"`
+	Labels      []trelloLabel `json:"labels"`
+}
+
+type trelloLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type trelloList struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+}
+
+type trelloChecklist struct {
+	IDCard     string `json:"idCard"`
+	Name       string `json:"name"`
+	CheckItems []struct {
+		Name  string `json:"name"`
+		State string `json:"state"` // complete | incomplete
+	} `json:"checkItems"`
+}
+
+// Lists whose cards count as done
+var trelloDoneLists = map[string]bool{"done": true, "complete": true, "completed": true, "finished": true}
+
+// parseTrello reads a Trello board export. Archived cards and cards in
+// archived lists are skipped; cards in a "Done" list, or whose due date is
+// marked complete, are completed. Labels naming a priority set it, and
+// checklists are appended to the description.
+func parseTrello(content string, now time.Time, opts dates.Options) ([]Row, error) {
+	var board trelloBoard
+	if err := json.Unmarshal([]byte(content), &board); err != nil {
+		return nil, fmt.Errorf("invalid Trello export: %w", err)
+	}
+
+	lists := map[string]trelloList{}
+	for _, l := range board.Lists {
+		lists[l.ID] = l
+	}
+	checklists := map[string][]trelloChecklist{}
+	for _, c := range board.Checklists {
+		checklists[c.IDCard] = append(checklists[c.IDCard], c)
+	}
+
+	rows := make([]Row, 0, len(board.Cards))
+	for i, card := range board.Cards {
+		task := types.Task{
+			Title:       card.Name,
+			Description: trelloDescription(card.Desc, checklists[card.ID]),
+		}
+
+		var labels []string
+		for _, l := range card.Labels {
+			name := l.Name
+			if name == "" {
+				name = l.Color
+			}
+			if p := parsePriority(name); p != 0 {
+				if task.Priority == 0 || p < task.Priority {
+					task.Priority = p
+				}
+				continue
+			}
+			labels = append(labels, name)
+		}
+		task.Labels = cleanLabels(labels)
+
+		list := lists[card.IDList]
+		if card.DueComplete || trelloDoneLists[strings.ToLower(strings.TrimSpace(list.Name))] {
+			task.Status = "completed"
+		}
+
+		row := newRow(i+1, task)
+		switch {
+		case card.Closed:
+			row.Skip = "archived in Trello"
+		case list.Closed:
+			row.Skip = fmt.Sprintf("list %q is archived in Trello", list.Name)
+		}
+
+		due, err := parseDue(card.Due, now, opts)
+		if err != nil {
+			row.Problem = err.Error()
+		}
+		row.Task.DueDate = due
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func trelloDescription(desc string, checklists []trelloChecklist) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(desc))
+	for _, c := range checklists {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(c.Name)
+		for _, item := range c.CheckItems {
+			box := "[ ]"
+			if item.State == "complete" {
+				box = "[x]"
+			}
+			fmt.Fprintf(&b, "\n- %s %s", box, item.Name)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package templates
+
+import "clementus360/ai-helper/types"
+
+// Builtins are available to every user and can't be edited or deleted
+var Builtins = []types.TaskTemplate{
+	{
+		ID:          "builtin-weekly-review",
+		Name:        "Weekly review",
+		Description: "Close out the week and plan the next one",
+		BuiltIn:     true,
+		Steps: []types.TemplateStep{
+			{Title: "Clear inboxes", Description: "Process email, messages and notes down to zero", DueOffset: "0d"},
+			{Title: "Review last week's tasks", Description: "Mark what got done and decide what to carry over", DueOffset: "0d"},
+			{Title: "Check the calendar", Description: "Look two weeks ahead for deadlines and prep work", DueOffset: "0d"},
+			{Title: "Pick next week's top 3", Description: "Choose the three outcomes that would make next week a success", DueOffset: "0d"},
+		},
+	},
+	{
+		ID:           "builtin-job-application",
+		Name:         "Job application",
+		Description:  "Apply for a role from research to follow-up",
+		Placeholders: []string{"role", "company"},
+		BuiltIn:      true,
+		Steps: []types.TemplateStep{
+			{Title: "Research {{company}}", Description: "Read about the team, product and recent news", DueOffset: "1d"},
+			{Title: "Tailor resume for {{role}}", Description: "Match your experience to the {{role}} posting at {{company}}", DueOffset: "2d"},
+			{Title: "Write cover letter for {{company}}", Description: "Keep it to one page and lead with why {{company}}", DueOffset: "3d"},
+			{Title: "Submit {{role}} application", Description: "Double-check attachments before sending", DueOffset: "4d"},
+			{Title: "Follow up with {{company}}", Description: "Send a short note if you haven't heard back", DueOffset: "2w"},
+		},
+	},
+	{
+		ID:           "builtin-blog-post",
+		Name:         "Publish a blog post",
+		Description:  "Take a post from idea to published",
+		Placeholders: []string{"topic"},
+		BuiltIn:      true,
+		Steps: []types.TemplateStep{
+			{Title: "Outline post on {{topic}}", Description: "List the key points and the one takeaway", DueOffset: "1d"},
+			{Title: "Write first draft on {{topic}}", Description: "Aim for done, not perfect", DueOffset: "3d"},
+			{Title: "Edit the {{topic}} draft", Description: "Cut ruthlessly and check the flow", DueOffset: "4d"},
+			{Title: "Publish and share the {{topic}} post", Description: "Add images, publish and post the link", DueOffset: "5d"},
+		},
+	},
+}
+
+// FindBuiltin returns the built-in template with the given ID
+func FindBuiltin(id string) (types.TaskTemplate, bool) {
+	for _, tmpl := range Builtins {
+		if tmpl.ID == id {
+			return tmpl, true
+		}
+	}
+	return types.TaskTemplate{}, false
+}
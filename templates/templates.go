@@ -0,0 +1,102 @@
+// Package templates expands task templates (weekly review, job application,
+// and user-defined routines) into concrete tasks.
+package templates
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MaxTemplateSteps = 20
+	BuiltinIDPrefix  = "builtin-"
+)
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+
+// Validate checks a user-defined template before it is saved
+func Validate(tmpl types.TaskTemplate) error {
+	if strings.TrimSpace(tmpl.Name) == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if len(tmpl.Steps) == 0 || len(tmpl.Steps) > MaxTemplateSteps {
+		return fmt.Errorf("templates need between 1 and %d steps", MaxTemplateSteps)
+	}
+
+	declared := map[string]bool{}
+	for _, name := range tmpl.Placeholders {
+		declared[name] = true
+	}
+
+	for i, step := range tmpl.Steps {
+		if strings.TrimSpace(step.Title) == "" {
+			return fmt.Errorf("step %d has an empty title", i+1)
+		}
+		if step.DueOffset != "" {
+			if err := dates.ValidateOffset(step.DueOffset); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+		for _, name := range placeholdersIn(step.Title + " " + step.Description) {
+			if !declared[name] {
+				return fmt.Errorf("step %d uses undeclared placeholder %q", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Instantiate expands a template into one pending task per step, in order.
+// Every declared placeholder must have a value.
+func Instantiate(tmpl types.TaskTemplate, values map[string]string, now time.Time, opts dates.Options) ([]types.Task, error) {
+	var missing []string
+	for _, name := range tmpl.Placeholders {
+		if strings.TrimSpace(values[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing values for placeholders: %s", strings.Join(missing, ", "))
+	}
+
+	fill := func(text string) string {
+		return placeholderRe.ReplaceAllStringFunc(text, func(marker string) string {
+			name := placeholderRe.FindStringSubmatch(marker)[1]
+			return strings.TrimSpace(values[name])
+		})
+	}
+
+	tasks := make([]types.Task, 0, len(tmpl.Steps))
+	for i, step := range tmpl.Steps {
+		task := types.Task{
+			Title:       fill(step.Title),
+			Description: fill(step.Description),
+			Status:      "pending",
+			// Stagger creation times so the steps keep their order
+			CreatedAt: now.Add(time.Duration(i) * time.Millisecond),
+		}
+		if step.DueOffset != "" {
+			due, err := dates.ApplyOffset(step.DueOffset, now, opts)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			task.DueDate = &due
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func placeholdersIn(text string) []string {
+	var names []string
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
@@ -0,0 +1,160 @@
+package templates
+
+import (
+	"clementus360/ai-helper/dates"
+	"clementus360/ai-helper/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	step := func(title, offset string) types.TemplateStep {
+		return types.TemplateStep{Title: title, DueOffset: offset}
+	}
+	manySteps := make([]types.TemplateStep, MaxTemplateSteps+1)
+	for i := range manySteps {
+		manySteps[i] = step("Step", "")
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    types.TaskTemplate
+		wantErr string // empty when valid
+	}{
+		{
+			name: "valid",
+			tmpl: types.TaskTemplate{Name: "Routine", Steps: []types.TemplateStep{step("Do it", "1d"), step("Check it", "")}},
+		},
+		{
+			name: "declared placeholders",
+			tmpl: types.TaskTemplate{
+				Name:         "Trip",
+				Placeholders: []string{"city"},
+				Steps:        []types.TemplateStep{{Title: "Book {{ city }} hotel", Description: "Near {{city}} centre"}},
+			},
+		},
+		{
+			name:    "missing name",
+			tmpl:    types.TaskTemplate{Name: "  ", Steps: []types.TemplateStep{step("Do it", "")}},
+			wantErr: "name is required",
+		},
+		{
+			name:    "no steps",
+			tmpl:    types.TaskTemplate{Name: "Empty"},
+			wantErr: "between 1 and",
+		},
+		{
+			name:    "too many steps",
+			tmpl:    types.TaskTemplate{Name: "Long", Steps: manySteps},
+			wantErr: "between 1 and",
+		},
+		{
+			name:    "empty step title",
+			tmpl:    types.TaskTemplate{Name: "Routine", Steps: []types.TemplateStep{step("Do it", ""), step(" ", "")}},
+			wantErr: "step 2 has an empty title",
+		},
+		{
+			name:    "bad offset",
+			tmpl:    types.TaskTemplate{Name: "Routine", Steps: []types.TemplateStep{step("Do it", "tomorrow")}},
+			wantErr: "invalid offset",
+		},
+		{
+			name:    "undeclared placeholder in description",
+			tmpl:    types.TaskTemplate{Name: "Trip", Steps: []types.TemplateStep{{Title: "Pack", Description: "For {{city}}"}}},
+			wantErr: `undeclared placeholder "city"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.tmpl)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuiltinsAreValid(t *testing.T) {
+	for _, tmpl := range Builtins {
+		if err := Validate(tmpl); err != nil {
+			t.Errorf("built-in %s: %v", tmpl.ID, err)
+		}
+		if !strings.HasPrefix(tmpl.ID, BuiltinIDPrefix) || !tmpl.BuiltIn {
+			t.Errorf("built-in %s is not marked as built in", tmpl.ID)
+		}
+		if found, ok := FindBuiltin(tmpl.ID); !ok || found.Name != tmpl.Name {
+			t.Errorf("FindBuiltin(%q) = %v, %v", tmpl.ID, found.Name, ok)
+		}
+	}
+	if _, ok := FindBuiltin("builtin-missing"); ok {
+		t.Error("FindBuiltin found a template that doesn't exist")
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, loc)
+	tmpl := types.TaskTemplate{
+		Name:         "Job application",
+		Placeholders: []string{"role", "company"},
+		Steps: []types.TemplateStep{
+			{Title: "Research {{company}}", DueOffset: "1d"},
+			{Title: "Apply for {{ role }}", Description: "At {{company}}", DueOffset: "2h"},
+			{Title: "Celebrate"},
+		},
+	}
+
+	tasks, err := Instantiate(tmpl, map[string]string{"role": " Engineer ", "company": "Acme"}, now, dates.Options{Location: loc})
+	if err != nil {
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(tasks))
+	}
+
+	tests := []struct {
+		title, description string
+		due                *time.Time
+	}{
+		{"Research Acme", "", ptr(time.Date(2026, 10, 15, 17, 0, 0, 0, loc))},
+		{"Apply for Engineer", "At Acme", ptr(now.Add(2 * time.Hour))},
+		{"Celebrate", "", nil},
+	}
+	for i, tt := range tests {
+		task := tasks[i]
+		if task.Title != tt.title || task.Description != tt.description || task.Status != "pending" {
+			t.Errorf("task %d = %q / %q / %q, want %q / %q / pending", i, task.Title, task.Description, task.Status, tt.title, tt.description)
+		}
+		switch {
+		case tt.due == nil && task.DueDate != nil:
+			t.Errorf("task %d due %v, want none", i, task.DueDate)
+		case tt.due != nil && (task.DueDate == nil || !task.DueDate.Equal(*tt.due)):
+			t.Errorf("task %d due %v, want %v", i, task.DueDate, tt.due)
+		}
+		if i > 0 && !task.CreatedAt.After(tasks[i-1].CreatedAt) {
+			t.Errorf("task %d is not created after task %d", i, i-1)
+		}
+	}
+}
+
+func TestInstantiateMissingValues(t *testing.T) {
+	tmpl := types.TaskTemplate{
+		Name:         "Trip",
+		Placeholders: []string{"city", "date"},
+		Steps:        []types.TemplateStep{{Title: "Visit {{city}} on {{date}}"}},
+	}
+
+	_, err := Instantiate(tmpl, map[string]string{"city": "  "}, time.Now(), dates.Options{})
+	if err == nil || !strings.Contains(err.Error(), "city, date") {
+		t.Errorf("Instantiate() = %v, want missing city and date", err)
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
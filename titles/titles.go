@@ -0,0 +1,11 @@
+// Package titles normalizes task titles so the same task written slightly
+// differently, by an import or by the assistant, is recognised as one.
+package titles
+
+import "strings"
+
+// Key is the form of a title used to spot duplicates: case and runs of
+// whitespace don't count
+func Key(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
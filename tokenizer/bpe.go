@@ -0,0 +1,162 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"clementus360/ai-helper/config"
+	"compress/gzip"
+	"container/heap"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// cl100k_base is the vocabulary of gpt-3.5-turbo and gpt-4, in tiktoken's
+// format: one base64 token and its merge rank per line
+//
+//go:embed cl100k_base.tiktoken.gz
+var cl100kBase []byte
+
+// CL100K counts tokens the way OpenAI's chat models encode them
+var CL100K = &BPE{name: "cl100k_base", data: cl100kBase}
+
+// BPE is a byte-pair encoder with a tiktoken vocabulary. It only counts
+// tokens, so it never builds the token IDs themselves. The vocabulary is
+// decoded the first time it's used.
+type BPE struct {
+	name string
+	data []byte
+
+	once  sync.Once
+	ranks map[string]int
+}
+
+func (b *BPE) Count(text string) int {
+	b.once.Do(b.load)
+	if b.ranks == nil {
+		return Estimator{}.Count(text)
+	}
+
+	count := 0
+	eachPiece(text, func(piece string) {
+		count += b.countPiece(piece)
+	})
+	return count
+}
+
+// countPiece merges the bytes of one pre-token, lowest rank first (the
+// leftmost of equal ranks), and returns how many tokens are left. Candidate
+// merges wait in a heap and are dropped when popped if either side has
+// merged since, so a long piece such as a pasted blob without spaces costs
+// O(n log n) rather than O(n²).
+func (b *BPE) countPiece(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	// Parts are identified by the offset they start at; next[i] is where
+	// the part after i starts, or -1 once i has merged into its left
+	// neighbour
+	n := len(piece)
+	next := make([]int, n)
+	prev := make([]int, n)
+	candidates := make(mergeHeap, 0, n)
+	for i := range next {
+		next[i], prev[i] = i+1, i-1
+		if i+2 <= n {
+			if rank, ok := b.ranks[piece[i:i+2]]; ok {
+				candidates = append(candidates, merge{rank, i, i + 1, i + 2})
+			}
+		}
+	}
+	heap.Init(&candidates)
+
+	parts := n
+	for candidates.Len() > 0 {
+		m := heap.Pop(&candidates).(merge)
+		if next[m.left] != m.mid || next[m.mid] != m.end {
+			continue // stale
+		}
+		next[m.left], next[m.mid] = m.end, -1
+		if m.end < n {
+			prev[m.end] = m.left
+		}
+		parts--
+
+		if p := prev[m.left]; p >= 0 {
+			if rank, ok := b.ranks[piece[p:m.end]]; ok {
+				heap.Push(&candidates, merge{rank, p, m.left, m.end})
+			}
+		}
+		if m.end < n {
+			if rank, ok := b.ranks[piece[m.left:next[m.end]]]; ok {
+				heap.Push(&candidates, merge{rank, m.left, m.end, next[m.end]})
+			}
+		}
+	}
+	return parts
+}
+
+// merge is a candidate merge of the adjacent parts piece[left:mid] and
+// piece[mid:end]
+type merge struct {
+	rank, left, mid, end int
+}
+
+// mergeHeap orders candidate merges by rank, then leftmost first
+type mergeHeap []merge
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].left < h[j].left
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(merge)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+func (b *BPE) load() {
+	ranks, err := parseTiktoken(b.data)
+	if err != nil {
+		// Counting falls back to the estimator rather than failing prompts
+		config.Logger.Errorf("Failed to load BPE vocabulary %s: %v", b.name, err)
+		return
+	}
+	b.ranks = ranks
+}
+
+func parseTiktoken(data []byte) (map[string]int, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	ranks := make(map[string]int, 100_000)
+	scanner := bufio.NewScanner(zr)
+	for line := 1; scanner.Scan(); line++ {
+		token, rank, ok := bytes.Cut(scanner.Bytes(), []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing rank", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		ranks[string(decoded)] = n
+	}
+	return ranks, scanner.Err()
+}
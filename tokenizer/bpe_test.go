@@ -0,0 +1,89 @@
+package tokenizer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// naiveCountPiece is the straightforward quadratic merge loop countPiece
+// must agree with
+func naiveCountPiece(ranks map[string]int, piece string) int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, at := -1, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := ranks[piece[bounds[i]:bounds[i+2]]]
+			if ok && (best < 0 || rank < best) {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = append(bounds[:at+1], bounds[at+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+func TestCL100KCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"tiktoken is great!", 6},
+	}
+	for _, tt := range tests {
+		if got := CL100K.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountPieceMatchesNaiveMerge(t *testing.T) {
+	CL100K.once.Do(CL100K.load)
+	if CL100K.ranks == nil {
+		t.Fatal("vocabulary failed to load")
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	alphabet := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+/=_-"
+	pieces := []string{
+		"antidisestablishmentarianism",
+		strings.Repeat("a", 300),
+		strings.Repeat("ab", 150),
+		"ünïcødé",
+	}
+	for i := 0; i < 200; i++ {
+		var sb strings.Builder
+		for j := rng.Intn(400) + 2; j > 0; j-- {
+			sb.WriteByte(alphabet[rng.Intn(len(alphabet))])
+		}
+		pieces = append(pieces, sb.String())
+	}
+
+	for _, piece := range pieces {
+		if got, want := CL100K.countPiece(piece), naiveCountPiece(CL100K.ranks, piece); got != want {
+			t.Errorf("countPiece(%q) = %d, naive merge gives %d", piece, got, want)
+		}
+	}
+}
+
+func TestCountLongBlobIsFast(t *testing.T) {
+	CL100K.Count("warm up")
+
+	blob := strings.Repeat("aGVsbG8gd29ybGQ", 7000) // about 100k characters, no spaces
+	start := time.Now()
+	if CL100K.Count(blob) == 0 {
+		t.Fatal("no tokens counted")
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("counting a %d-byte blob took %v", len(blob), elapsed)
+	}
+}
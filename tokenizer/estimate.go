@@ -0,0 +1,46 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator approximates the count for models whose vocabulary isn't
+// embedded, such as Gemini's SentencePiece model. Instead of a flat four
+// characters per token it charges each character by script: English packs
+// several characters into a token, other alphabets fewer, and CJK, Indic
+// scripts and emoji a token or more per character.
+type Estimator struct{}
+
+func (Estimator) Count(text string) int {
+	cost := 0.0
+	for _, r := range text {
+		cost += runeCost(r)
+	}
+	return int(math.Ceil(cost))
+}
+
+// Tokens per character, by script
+func runeCost(r rune) float64 {
+	switch {
+	case r < utf8.RuneSelf:
+		return 0.25
+	case unicode.Is(unicode.Latin, r):
+		return 0.4
+	case unicode.In(r, unicode.Cyrillic, unicode.Greek, unicode.Armenian, unicode.Georgian, unicode.Hebrew, unicode.Arabic):
+		return 0.5
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return 1
+	case unicode.In(r, unicode.Devanagari, unicode.Bengali, unicode.Tamil, unicode.Telugu, unicode.Thai, unicode.Khmer):
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Cf, unicode.Variation_Selector):
+		// Combining marks, zero-width joiners and emoji variation selectors
+		return 0.5
+	case unicode.Is(unicode.So, r) || r >= 0x1F000:
+		return 2 // emoji
+	case unicode.IsSpace(r) || unicode.IsPunct(r):
+		return 0.5
+	}
+	return 1
+}
@@ -0,0 +1,97 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// eachPiece splits text the way cl100k_base does before merging:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead, so the alternatives are matched by hand,
+// first match wins.
+func eachPiece(text string, fn func(piece string)) {
+	for i := 0; i < len(text); {
+		end := matchPiece(text, i)
+		fn(text[i:end])
+		i = end
+	}
+}
+
+// matchPiece returns the end of the piece starting at i
+func matchPiece(s string, i int) int {
+	r, size := utf8.DecodeRuneInString(s[i:])
+
+	// Contractions
+	if r == '\'' {
+		rest := strings.ToLower(s[i+1 : min(i+3, len(s))])
+		for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+			if strings.HasPrefix(rest, suffix) {
+				return i + 1 + len(suffix)
+			}
+		}
+	}
+
+	// A word, with at most one leading non-letter such as a space
+	start := i
+	if !isNewline(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+		start = i + size
+	}
+	if end := skip(s, start, unicode.IsLetter, -1); end > start {
+		return end
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		return skip(s, i, unicode.IsNumber, 3)
+	}
+
+	// Punctuation, with an optional leading space and trailing newlines
+	start = i
+	if r == ' ' {
+		start++
+	}
+	if end := skip(s, start, isSymbol, -1); end > start {
+		return skip(s, end, isNewline, -1)
+	}
+
+	// Whitespace: up to its last newline, else all but the last character
+	// when something follows, so the next word keeps its leading space
+	end := skip(s, i, unicode.IsSpace, -1)
+	if last := strings.LastIndexAny(s[i:end], "\r\n"); last >= 0 {
+		return i + last + 1
+	}
+	if end < len(s) {
+		if _, lastSize := utf8.DecodeLastRuneInString(s[i:end]); end-lastSize > i {
+			return end - lastSize
+		}
+	}
+	if end > i {
+		return end
+	}
+	return i + size
+}
+
+// skip returns the end of the run of up to limit runes matching f starting
+// at i; a negative limit means no limit
+func skip(s string, i int, f func(rune) bool, limit int) int {
+	for n := 0; i < len(s) && n != limit; n++ {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !f(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
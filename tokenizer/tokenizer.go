@@ -0,0 +1,27 @@
+// Package tokenizer counts the tokens a piece of text costs a model, so
+// prompts can be fitted to a context window without calling the provider.
+package tokenizer
+
+// Counter counts the tokens text encodes to
+type Counter interface {
+	Count(text string) int
+}
+
+// Truncate shortens text to at most limit tokens, keeping its start
+func Truncate(c Counter, text string, limit int) string {
+	count := c.Count(text)
+	if count <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+
+	// Cut in proportion, then back off until it fits
+	runes := []rune(text)
+	n := len(runes) * limit / count
+	for n > 0 && c.Count(string(runes[:n])) > limit {
+		n -= max(1, n/10)
+	}
+	return string(runes[:max(n, 0)])
+}
@@ -0,0 +1,306 @@
+// Package transcript converts sessions to and from their portable export
+// formats: a versioned JSON schema, Markdown and standalone HTML.
+package transcript
+
+import (
+	"bytes"
+	"clementus360/ai-helper/ical"
+	"clementus360/ai-helper/types"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// Format names accepted by the export endpoint
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// Limits on one transcript, for export and import
+const (
+	MaxMessages = 5000
+	MaxTasks    = 5000
+)
+
+const timeLayout = "Jan 2, 2006 3:04 PM"
+
+// Build assembles the transcript of a session
+func Build(session types.Session, summary string, messages []types.Message, tasks []types.Task, now time.Time) types.Transcript {
+	t := types.Transcript{
+		Schema:     types.TranscriptSchema,
+		Version:    types.TranscriptSchemaVersion,
+		ExportedAt: now.UTC(),
+		Session: types.TranscriptSession{
+			ID:              session.ID,
+			Title:           session.Title,
+			CreatedAt:       session.CreatedAt,
+			Tags:            session.Tags,
+			ParentSessionID: session.ParentSessionID,
+		},
+		Summary:  summary,
+		Messages: make([]types.TranscriptMessage, 0, len(messages)),
+		Tasks:    make([]types.TranscriptTask, 0, len(tasks)),
+	}
+	for _, m := range messages {
+		t.Messages = append(t.Messages, types.TranscriptMessage{
+			ID:            m.ID,
+			Sender:        m.Sender,
+			Content:       m.Content,
+			CreatedAt:     m.CreatedAt,
+			UserMessageID: m.UserMessageID,
+			EditedAt:      m.EditedAt,
+		})
+	}
+	for _, task := range tasks {
+		t.Tasks = append(t.Tasks, types.TranscriptTask{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      task.Status,
+			DueDate:     task.DueDate,
+			CreatedAt:   task.CreatedAt,
+			MessageID:   task.MessageID,
+			Recurrence:  task.Recurrence,
+			AISuggested: task.AISuggested,
+		})
+	}
+	return t
+}
+
+// Parse reads a JSON transcript, rejecting other documents, schema
+// versions newer than this server understands and transcripts too large or
+// malformed to import
+func Parse(data []byte) (types.Transcript, error) {
+	var t types.Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return types.Transcript{}, fmt.Errorf("invalid transcript JSON: %w", err)
+	}
+	if t.Schema != types.TranscriptSchema {
+		return types.Transcript{}, fmt.Errorf("not a transcript: schema is %q", t.Schema)
+	}
+	if t.Version < 1 || t.Version > types.TranscriptSchemaVersion {
+		return types.Transcript{}, fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	if len(t.Messages) > MaxMessages || len(t.Tasks) > MaxTasks {
+		return types.Transcript{}, fmt.Errorf("transcripts hold at most %d messages and %d tasks", MaxMessages, MaxTasks)
+	}
+	for i, m := range t.Messages {
+		if m.Sender != "user" && m.Sender != "ai" {
+			return types.Transcript{}, fmt.Errorf("message %d has unknown sender %q", i+1, m.Sender)
+		}
+	}
+	for i, task := range t.Tasks {
+		if strings.TrimSpace(task.Title) == "" {
+			return types.Transcript{}, fmt.Errorf("task %d has no title", i+1)
+		}
+	}
+	return t, nil
+}
+
+// Records turns a transcript into a new session owned by userID, with its
+// messages and tasks. Every record gets a fresh ID from newID, and links
+// between them (replies to the messages they answer, tasks to the message
+// that created them) follow the new IDs; links to anything outside the
+// transcript are dropped.
+func Records(t types.Transcript, userID string, newID func() string, now time.Time) (types.Session, []types.Message, []types.Task) {
+	session := types.Session{
+		ID:           newID(),
+		UserID:       userID,
+		Title:        singleLine(t.Session.Title),
+		Tags:         t.Session.Tags,
+		State:        types.SessionStateActive,
+		LastActiveAt: &now,
+	}
+	if session.Title == "" {
+		session.Title = "Imported conversation"
+	}
+
+	newIDs := make(map[string]string, len(t.Messages))
+	for _, m := range t.Messages {
+		if m.ID != "" {
+			newIDs[m.ID] = newID()
+		}
+	}
+
+	messages := make([]types.Message, len(t.Messages))
+	for i, m := range t.Messages {
+		id := newIDs[m.ID]
+		if id == "" {
+			id = newID()
+		}
+		messages[i] = types.Message{
+			ID:            id,
+			UserID:        userID,
+			Sender:        m.Sender,
+			Content:       m.Content,
+			CreatedAt:     m.CreatedAt,
+			SessionID:     session.ID,
+			UserMessageID: newIDs[m.UserMessageID],
+			EditedAt:      m.EditedAt,
+		}
+		if m.CreatedAt.IsZero() {
+			messages[i].CreatedAt = now
+		}
+	}
+
+	tasks := make([]types.Task, len(t.Tasks))
+	for i, task := range t.Tasks {
+		tasks[i] = types.Task{
+			ID:          newID(),
+			UserID:      userID,
+			SessionID:   &session.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      task.Status,
+			DueDate:     task.DueDate,
+			CreatedAt:   task.CreatedAt,
+			AISuggested: task.AISuggested,
+		}
+		if task.Status != "completed" && task.Status != "cancelled" {
+			tasks[i].Status = "pending"
+		}
+		if tasks[i].CreatedAt.IsZero() {
+			tasks[i].CreatedAt = now
+		}
+		if task.Recurrence != "" && ical.ValidateRRule(task.Recurrence) == nil {
+			tasks[i].Recurrence = task.Recurrence
+		}
+		if task.MessageID != nil && newIDs[*task.MessageID] != "" {
+			messageID := newIDs[*task.MessageID]
+			tasks[i].MessageID = &messageID
+		}
+	}
+
+	return session, messages, tasks
+}
+
+// JSON renders the transcript in its portable schema
+func JSON(t types.Transcript) ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// Markdown renders the transcript for reading, with times in loc
+func Markdown(t types.Transcript, loc *time.Location) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", singleLine(t.Session.Title))
+	if t.Session.CreatedAt != nil {
+		fmt.Fprintf(&b, "_Started %s_", t.Session.CreatedAt.In(loc).Format(timeLayout))
+		if len(t.Session.Tags) > 0 {
+			fmt.Fprintf(&b, " · %s", strings.Join(t.Session.Tags, ", "))
+		}
+		b.WriteString("\n\n")
+	}
+
+	if t.Summary != "" {
+		b.WriteString("## Summary\n\n")
+		b.WriteString(strings.TrimSpace(t.Summary))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("## Conversation\n\n")
+	if len(t.Messages) == 0 {
+		b.WriteString("_No messages._\n\n")
+	}
+	for _, m := range t.Messages {
+		fmt.Fprintf(&b, "**%s** · %s", senderName(m.Sender), m.CreatedAt.In(loc).Format(timeLayout))
+		if m.EditedAt != nil {
+			b.WriteString(" (edited)")
+		}
+		b.WriteString("\n\n")
+		// Quote the message so its own Markdown can't break the layout
+		for _, line := range strings.Split(strings.TrimSpace(m.Content), "\n") {
+			b.WriteString(strings.TrimRight("> "+line, " "))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(t.Tasks) > 0 {
+		b.WriteString("## Tasks\n\n")
+		for _, task := range t.Tasks {
+			box := " "
+			if task.Status == "completed" {
+				box = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s _(%s", box, singleLine(task.Title), task.Status)
+			if task.DueDate != nil {
+				fmt.Fprintf(&b, ", due %s", task.DueDate.In(loc).Format(timeLayout))
+			}
+			b.WriteString(")_\n")
+			if desc := singleLine(task.Description); desc != "" {
+				fmt.Fprintf(&b, "  %s\n", desc)
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String())
+}
+
+// HTML renders the transcript as a standalone page, with times in loc
+func HTML(t types.Transcript, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, struct {
+		types.Transcript
+		Loc *time.Location
+	}{t, loc})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render transcript: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func senderName(sender string) string {
+	if sender == "user" {
+		return "You"
+	}
+	return "Assistant"
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var pageTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"sender": senderName,
+	"when": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format(timeLayout)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Session.Title}}</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:46rem;margin:2rem auto;padding:0 1rem;color:#222;line-height:1.5}
+.meta{color:#666;font-size:.9rem}
+.msg{border-radius:.5rem;padding:.75rem 1rem;margin:.75rem 0;white-space:pre-wrap}
+.user{background:#e8f0fe}
+.ai{background:#f4f4f4}
+.who{font-weight:600;display:block;white-space:normal}
+.done{text-decoration:line-through;color:#777}
+</style>
+</head>
+<body>
+<h1>{{.Session.Title}}</h1>
+{{if .Session.CreatedAt}}<p class="meta">Started {{when .Session.CreatedAt .Loc}}{{range $i, $t := .Session.Tags}}{{if eq $i 0}} · {{else}}, {{end}}{{$t}}{{end}}</p>{{end}}
+{{if .Summary}}<h2>Summary</h2>
+<p>{{.Summary}}</p>{{end}}
+<h2>Conversation</h2>
+{{range .Messages}}<div class="msg {{if eq .Sender "user"}}user{{else}}ai{{end}}"><span class="who">{{sender .Sender}} <span class="meta">{{when .CreatedAt $.Loc}}{{if .EditedAt}} (edited){{end}}</span></span>{{.Content}}</div>
+{{else}}<p class="meta">No messages.</p>
+{{end}}
+{{if .Tasks}}<h2>Tasks</h2>
+<ul>
+{{range .Tasks}}<li><span{{if eq .Status "completed"}} class="done"{{end}}>{{.Title}}</span> <span class="meta">({{.Status}}{{if .DueDate}}, due {{when .DueDate $.Loc}}{{end}})</span>{{if .Description}}<br>{{.Description}}{{end}}</li>
+{{end}}</ul>{{end}}
+<p class="meta">Exported {{when .ExportedAt .Loc}}</p>
+</body>
+</html>
+`))
@@ -0,0 +1,187 @@
+package transcript
+
+import (
+	"clementus360/ai-helper/types"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTranscript() types.Transcript {
+	created := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	due := created.Add(48 * time.Hour)
+	session := types.Session{ID: "s1", Title: "Job search", CreatedAt: &created, Tags: []string{"career"}}
+	messageID := "m2"
+	messages := []types.Message{
+		{ID: "m1", Sender: "user", Content: "Where do I start?", CreatedAt: created},
+		{ID: "m2", Sender: "ai", Content: "Start with your **CV**.\n# not a heading", CreatedAt: created.Add(5 * time.Second), UserMessageID: "m1"},
+	}
+	tasks := []types.Task{
+		{ID: "t1", Title: "Update CV", Status: "pending", DueDate: &due, CreatedAt: created, MessageID: &messageID, AISuggested: true},
+		{ID: "t2", Title: "Apply <now>", Status: "completed", CreatedAt: created},
+	}
+	return Build(session, "Planned the search.", messages, tasks, created.Add(time.Hour))
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	want := sampleTranscript()
+	data, err := JSON(want)
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got.Session.Title != want.Session.Title || got.Summary != want.Summary {
+		t.Errorf("session = %q / %q, want %q / %q", got.Session.Title, got.Summary, want.Session.Title, want.Summary)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].UserMessageID != "m1" || got.Messages[1].Content != want.Messages[1].Content {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+	if len(got.Tasks) != 2 || got.Tasks[0].MessageID == nil || *got.Tasks[0].MessageID != "m2" || !got.Tasks[0].DueDate.Equal(*want.Tasks[0].DueDate) {
+		t.Errorf("tasks = %+v", got.Tasks)
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := `"schema": "ai-helper.transcript", "version": 1`
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // empty when valid
+	}{
+		{name: "valid", data: `{` + valid + `, "messages": [{"id": "m1", "sender": "user", "content": "hi"}]}`},
+		{name: "unknown fields", data: `{` + valid + `, "mood": "great"}`},
+		{name: "not JSON", data: `# Job search`, wantErr: "invalid transcript JSON"},
+		{name: "other schema", data: `{"schema": "ai-helper.account-export", "version": 1}`, wantErr: "not a transcript"},
+		{name: "newer version", data: `{"schema": "ai-helper.transcript", "version": 2}`, wantErr: "unsupported transcript version 2"},
+		{name: "no version", data: `{"schema": "ai-helper.transcript"}`, wantErr: "unsupported transcript version 0"},
+		{name: "unknown sender", data: `{` + valid + `, "messages": [{"sender": "system", "content": "hi"}]}`, wantErr: `unknown sender "system"`},
+		{name: "untitled task", data: `{` + valid + `, "tasks": [{"title": "  ", "status": "pending"}]}`, wantErr: "task 1 has no title"},
+		{
+			name:    "too many messages",
+			data:    `{` + valid + `, "messages": [` + strings.Repeat(`{"sender": "user"},`, MaxMessages) + `{"sender": "user"}]}`,
+			wantErr: "at most",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Parse() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Parse() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	tr := sampleTranscript()
+	tr.Messages = append(tr.Messages, types.TranscriptMessage{ID: "m3", Sender: "ai", Content: "Orphan", UserMessageID: "elsewhere"})
+	outside := "elsewhere"
+	tr.Tasks = append(tr.Tasks,
+		types.TranscriptTask{ID: "t3", Title: "Odd status", Status: "archived", MessageID: &outside, Recurrence: "FREQ=HOURLY"},
+		types.TranscriptTask{ID: "t4", Title: "Weekly", Status: "pending", Recurrence: "FREQ=WEEKLY"},
+	)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	n := 0
+	newID := func() string {
+		n++
+		return fmt.Sprintf("new-%d", n)
+	}
+
+	session, messages, tasks := Records(tr, "u2", newID, now)
+
+	if session.ID != "new-1" || session.UserID != "u2" || session.Title != "Job search" || session.State != types.SessionStateActive {
+		t.Errorf("session = %+v", session)
+	}
+	if session.ParentSessionID != nil {
+		t.Errorf("imported session kept parent %v", *session.ParentSessionID)
+	}
+
+	ids := map[string]bool{session.ID: true}
+	for _, m := range messages {
+		ids[m.ID] = true
+		if m.UserID != "u2" || m.SessionID != session.ID {
+			t.Errorf("message %s not moved to the new session: %+v", m.ID, m)
+		}
+	}
+	if messages[1].UserMessageID != messages[0].ID {
+		t.Errorf("reply links to %q, want the new ID %q", messages[1].UserMessageID, messages[0].ID)
+	}
+	if messages[2].UserMessageID != "" {
+		t.Errorf("reply to a message outside the transcript links to %q", messages[2].UserMessageID)
+	}
+	if !messages[2].CreatedAt.Equal(now) {
+		t.Errorf("message without a time created at %v, want %v", messages[2].CreatedAt, now)
+	}
+
+	tests := []struct {
+		status, recurrence string
+		messageID          *string
+	}{
+		{"pending", "", &messages[1].ID},
+		{"completed", "", nil},
+		{"pending", "", nil},
+		{"pending", "FREQ=WEEKLY", nil},
+	}
+	for i, tt := range tests {
+		task := tasks[i]
+		ids[task.ID] = true
+		if task.UserID != "u2" || task.SessionID == nil || *task.SessionID != session.ID {
+			t.Errorf("task %d not moved to the new session: %+v", i, task)
+		}
+		if task.Status != tt.status || task.Recurrence != tt.recurrence {
+			t.Errorf("task %d = %q / %q, want %q / %q", i, task.Status, task.Recurrence, tt.status, tt.recurrence)
+		}
+		switch {
+		case tt.messageID == nil && task.MessageID != nil:
+			t.Errorf("task %d links to message %q, want none", i, *task.MessageID)
+		case tt.messageID != nil && (task.MessageID == nil || *task.MessageID != *tt.messageID):
+			t.Errorf("task %d links to %v, want %q", i, task.MessageID, *tt.messageID)
+		}
+	}
+
+	if len(ids) != 1+len(messages)+len(tasks) {
+		t.Errorf("IDs were reused: %v", ids)
+	}
+	for id := range ids {
+		if !strings.HasPrefix(id, "new-") {
+			t.Errorf("record kept its old ID %q", id)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	out := string(Markdown(sampleTranscript(), time.UTC))
+
+	for _, want := range []string{
+		"# Job search",
+		"_Started Oct 14, 2026 9:00 AM_ · career",
+		"## Summary\n\nPlanned the search.",
+		"**You** · Oct 14, 2026 9:00 AM",
+		"> Start with your **CV**.\n> # not a heading",
+		"- [ ] Update CV _(pending, due Oct 16, 2026 9:00 AM)_",
+		"- [x] Apply <now> _(completed)_",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTMLEscapes(t *testing.T) {
+	out, err := HTML(sampleTranscript(), time.UTC)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	if strings.Contains(string(out), "Apply <now>") || !strings.Contains(string(out), "Apply &lt;now&gt;") {
+		t.Errorf("task title not escaped:\n%s", out)
+	}
+}
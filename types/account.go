@@ -0,0 +1,131 @@
+package types
+
+import "time"
+
+// Account archive schema identifiers, bumped like the transcript schema
+const (
+	AccountArchiveSchema  = "ai-helper.account-export"
+	AccountArchiveVersion = 1
+)
+
+// Data job kinds
+const (
+	DataJobExport = "export"
+	DataJobImport = "import"
+)
+
+// Data job statuses
+const (
+	DataJobPending   = "pending"
+	DataJobRunning   = "running"
+	DataJobCompleted = "completed"
+	DataJobFailed    = "failed"
+	DataJobExpired   = "expired" // export archive has been removed
+)
+
+// DataJob tracks a background account export or import
+type DataJob struct {
+	ID          string         `json:"id,omitempty"`
+	UserID      string         `json:"user_id"`
+	Kind        string         `json:"kind"`
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	SizeBytes   int64          `json:"size_bytes,omitempty"`
+	SHA256      string         `json:"sha256,omitempty"` // of the export archive
+	Counts      map[string]int `json:"counts,omitempty"` // records per table
+	CreatedAt   *time.Time     `json:"created_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+}
+
+// ArchiveManifest describes the files in an account archive
+type ArchiveManifest struct {
+	Schema       string        `json:"schema"`
+	Version      int           `json:"version"`
+	SourceUserID string        `json:"source_user_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Files        []ArchiveFile `json:"files"`
+}
+
+type ArchiveFile struct {
+	Name    string `json:"name"`
+	Table   string `json:"table"`
+	Records int    `json:"records"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// ImportReport is the result of validating an archive for import
+type ImportReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Valid    bool           `json:"valid"`
+	Counts   map[string]int `json:"counts"`             // records that will be imported, per table
+	Skipped  map[string]int `json:"skipped,omitempty"`  // records left out, per table
+	Problems []string       `json:"problems,omitempty"` // why records were left out, or why the archive is invalid
+}
+
+type DataJobResponse struct {
+	Success bool    `json:"success"`
+	Job     DataJob `json:"job"`
+}
+
+type ImportResponse struct {
+	Success bool         `json:"success"`
+	Report  ImportReport `json:"report"`
+	Job     *DataJob     `json:"job,omitempty"` // set when the import was started
+}
+
+// AccountDeletionConfirmation must be sent verbatim to request deletion
+const AccountDeletionConfirmation = "DELETE MY ACCOUNT"
+
+// Account deletion statuses
+const (
+	AccountDeletionScheduled = "scheduled"
+	AccountDeletionCancelled = "cancelled"
+	AccountDeletionCompleted = "completed"
+)
+
+// AccountDeletion is a request to erase an account. Data stays until
+// PurgeAfter so the request can be cancelled; the record itself is kept
+// after the purge to hold the receipt.
+type AccountDeletion struct {
+	ID          string           `json:"id,omitempty"`
+	UserID      string           `json:"user_id"`
+	Status      string           `json:"status"`
+	RequestedAt *time.Time       `json:"requested_at,omitempty"`
+	PurgeAfter  time.Time        `json:"purge_after"`
+	CancelledAt *time.Time       `json:"cancelled_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	Progress    *DeletionReceipt `json:"progress,omitempty"` // what a purge has erased so far, unsigned
+	Receipt     *DeletionReceipt `json:"receipt,omitempty"`
+}
+
+// DeletionReceipt records what a purge erased. Signature is an HMAC of the
+// receipt's JSON encoding with Signature left empty.
+type DeletionReceipt struct {
+	DeletionID     string           `json:"deletion_id"`
+	UserID         string           `json:"user_id"`
+	RequestedAt    time.Time        `json:"requested_at"`
+	PurgedAt       time.Time        `json:"purged_at"`
+	Counts         map[string]int64 `json:"counts"`                   // rows deleted per table
+	MissingTables  []string         `json:"missing_tables,omitempty"` // tables this deployment doesn't have
+	Files          int              `json:"files"`                    // stored export archives deleted
+	AuthUserErased bool             `json:"auth_user_erased"`
+	Algorithm      string           `json:"algorithm"`
+	Signature      string           `json:"signature"`
+}
+
+type DeleteAccountRequest struct {
+	Confirmation string `json:"confirmation"`
+}
+
+type AccountDeletionResponse struct {
+	Success  bool            `json:"success"`
+	Deletion AccountDeletion `json:"deletion"`
+}
+
+type DeletionReceiptResponse struct {
+	Success bool            `json:"success"`
+	Receipt DeletionReceipt `json:"receipt"`
+	Valid   bool            `json:"valid"` // signature checks out
+}
@@ -0,0 +1,34 @@
+package types
+
+import "time"
+
+// BoardColumn is a user-defined kanban column. Moving a task into a column
+// with a Status also sets the task's status, e.g. a "Done" column.
+type BoardColumn struct {
+	ID        string     `json:"id,omitempty"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status,omitempty"`
+	Position  string     `json:"position"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ReorderTaskRequest moves a task between two neighbours in a column.
+// AfterID is the task that should come right before it and BeforeID the
+// one right after; with neither the task goes to the end of the column.
+type ReorderTaskRequest struct {
+	TaskID   string  `json:"task_id"`
+	ColumnID *string `json:"column_id,omitempty"` // omitted keeps the current column, "" removes it
+	AfterID  string  `json:"after_id,omitempty"`
+	BeforeID string  `json:"before_id,omitempty"`
+}
+
+type ColumnsResponse struct {
+	Success bool          `json:"success"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+type ColumnResponse struct {
+	Success bool        `json:"success"`
+	Column  BoardColumn `json:"column"`
+}
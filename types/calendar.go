@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// CalendarFeedToken authenticates calendar clients, which can't send Bearer JWTs.
+// Only a SHA-256 hash of the token is stored.
+type CalendarFeedToken struct {
+	ID        string     `json:"id,omitempty"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"token_hash"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CalendarFeedResponse struct {
+	Success bool   `json:"success"`
+	Token   string `json:"token"`
+	FeedURL string `json:"feed_url"` // path to subscribe to, relative to the server
+}
@@ -5,31 +5,76 @@ import (
 )
 
 type Message struct {
-	ID            string    `json:"id,omitempty"`
-	UserID        string    `json:"user_id"`
-	Sender        string    `json:"sender"`
-	Content       string    `json:"content"`
-	CreatedAt     time.Time `json:"created_at,omitempty"`
-	SessionID     string    `json:"session_id"`                // for associating messages with chat sessions
-	UserMessageID string    `json:"user_message_id,omitempty"` // for linking to user messages
+	ID            string     `json:"id,omitempty"`
+	UserID        string     `json:"user_id"`
+	Sender        string     `json:"sender"`
+	Content       string     `json:"content"`
+	CreatedAt     time.Time  `json:"created_at,omitempty"`
+	SessionID     string     `json:"session_id"`                // for associating messages with chat sessions
+	UserMessageID string     `json:"user_message_id,omitempty"` // for linking to user messages
+	EditedAt      *time.Time `json:"edited_at,omitempty"`
+	SupersededAt  *time.Time `json:"superseded_at,omitempty"`  // set on AI replies replaced by a regeneration
+	PromptVersion string     `json:"prompt_version,omitempty"` // coach prompt an AI reply was generated with
+}
+
+// Policies for the task changes made by a reply that gets regenerated
+const (
+	SupersededTasksKeep     = "keep"
+	SupersededTasksRollback = "rollback"
+)
+
+type EditMessageRequest struct {
+	Content    string `json:"content"`
+	Regenerate bool   `json:"regenerate,omitempty"` // also replace the AI reply
+	TaskPolicy string `json:"task_policy,omitempty"`
+	Timezone   string `json:"timezone,omitempty"`
+}
+
+type RegenerateRequest struct {
+	TaskPolicy string `json:"task_policy,omitempty"` // "keep" (default) or "rollback"
+	Timezone   string `json:"timezone,omitempty"`
+}
+
+type MessageResponse struct {
+	Success bool    `json:"success"`
+	Message Message `json:"message"`
+}
+
+type RegenerateResponse struct {
+	Success         bool     `json:"success"`
+	UserMessage     Message  `json:"user_message"`
+	Reply           Message  `json:"reply"`
+	ActionItems     []Task   `json:"action_items,omitempty"`
+	Superseded      []string `json:"superseded"`                  // IDs of the replies now kept as alternates
+	RolledBackTasks []string `json:"rolled_back_tasks,omitempty"` // task IDs changed back under the rollback policy
+	Warnings        []string `json:"warnings,omitempty"`          // assistant changes that couldn't be made
+}
+
+type MessageAlternatesResponse struct {
+	Success    bool      `json:"success"`
+	Alternates []Message `json:"alternates"` // every reply to the message, oldest first
 }
 
 type ChatRequest struct {
 	Message   string `json:"message"`
 	SessionID string `json:"session_id,omitempty"`
 	ForceNew  bool   `json:"force_new,omitempty"` // if true, create a new session even if one exists
+	Timezone  string `json:"timezone,omitempty"`  // IANA name used to resolve relative due dates
 }
 
 type ChatResponse struct {
-	Success      bool   `json:"success"`
-	UserMessage  string `json:"user_message"`
-	AIResponse   string `json:"ai_response,omitempty"`  // blank for now
-	ActionItems  []Task `json:"action_items,omitempty"` // future task suggestions
-	ErrorMessage string `json:"error,omitempty"`        // only set on failure
-	SessionID    string `json:"session_id"`
+	Success      bool     `json:"success"`
+	UserMessage  string   `json:"user_message"`
+	AIResponse   string   `json:"ai_response,omitempty"`  // blank for now
+	ActionItems  []Task   `json:"action_items,omitempty"` // future task suggestions
+	Warnings     []string `json:"warnings,omitempty"`     // assistant changes that couldn't be made
+	ErrorMessage string   `json:"error,omitempty"`        // only set on failure
+	SessionID    string   `json:"session_id"`
 }
 
 type GetMessagesResponse struct {
-	Success  bool      `json:"success"`
-	Messages []Message `json:"messages"`
+	Success    bool      `json:"success"`
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
 }
@@ -14,37 +14,49 @@ type UserActivity struct {
 }
 
 type SessionMetrics struct {
-	SessionID           string    `json:"session_id"`
-	UserID              string    `json:"user_id"`
-	MessageCount        int       `json:"message_count"`
-	TasksCreated        int       `json:"tasks_created"`
-	TasksCompleted      int       `json:"tasks_completed"`
-	LastActiveAt        time.Time `json:"last_active_at"`
-	DominantMood        string    `json:"dominant_mood,omitempty"`
-	PrimaryTopics       []string  `json:"primary_topics,omitempty"`
-	UserEngagementLevel string    `json:"engagement_level"` // "low", "medium", "high"
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	SessionID           string             `json:"session_id"`
+	UserID              string             `json:"user_id"`
+	MessageCount        int                `json:"message_count"`
+	TasksCreated        int                `json:"tasks_created"`
+	TasksCompleted      int                `json:"tasks_completed"`
+	LastActiveAt        time.Time          `json:"last_active_at"`
+	DominantMood        string             `json:"dominant_mood,omitempty"`
+	PrimaryTopics       []string           `json:"primary_topics,omitempty"`
+	UserEngagementLevel string             `json:"engagement_level"`       // "low", "medium", "high"
+	MoodScores          map[string]float64 `json:"mood_scores,omitempty"`  // per-mood weights, older messages decayed
+	TopicCounts         map[string]int     `json:"topic_counts,omitempty"` // user messages mentioning each topic
+	AvgMessageGap       float64            `json:"avg_message_gap_seconds,omitempty"`
+	LastMessageAt       *time.Time         `json:"last_message_at,omitempty"` // the latest user message
+	SignalsVersion      int                `json:"signals_version"`           // bumped on each signals write, for compare-and-swap
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
 }
 
 type UserPatterns struct {
-	UserID                 string    `json:"user_id"`
-	PreferredResponseStyle string    `json:"preferred_response_style"` // "task_focused", "discussion_focused", "balanced"
-	CommonStruggles        []string  `json:"common_struggles"`
-	SuccessfulStrategies   []string  `json:"successful_strategies"`
-	TimePreferences        string    `json:"time_preferences,omitempty"`
-	LastAnalyzed           time.Time `json:"last_analyzed"`
-	CreatedAt              time.Time `json:"created_at"`
-	UpdatedAt              time.Time `json:"updated_at"`
+	UserID                 string             `json:"user_id"`
+	PreferredResponseStyle string             `json:"preferred_response_style"` // "task_focused", "discussion_focused", "balanced"
+	CommonStruggles        []string           `json:"common_struggles"`
+	SuccessfulStrategies   []string           `json:"successful_strategies"`
+	TimePreferences        string             `json:"time_preferences,omitempty"`
+	TaskCompletionRates    map[string]float64 `json:"task_completion_rates,omitempty"` // ai_suggested, self_created, all
+	PromptVersion          string             `json:"prompt_version,omitempty"`        // patterns prompt the struggles and strategies came from
+	LastAnalyzed           time.Time          `json:"last_analyzed"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
 }
 
 type SmartContext struct {
 	Summary         string         `json:"summary"`
-	RecentMessages  []Message      `json:"recent_messages"`
+	RecentMessages  []Message      `json:"recent_messages"` // oldest first
 	KeyTasks        []Task         `json:"key_tasks"`
 	SessionMetrics  SessionMetrics `json:"session_metrics"`
 	UserPatterns    UserPatterns   `json:"user_patterns"`
 	PrioritySignals []string       `json:"priority_signals"`
+	Profile         UserProfile    `json:"profile"`
+	Templates       []TaskTemplate `json:"templates"`
+	Focus           FocusSummary   `json:"focus"`
+	Columns         []BoardColumn  `json:"columns"`
+	Memories        []Memory       `json:"memories"` // recalled for the current message
 }
 
 // Enhanced session context (backward compatible)
@@ -0,0 +1,60 @@
+package types
+
+import "time"
+
+// FocusSession is one stretch of tracked work on a task. A plain timer runs
+// until stopped; a pomodoro also has a planned length.
+type FocusSession struct {
+	ID              string     `json:"id,omitempty"`
+	UserID          string     `json:"user_id"`
+	TaskID          string     `json:"task_id"`
+	SessionID       *string    `json:"session_id,omitempty"` // chat session it was started from
+	Kind            string     `json:"kind"`                 // "timer" or "pomodoro"
+	PlannedMinutes  int        `json:"planned_minutes,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"` // nil while running
+	DurationSeconds int        `json:"duration_seconds"`
+	Completed       bool       `json:"completed"` // pomodoro ran its planned length
+}
+
+type StartTimerRequest struct {
+	Kind           string `json:"kind,omitempty"` // defaults to "timer"
+	PlannedMinutes int    `json:"planned_minutes,omitempty"`
+	SessionID      string `json:"session_id,omitempty"`
+}
+
+// TaskFocusTotal is the tracked time on a single task
+type TaskFocusTotal struct {
+	TaskID    string `json:"task_id"`
+	TaskTitle string `json:"task_title,omitempty"`
+	Seconds   int    `json:"seconds"`
+	Sessions  int    `json:"sessions"`
+}
+
+// DailyFocusTotal is the tracked time on one day in the user's timezone
+type DailyFocusTotal struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Seconds  int    `json:"seconds"`
+	Sessions int    `json:"sessions"`
+}
+
+// FocusSummary is the recent focus time the coach sees in its context
+type FocusSummary struct {
+	TodaySeconds int              `json:"today_seconds"`
+	WeekSeconds  int              `json:"week_seconds"`
+	TopTasks     []TaskFocusTotal `json:"top_tasks,omitempty"`
+	Running      *FocusSession    `json:"running,omitempty"`
+}
+
+type FocusSessionResponse struct {
+	Success bool         `json:"success"`
+	Session FocusSession `json:"session"`
+}
+
+type FocusSessionsResponse struct {
+	Success      bool              `json:"success"`
+	Sessions     []FocusSession    `json:"sessions"`
+	PerTask      []TaskFocusTotal  `json:"per_task"`
+	PerDay       []DailyFocusTotal `json:"per_day"`
+	TotalSeconds int               `json:"total_seconds"`
+}
@@ -0,0 +1,56 @@
+package types
+
+// Task import formats
+const (
+	TaskImportCSV      = "csv"
+	TaskImportMarkdown = "markdown"
+	TaskImportTodoist  = "todoist"
+	TaskImportTrello   = "trello"
+)
+
+// Outcomes of importing one row
+const (
+	TaskImportImported = "imported" // or would be, in a dry run
+	TaskImportSkipped  = "skipped"  // duplicate or archived
+	TaskImportInvalid  = "invalid"  // couldn't be read
+	TaskImportFailed   = "failed"   // read but couldn't be saved
+)
+
+// CSVMapping names the CSV header of each task field. Unset fields are
+// matched against common header names; an empty mapping guesses them all.
+type CSVMapping struct {
+	Title          string `json:"title,omitempty"`
+	Description    string `json:"description,omitempty"`
+	Due            string `json:"due,omitempty"`
+	Priority       string `json:"priority,omitempty"`
+	Labels         string `json:"labels,omitempty"`
+	Completed      string `json:"completed,omitempty"` // yes/no, true/false, done, or a status
+	LabelSeparator string `json:"label_separator,omitempty"`
+}
+
+type TaskImportRequest struct {
+	Format   string      `json:"format"`
+	Content  string      `json:"content"` // the file's text
+	Mapping  *CSVMapping `json:"mapping,omitempty"`
+	Timezone string      `json:"timezone,omitempty"` // for dates without one
+	DryRun   bool        `json:"dry_run,omitempty"`
+}
+
+// TaskImportRow reports what happened to one row of the import
+type TaskImportRow struct {
+	Row    int    `json:"row"` // line number, or position in the JSON export
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	TaskID string `json:"task_id,omitempty"`
+}
+
+type TaskImportResponse struct {
+	Success  bool            `json:"success"`
+	DryRun   bool            `json:"dry_run"`
+	Imported int             `json:"imported"`
+	Skipped  int             `json:"skipped"`
+	Invalid  int             `json:"invalid"`
+	Failed   int             `json:"failed"`
+	Rows     []TaskImportRow `json:"rows"`
+}
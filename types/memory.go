@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// Memory is a durable fact about the user, e.g. "works night shifts",
+// learned in one session and recalled in later ones
+type Memory struct {
+	ID              string    `json:"id,omitempty"`
+	UserID          string    `json:"user_id"`
+	Content         string    `json:"content"`
+	Embedding       []float32 `json:"embedding,omitempty"`
+	Embedder        string    `json:"embedder,omitempty"` // which embedder produced Embedding
+	SourceSessionID *string   `json:"source_session_id,omitempty"`
+	SourceMessageID *string   `json:"source_message_id,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	Score           float64   `json:"-"` // relevance when recalled
+}
+
+type MemoriesResponse struct {
+	Success  bool     `json:"success"`
+	Memories []Memory `json:"memories"`
+}
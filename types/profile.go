@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// UserProfile holds per-user preferences used to localise dates and scheduling
+type UserProfile struct {
+	UserID       string     `json:"user_id"`
+	Timezone     string     `json:"timezone"`      // IANA name, e.g. "Asia/Tokyo"
+	Locale       string     `json:"locale"`        // BCP 47 tag, e.g. "ja-JP"
+	WorkdayStart string     `json:"workday_start"` // "09:00"
+	WorkdayEnd   string     `json:"workday_end"`   // "17:00"
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+type ProfileResponse struct {
+	Success bool        `json:"success"`
+	Profile UserProfile `json:"profile"`
+}
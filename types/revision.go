@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// FieldChange is the before and after value of one task field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// TaskRevision records one change to a task and who made it
+type TaskRevision struct {
+	ID        string                 `json:"id,omitempty"`
+	TaskID    string                 `json:"task_id"`
+	UserID    string                 `json:"user_id"`
+	Action    string                 `json:"action"`               // "created" | "updated" | "deleted"
+	ActorType string                 `json:"actor_type"`           // "user" | "ai" | "system"
+	MessageID *string                `json:"message_id,omitempty"` // AI message that caused the change
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type TaskHistoryResponse struct {
+	Success      bool           `json:"success"`
+	TaskID       string         `json:"task_id"`
+	Revisions    []TaskRevision `json:"revisions"`
+	ErrorMessage string         `json:"error,omitempty"`
+}
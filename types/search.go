@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// Search result types
+const (
+	SearchTypeMessage = "message"
+	SearchTypeTask    = "task"
+	SearchTypeSummary = "summary"
+)
+
+// SearchResult is one ranked match. Snippet is an excerpt with the matched
+// terms wrapped in <mark> tags.
+type SearchResult struct {
+	Type      string    `json:"type"` // message | task | summary
+	ID        string    `json:"id"`   // message, task or session ID
+	SessionID *string   `json:"session_id,omitempty"`
+	Title     string    `json:"title,omitempty"` // task or session title
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResponse struct {
+	Success bool           `json:"success"`
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
@@ -4,22 +4,67 @@ import "time"
 
 // New session-related types
 type Session struct {
-	ID        string     `json:"id,omitempty"` // <-- omitempty is critical
-	UserID    string     `json:"user_id"`
-	Title     string     `json:"title"`
-	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ID                  string     `json:"id,omitempty"` // <-- omitempty is critical
+	UserID              string     `json:"user_id"`
+	Title               string     `json:"title"`
+	CreatedAt           *time.Time `json:"created_at,omitempty"`
+	ParentSessionID     *string    `json:"parent_session_id,omitempty"`      // set on forks
+	ForkedFromMessageID *string    `json:"forked_from_message_id,omitempty"` // last message copied from the parent
+	Branches            []Session  `json:"branches,omitempty"`               // forks, in tree view only
+	Pinned              bool       `json:"pinned"`
+	ArchivedAt          *time.Time `json:"archived_at,omitempty"`
+	Tags                []string   `json:"tags,omitempty"`
+	State               string     `json:"state,omitempty"` // one of the SessionState* values
+	LastActiveAt        *time.Time `json:"last_active_at,omitempty"`
+	ClosedAt            *time.Time `json:"closed_at,omitempty"`
+}
+
+// Session lifecycle states. A session goes idle after a short pause and
+// closes after a long one; posting to it again makes it active.
+const (
+	SessionStateActive = "active"
+	SessionStateIdle   = "idle"
+	SessionStateClosed = "closed"
+)
+
+// Values for SessionFilter.Archived
+const (
+	SessionArchivedExclude = ""     // active sessions only
+	SessionArchivedOnly    = "only" // archived sessions only
+	SessionArchivedInclude = "all"  // both
+)
+
+// SessionFilter narrows a session listing
+type SessionFilter struct {
+	Tree     bool     // top-level sessions with their forks nested
+	Pinned   *bool    // nil means either
+	Archived string   // one of the SessionArchived* values
+	Tags     []string // sessions carrying all of these tags
+	State    string   // one of the SessionState* values, or "" for any
+}
+
+// UpdateSessionRequest changes any of a session's user-editable fields.
+// A nil field is left unchanged; an empty Tags list clears the tags.
+type UpdateSessionRequest struct {
+	Title    *string   `json:"title,omitempty"`
+	Pinned   *bool     `json:"pinned,omitempty"`
+	Archived *bool     `json:"archived,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
 }
 
 type SessionSummary struct {
-	SessionID   string    `json:"session_id"`
-	UserID      string    `json:"user_id"`
-	Summary     string    `json:"summary"`
-	LastUpdated time.Time `json:"last_updated,omitempty"`
+	SessionID     string    `json:"session_id"`
+	UserID        string    `json:"user_id"`
+	Summary       string    `json:"summary"`
+	PromptVersion string    `json:"prompt_version,omitempty"` // summary prompt it was generated with
+	LastUpdated   time.Time `json:"last_updated,omitempty"`
 }
 
 type GetSessionsResponse struct {
-	Success  bool      `json:"success"`
-	Sessions []Session `json:"sessions"`
+	Success    bool      `json:"success"`
+	Sessions   []Session `json:"sessions"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
 }
 
 type SessionResponse struct {
@@ -17,6 +17,19 @@ type Task struct {
 	Decision      string     `json:"decision,omitempty"`   // approved | declined | undecided
 	FollowUpDueAt time.Time  `json:"follow_up_due_at,omitempty"`
 	FollowedUp    bool       `json:"followed_up,omitempty"`
+	Recurrence    string     `json:"recurrence,omitempty"` // RRULE value, e.g. "FREQ=WEEKLY;BYDAY=MO"
+	ColumnID      *string    `json:"column_id,omitempty"`  // board column, nil for none
+	Position      string     `json:"position,omitempty"`   // fractional index within the column
+	Priority      int        `json:"priority,omitempty"`   // 1 (highest) to 4 (lowest), 0 for none
+	Labels        []string   `json:"labels,omitempty"`
+}
+
+// CreateTaskRequest accepts either an explicit due_date or a natural-language
+// due phrase such as "friday afternoon", resolved in the given timezone
+type CreateTaskRequest struct {
+	Task
+	Due      string `json:"due,omitempty"`
+	Timezone string `json:"timezone,omitempty"` // IANA name, e.g. "Asia/Tokyo"
 }
 
 type TaskResponse struct {
@@ -37,7 +50,9 @@ type GetTasksResponse struct {
 	Total        int    `json:"total,omitempty"`  // Optional: total count for pagination
 	Limit        int    `json:"limit,omitempty"`  // Echoed back from request
 	Offset       int    `json:"offset,omitempty"` // Echoed back from request
-	ErrorMessage string `json:"error,omitempty"`  // Only set on failure
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
+	ErrorMessage string `json:"error,omitempty"` // Only set on failure
 }
 
 type GetSingleTaskResponse struct {
@@ -51,3 +66,38 @@ type SupabaseGetTasksResponse struct {
 	Data  []Task `json:"data"`
 	Count int64  `json:"count"`
 }
+
+// TaskBatchOperation is one create, update or delete in a batch request
+type TaskBatchOperation struct {
+	Op      string                 `json:"op"`                // "create" | "update" | "delete"
+	ID      string                 `json:"id,omitempty"`      // task ID for update and delete
+	Task    *Task                  `json:"task,omitempty"`    // new task for create
+	Updates map[string]interface{} `json:"updates,omitempty"` // fields to change for update
+}
+
+type TaskBatchRequest struct {
+	Mode       string               `json:"mode"` // "atomic" (all-or-nothing) | "best_effort"
+	Operations []TaskBatchOperation `json:"operations"`
+}
+
+// TaskBatchResult reports the outcome of one operation, in request order
+type TaskBatchResult struct {
+	Index         int    `json:"index"`
+	Op            string `json:"op"`
+	ID            string `json:"id,omitempty"`
+	Success       bool   `json:"success"`
+	Task          *Task  `json:"task,omitempty"`
+	Error         string `json:"error,omitempty"`
+	RollbackError string `json:"rollback_error,omitempty"` // set when a rolled back operation is still applied
+}
+
+type TaskBatchResponse struct {
+	Success        bool              `json:"success"`
+	Mode           string            `json:"mode"`
+	Results        []TaskBatchResult `json:"results"`
+	Applied        int               `json:"applied"`
+	Failed         int               `json:"failed"`
+	RolledBack     bool              `json:"rolled_back,omitempty"`     // atomic batches undone after a failure
+	RollbackFailed int               `json:"rollback_failed,omitempty"` // operations still applied after a failed rollback
+	ErrorMessage   string            `json:"error,omitempty"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+// TemplateStep is one ordered step of a task template
+type TemplateStep struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	DueOffset   string `json:"due_offset,omitempty"` // relative to instantiation, e.g. "2h", "3d", "1w"
+}
+
+// TaskTemplate is a reusable routine that expands into one task per step.
+// Titles and descriptions may contain {{placeholder}} markers.
+type TaskTemplate struct {
+	ID           string         `json:"id,omitempty"`
+	UserID       string         `json:"user_id,omitempty"` // empty for built-in templates
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	Placeholders []string       `json:"placeholders,omitempty"`
+	Steps        []TemplateStep `json:"steps"`
+	BuiltIn      bool           `json:"built_in,omitempty"`
+	CreatedAt    *time.Time     `json:"created_at,omitempty"`
+}
+
+type InstantiateTemplateRequest struct {
+	TemplateID string            `json:"template_id"`
+	Values     map[string]string `json:"values,omitempty"` // placeholder values
+	SessionID  string            `json:"session_id,omitempty"`
+}
+
+type GetTemplatesResponse struct {
+	Success   bool           `json:"success"`
+	Templates []TaskTemplate `json:"templates"`
+}
+
+type TemplateResponse struct {
+	Success  bool         `json:"success"`
+	Template TaskTemplate `json:"template"`
+}
+
+type InstantiateTemplateResponse struct {
+	Success bool   `json:"success"`
+	Tasks   []Task `json:"tasks"`
+}
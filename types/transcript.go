@@ -0,0 +1,57 @@
+package types
+
+import "time"
+
+// Transcript schema identifiers. Version is bumped whenever a field changes
+// meaning or is removed; new optional fields keep the version.
+const (
+	TranscriptSchema        = "ai-helper.transcript"
+	TranscriptSchemaVersion = 1
+)
+
+// Transcript is the portable form of a session used by export and import
+type Transcript struct {
+	Schema     string              `json:"schema"`  // always TranscriptSchema
+	Version    int                 `json:"version"` // TranscriptSchemaVersion when written
+	ExportedAt time.Time           `json:"exported_at"`
+	Session    TranscriptSession   `json:"session"`
+	Summary    string              `json:"summary,omitempty"`
+	Messages   []TranscriptMessage `json:"messages"`
+	Tasks      []TranscriptTask    `json:"tasks"`
+}
+
+type TranscriptSession struct {
+	ID              string     `json:"id"`
+	Title           string     `json:"title"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	ParentSessionID *string    `json:"parent_session_id,omitempty"`
+}
+
+type TranscriptMessage struct {
+	ID            string     `json:"id"`
+	Sender        string     `json:"sender"` // "user" or "ai"
+	Content       string     `json:"content"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UserMessageID string     `json:"user_message_id,omitempty"` // on replies, the message answered
+	EditedAt      *time.Time `json:"edited_at,omitempty"`
+}
+
+type TranscriptTask struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Status      string     `json:"status"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	MessageID   *string    `json:"message_id,omitempty"` // message that created the task
+	Recurrence  string     `json:"recurrence,omitempty"`
+	AISuggested bool       `json:"ai_suggested"`
+}
+
+type ImportTranscriptResponse struct {
+	Success  bool    `json:"success"`
+	Session  Session `json:"session"`
+	Messages int     `json:"messages"` // messages imported
+	Tasks    int     `json:"tasks"`    // tasks imported
+}